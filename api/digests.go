@@ -0,0 +1,306 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	digestTypeSNS     = "sns"
+	digestTypeWebhook = "webhook"
+
+	digestFrequencyDaily  = "DAILY"
+	digestFrequencyWeekly = "WEEKLY"
+)
+
+var (
+	// DigestInterval is how often the digest scheduler checks for digests that are due
+	DigestInterval = 1 * time.Hour
+
+	// DigestWebhookTimeout is the timeout for delivering a digest to a webhook
+	DigestWebhookTimeout = 10 * time.Second
+)
+
+// digestStore keeps the digest subscriptions in memory and, if a file is configured, persists
+// them as JSON so they survive restarts
+type digestStore struct {
+	file          string
+	mu            sync.Mutex
+	subscriptions map[string]*DigestSubscription
+}
+
+// newDigestStore creates a digest store, loading any subscriptions persisted in the file
+func newDigestStore(file string) (*digestStore, error) {
+	d := &digestStore{
+		file:          file,
+		subscriptions: map[string]*DigestSubscription{},
+	}
+
+	if file == "" {
+		log.Warn("digest subscriptions file is not configured, subscriptions will not be persisted")
+		return d, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+		return nil, errors.Wrap(err, "failed to read digest subscriptions")
+	}
+
+	subscriptions := []*DigestSubscription{}
+	if err := json.Unmarshal(b, &subscriptions); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal digest subscriptions")
+	}
+
+	for _, s := range subscriptions {
+		d.subscriptions[s.ID] = s
+	}
+
+	log.Infof("loaded %d digest subscriptions from %s", len(subscriptions), file)
+
+	return d, nil
+}
+
+// list returns copies of the subscriptions for a space, or all subscriptions if account and space are empty
+func (d *digestStore) list(account, space string) []*DigestSubscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := []*DigestSubscription{}
+	for _, s := range d.subscriptions {
+		if account != "" && (s.Account != account || s.Space != space) {
+			continue
+		}
+
+		c := *s
+		out = append(out, &c)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+
+	return out
+}
+
+// get returns a copy of the subscription with the id, the subscription must belong to the space
+func (d *digestStore) get(account, space, id string) (*DigestSubscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, ok := d.subscriptions[id]
+	if !ok || s.Account != account || s.Space != space {
+		msg := fmt.Sprintf("digest subscription %s not found for space %s", id, space)
+		return nil, apierror.New(apierror.ErrNotFound, msg, nil)
+	}
+
+	c := *s
+	return &c, nil
+}
+
+// put creates or replaces the subscription
+func (d *digestStore) put(sub *DigestSubscription) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	previous, exists := d.subscriptions[sub.ID]
+
+	c := *sub
+	d.subscriptions[sub.ID] = &c
+
+	if err := d.save(); err != nil {
+		if exists {
+			d.subscriptions[sub.ID] = previous
+		} else {
+			delete(d.subscriptions, sub.ID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// update applies the change to the subscription with the id and returns a copy of the updated
+// subscription.  The subscription must belong to the space, the change is made under the lock so
+// it can't race with a delete or a delivery.
+func (d *digestStore) update(account, space, id string, change func(*DigestSubscription)) (*DigestSubscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, ok := d.subscriptions[id]
+	if !ok || s.Account != account || s.Space != space {
+		msg := fmt.Sprintf("digest subscription %s not found for space %s", id, space)
+		return nil, apierror.New(apierror.ErrNotFound, msg, nil)
+	}
+
+	c := *s
+	change(&c)
+	d.subscriptions[id] = &c
+
+	if err := d.save(); err != nil {
+		d.subscriptions[id] = s
+		return nil, err
+	}
+
+	out := c
+	return &out, nil
+}
+
+// delete removes the subscription with the id, the subscription must belong to the space
+func (d *digestStore) delete(account, space, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, ok := d.subscriptions[id]
+	if !ok || s.Account != account || s.Space != space {
+		msg := fmt.Sprintf("digest subscription %s not found for space %s", id, space)
+		return apierror.New(apierror.ErrNotFound, msg, nil)
+	}
+
+	delete(d.subscriptions, id)
+
+	if err := d.save(); err != nil {
+		d.subscriptions[id] = s
+		return err
+	}
+
+	return nil
+}
+
+// recordDelivery records the result of delivering the digest for the subscription with the id.  The
+// subscription may have been deleted while the digest was being sent, in which case this is a no-op.
+func (d *digestStore) recordDelivery(id string, sent *time.Time, lastError string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, ok := d.subscriptions[id]
+	if !ok {
+		return nil
+	}
+
+	previous := *s
+	s.LastSent = sent
+	s.LastError = lastError
+
+	if err := d.save(); err != nil {
+		*s = previous
+		return err
+	}
+
+	return nil
+}
+
+// save writes the subscriptions to the file (if configured).  The file is written to a
+// temporary file and renamed so a failed write doesn't lose the existing subscriptions.
+// The caller must hold the lock.
+func (d *digestStore) save() error {
+	if d.file == "" {
+		return nil
+	}
+
+	subscriptions := make([]*DigestSubscription, 0, len(d.subscriptions))
+	for _, s := range d.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].ID < subscriptions[j].ID
+	})
+
+	b, err := json.MarshalIndent(subscriptions, "", "  ")
+	if err != nil {
+		return apierror.New(apierror.ErrInternalError, "failed to marshal digest subscriptions", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.file), filepath.Base(d.file)+".*")
+	if err != nil {
+		return apierror.New(apierror.ErrInternalError, "failed to save digest subscriptions", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return apierror.New(apierror.ErrInternalError, "failed to save digest subscriptions", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return apierror.New(apierror.ErrInternalError, "failed to save digest subscriptions", err)
+	}
+
+	if err := os.Rename(tmp.Name(), d.file); err != nil {
+		return apierror.New(apierror.ErrInternalError, "failed to save digest subscriptions", err)
+	}
+
+	return nil
+}
+
+// digestDue returns true if the subscription hasn't been sent within its frequency
+func digestDue(sub *DigestSubscription, now time.Time) bool {
+	if sub.LastSent == nil {
+		return true
+	}
+
+	period := 24 * time.Hour
+	if sub.Frequency == digestFrequencyWeekly {
+		period = 7 * 24 * time.Hour
+	}
+
+	return !now.Before(sub.LastSent.Add(period))
+}
+
+// startDigestScheduler runs the digest scheduler in the background until the context is cancelled
+func (s *server) startDigestScheduler(ctx context.Context, interval time.Duration) {
+	log.Infof("starting digest scheduler with interval %s", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("stopping digest scheduler")
+				return
+			case <-ticker.C:
+				s.sendDueDigests(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+// sendDueDigests delivers the digests that are due.  The result of the delivery is recorded on the
+// subscription, failed deliveries are retried on the next run.
+func (s *server) sendDueDigests(ctx context.Context, now time.Time) {
+	for _, sub := range s.digestStore.list("", "") {
+		if !digestDue(sub, now) {
+			continue
+		}
+
+		log.Infof("sending %s cost digest %s for space %s in account %s", sub.Type, sub.ID, sub.Space, sub.Account)
+
+		var sent *time.Time
+		var lastError string
+		if err := s.sendDigest(ctx, sub); err != nil {
+			log.Errorf("failed to send cost digest %s for space %s: %s", sub.ID, sub.Space, err)
+			sent, lastError = sub.LastSent, err.Error()
+		} else {
+			sent = &now
+		}
+
+		if err := s.digestStore.recordDelivery(sub.ID, sent, lastError); err != nil {
+			log.Errorf("failed to update cost digest subscription %s: %s", sub.ID, err)
+		}
+	}
+}
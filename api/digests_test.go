@@ -0,0 +1,425 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+func TestDigestStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "digests.json")
+
+	store, err := newDigestStore(file)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	created := time.Date(2021, time.May, 1, 0, 0, 0, 0, time.UTC)
+	subs := []*DigestSubscription{
+		{ID: "d-1", Account: "012345678901", Space: "spc-1", Type: digestTypeSNS, Frequency: digestFrequencyWeekly, CreatedAt: created},
+		{ID: "d-2", Account: "012345678901", Space: "spc-1", Type: digestTypeWebhook, Frequency: digestFrequencyDaily, CreatedAt: created.Add(time.Hour)},
+		{ID: "d-3", Account: "012345678901", Space: "spc-2", Type: digestTypeWebhook, Frequency: digestFrequencyDaily, CreatedAt: created},
+	}
+
+	for _, s := range subs {
+		if err := store.put(s); err != nil {
+			t.Fatalf("expected nil error, got %s", err)
+		}
+	}
+
+	if out := store.list("012345678901", "spc-1"); len(out) != 2 || out[0].ID != "d-1" || out[1].ID != "d-2" {
+		t.Errorf("expected d-1 and d-2 for spc-1, got %+v", out)
+	}
+
+	if out := store.list("", ""); len(out) != 3 {
+		t.Errorf("expected 3 subscriptions, got %d", len(out))
+	}
+
+	// subscriptions in another space are not found
+	if _, err := store.get("012345678901", "spc-2", "d-1"); err == nil {
+		t.Error("expected not found error, got nil")
+	} else if aerr, ok := err.(apierror.Error); !ok || aerr.Code != apierror.ErrNotFound {
+		t.Errorf("expected not found error, got %s", err)
+	}
+
+	// returned subscriptions are copies
+	out, err := store.get("012345678901", "spc-1", "d-1")
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+	out.Endpoint = "changed"
+	if out, _ := store.get("012345678901", "spc-1", "d-1"); out.Endpoint == "changed" {
+		t.Error("expected store to be unchanged by modifying a returned subscription")
+	}
+
+	sent := created.Add(24 * time.Hour)
+	if err := store.recordDelivery("d-2", &sent, ""); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if err := store.recordDelivery("missing", &sent, ""); err != nil {
+		t.Errorf("expected nil error recording delivery for deleted subscription, got %s", err)
+	}
+
+	if err := store.delete("012345678901", "spc-2", "d-3"); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if err := store.delete("012345678901", "spc-2", "d-3"); err == nil {
+		t.Error("expected error deleting missing subscription, got nil")
+	}
+
+	// subscriptions are loaded from the file
+	loaded, err := newDigestStore(file)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if out := loaded.list("", ""); !reflect.DeepEqual(out, store.list("", "")) {
+		t.Errorf("expected loaded subscriptions to match, got %+v", out)
+	}
+
+	if out, _ := loaded.get("012345678901", "spc-1", "d-2"); out == nil || out.LastSent == nil || !out.LastSent.Equal(sent) {
+		t.Errorf("expected persisted last sent %s, got %+v", sent, out)
+	}
+
+	// subscriptions are kept in memory without a file
+	memory, err := newDigestStore("")
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if err := memory.put(subs[0]); err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+}
+
+func TestDigestStoreUpdate(t *testing.T) {
+	store, err := newDigestStore(filepath.Join(t.TempDir(), "digests.json"))
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	created := time.Date(2021, time.May, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		sub := &DigestSubscription{ID: fmt.Sprintf("d-%d", i), Account: "012345678901", Space: "spc-1", Type: digestTypeSNS, Frequency: digestFrequencyWeekly, CreatedAt: created}
+		if err := store.put(sub); err != nil {
+			t.Fatalf("expected nil error, got %s", err)
+		}
+	}
+
+	out, err := store.update("012345678901", "spc-1", "d-0", func(sub *DigestSubscription) {
+		sub.Frequency = digestFrequencyDaily
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if out.Frequency != digestFrequencyDaily {
+		t.Errorf("expected frequency %s, got %s", digestFrequencyDaily, out.Frequency)
+	}
+
+	// subscriptions in another space are not found
+	_, err = store.update("012345678901", "spc-2", "d-0", func(sub *DigestSubscription) {})
+	if aerr, ok := err.(apierror.Error); !ok || aerr.Code != apierror.ErrNotFound {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	// updates racing with deliveries keep the delivery and updates racing with deletes don't
+	// recreate the subscription
+	sent := created.Add(24 * time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("d-%d", i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			store.update("012345678901", "spc-1", id, func(sub *DigestSubscription) {
+				sub.Endpoint = "arn:aws:sns:us-east-1:012345678901:updated"
+			})
+		}()
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				store.recordDelivery(id, &sent, "")
+			} else {
+				store.delete("012345678901", "spc-1", id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 20; i++ {
+		sub, err := store.get("012345678901", "spc-1", fmt.Sprintf("d-%d", i))
+		if i%2 == 1 {
+			if err == nil {
+				t.Errorf("expected deleted subscription d-%d to stay deleted, got %+v", i, sub)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("expected nil error, got %s", err)
+		}
+
+		if sub.LastSent == nil || !sub.LastSent.Equal(sent) {
+			t.Errorf("expected d-%d last sent %s to be kept, got %v", i, sent, sub.LastSent)
+		}
+
+		if sub.Endpoint != "arn:aws:sns:us-east-1:012345678901:updated" {
+			t.Errorf("expected d-%d endpoint to be updated, got %s", i, sub.Endpoint)
+		}
+	}
+}
+
+func TestDigestDue(t *testing.T) {
+	now := time.Date(2021, time.May, 15, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+
+	tests := []struct {
+		sub  *DigestSubscription
+		want bool
+	}{
+		{&DigestSubscription{Frequency: digestFrequencyWeekly}, true},
+		{&DigestSubscription{Frequency: digestFrequencyDaily, LastSent: ago(23 * time.Hour)}, false},
+		{&DigestSubscription{Frequency: digestFrequencyDaily, LastSent: ago(24 * time.Hour)}, true},
+		{&DigestSubscription{Frequency: digestFrequencyWeekly, LastSent: ago(6 * 24 * time.Hour)}, false},
+		{&DigestSubscription{Frequency: digestFrequencyWeekly, LastSent: ago(7 * 24 * time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		if got := digestDue(tt.sub, now); got != tt.want {
+			t.Errorf("digestDue(%s, %v) = %t, want %t", tt.sub.Frequency, tt.sub.LastSent, got, tt.want)
+		}
+	}
+}
+
+func TestValidateDigestRequest(t *testing.T) {
+	tests := []struct {
+		req       DigestSubscriptionRequest
+		frequency string
+		wantErr   bool
+	}{
+		{DigestSubscriptionRequest{Type: "SNS", Endpoint: "arn:aws:sns:us-east-1:012345678901:digests"}, digestFrequencyWeekly, false},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "https://example.com/hook", Frequency: "daily"}, digestFrequencyDaily, false},
+		{DigestSubscriptionRequest{Type: "sns", Endpoint: "arn:aws:sns:us-east-1:999999999999:digests"}, "", true},
+		{DigestSubscriptionRequest{Type: "sns", Endpoint: "arn:aws:s3:::bucket"}, "", true},
+		{DigestSubscriptionRequest{Type: "sns", Endpoint: "digests"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "http://example.com/hook"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "https:///hook"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "https://127.0.0.1/hook"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "https://localhost:8443/hook"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "https://[::1]/hook"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "https://169.254.169.254/latest/meta-data"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "https://10.1.2.3/hook"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook"}, "", true},
+		{DigestSubscriptionRequest{Type: "email", Endpoint: "jdoe@example.com"}, "", true},
+		{DigestSubscriptionRequest{Type: "webhook", Endpoint: "https://example.com/hook", Frequency: "MONTHLY"}, "", true},
+	}
+
+	for _, tt := range tests {
+		req := tt.req
+		err := validateDigestRequest("012345678901", &req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateDigestRequest(%+v) error = %v, wantErr %v", tt.req, err, tt.wantErr)
+			continue
+		}
+
+		if err != nil {
+			if aerr, ok := err.(apierror.Error); !ok || aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("expected bad request error, got %s", err)
+			}
+			continue
+		}
+
+		if req.Frequency != tt.frequency {
+			t.Errorf("expected frequency %s, got %s", tt.frequency, req.Frequency)
+		}
+	}
+}
+
+func TestToServiceCosts(t *testing.T) {
+	results := []*costexplorer.ResultByTime{
+		{
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    aws.StringSlice([]string{"Amazon Simple Storage Service"}),
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": {Amount: aws.String("1.004"), Unit: aws.String("USD")}},
+				},
+				{
+					Keys:    aws.StringSlice([]string{"Amazon Elastic Compute Cloud - Compute"}),
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": {Amount: aws.String("10"), Unit: aws.String("USD")}},
+				},
+				{
+					Keys:    aws.StringSlice([]string{"AWS Lambda"}),
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": {Amount: aws.String("1"), Unit: aws.String("USD")}},
+				},
+			},
+		},
+		{
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    aws.StringSlice([]string{"Amazon Elastic Compute Cloud - Compute"}),
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": {Amount: aws.String("2.5"), Unit: aws.String("USD")}},
+				},
+			},
+		},
+	}
+
+	out, err := toServiceCosts(results, costexplorer.MetricUnblendedCost)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := []*ServiceCost{
+		{Service: "Amazon Elastic Compute Cloud - Compute", Amount: 12.5, Unit: "USD"},
+		{Service: "AWS Lambda", Amount: 1, Unit: "USD"},
+		{Service: "Amazon Simple Storage Service", Amount: 1, Unit: "USD"},
+	}
+
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	results[0].Groups[0].Metrics["UnblendedCost"].Amount = aws.String("bad")
+	if _, err := toServiceCosts(results, costexplorer.MetricUnblendedCost); err == nil {
+		t.Error("expected error for invalid amount, got nil")
+	}
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	digest := &CostDigest{
+		Account:     "012345678901",
+		Space:       "spc-1",
+		MonthToDate: &DigestAmount{Amount: 12.5, Unit: "USD"},
+	}
+
+	var got CostDigest
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected application/json content type, got %s", ct)
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode digest: %s", err)
+		}
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	if err := deliverWebhook(context.TODO(), ts.Client(), ts.URL+"/hook", digest); err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if !reflect.DeepEqual(&got, digest) {
+		t.Errorf("expected %+v, got %+v", digest, got)
+	}
+
+	if err := deliverWebhook(context.TODO(), ts.Client(), ts.URL+"/fail", digest); err == nil {
+		t.Error("expected error for failed webhook, got nil")
+	}
+}
+
+func TestWebhookAddressAllowed(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:2800::1":    true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.0.0.1":        false,
+		"172.16.5.4":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"::":              false,
+		"224.0.0.1":       false,
+		"::ffff:10.0.0.1": false,
+	}
+
+	for addr, expected := range tests {
+		if out := webhookAddressAllowed(net.ParseIP(addr)); out != expected {
+			t.Errorf("webhookAddressAllowed(%s) = %t, expected %t", addr, out, expected)
+		}
+	}
+}
+
+func TestWebhookClient(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "https://169.254.169.254/latest/meta-data", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	digest := &CostDigest{Account: "012345678901", Space: "spc-1"}
+
+	// the test server listens on loopback, so the webhook client refuses to connect
+	client := newWebhookClient(time.Second)
+	if err := deliverWebhook(context.TODO(), client, ts.URL+"/hook", digest); err == nil {
+		t.Error("expected error delivering to a loopback address, got nil")
+	}
+
+	// redirects are refused
+	redirectClient := ts.Client()
+	redirectClient.CheckRedirect = client.CheckRedirect
+	if err := deliverWebhook(context.TODO(), redirectClient, ts.URL+"/redirect", digest); err == nil {
+		t.Error("expected error following a webhook redirect, got nil")
+	}
+}
+
+func TestDigestMessage(t *testing.T) {
+	out := digestMessage(&CostDigest{
+		Account: "012345678901",
+		Space:   "spc-1",
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String("2021-05-01"),
+			End:   aws.String("2021-05-15"),
+		},
+		MonthToDate: &DigestAmount{Amount: 12.5, Unit: "USD"},
+		Forecast:    &DigestAmount{Amount: 14, Unit: "USD"},
+		TopServices: []*ServiceCost{
+			{Service: "Amazon Elastic Compute Cloud - Compute", Amount: 12.5, Unit: "USD"},
+		},
+	})
+
+	for _, s := range []string{
+		"Cost digest for space spc-1 in account 012345678901",
+		"Month to date (2021-05-01 - 2021-05-15): 12.50 USD",
+		"Forecast for the rest of the month: 14.00 USD",
+		"  Amazon Elastic Compute Cloud - Compute: 12.50 USD",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected message to contain %q, got:\n%s", s, out)
+		}
+	}
+}
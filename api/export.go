@@ -0,0 +1,329 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	exportJSON = "json"
+	exportCSV  = "csv"
+	exportXLSX = "xlsx"
+
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// exportFormat determines the response format from the format query parameter, falling back to
+// the Accept header.  JSON is returned by default.
+func exportFormat(r *http.Request) (string, error) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		switch strings.ToLower(f) {
+		case exportJSON, exportCSV, exportXLSX:
+			return strings.ToLower(f), nil
+		default:
+			msg := fmt.Sprintf("invalid format '%s', valid values json, csv, xlsx", f)
+			return "", apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+	}
+
+	for _, a := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(a))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "text/csv":
+			return exportCSV, nil
+		case xlsxContentType:
+			return exportXLSX, nil
+		case "application/json":
+			return exportJSON, nil
+		}
+	}
+
+	return exportJSON, nil
+}
+
+// costTable is a flattened representation of cost and usage results with one row per period and
+// group and two columns (amount and unit) per metric
+type costTable struct {
+	header []string
+	rows   [][]string
+
+	// numeric marks the columns that are written as numbers in a spreadsheet
+	numeric []bool
+}
+
+// newCostTable flattens the cost and usage results into a table.  The group columns are named
+// after the groupBy values and the metric columns are the union of the metrics in the results.
+func newCostTable(results []*costexplorer.ResultByTime, groupBy []string) *costTable {
+	metricSet := map[string]struct{}{}
+	groupColumns := len(groupBy)
+	for _, r := range results {
+		for m := range r.Total {
+			metricSet[m] = struct{}{}
+		}
+
+		for _, g := range r.Groups {
+			for m := range g.Metrics {
+				metricSet[m] = struct{}{}
+			}
+
+			if len(g.Keys) > groupColumns {
+				groupColumns = len(g.Keys)
+			}
+		}
+	}
+
+	metrics := make([]string, 0, len(metricSet))
+	for m := range metricSet {
+		metrics = append(metrics, m)
+	}
+	sort.Strings(metrics)
+
+	t := &costTable{header: []string{"Start", "End"}}
+	for i := 0; i < groupColumns; i++ {
+		if i < len(groupBy) {
+			t.header = append(t.header, groupBy[i])
+		} else {
+			t.header = append(t.header, fmt.Sprintf("Group%d", i+1))
+		}
+	}
+	t.header = append(t.header, "Estimated")
+
+	t.numeric = make([]bool, len(t.header))
+	for _, m := range metrics {
+		t.header = append(t.header, m, m+"Unit")
+		t.numeric = append(t.numeric, true, false)
+	}
+
+	row := func(r *costexplorer.ResultByTime, keys []string, values map[string]*costexplorer.MetricValue) []string {
+		var start, end string
+		if r.TimePeriod != nil {
+			start, end = aws.StringValue(r.TimePeriod.Start), aws.StringValue(r.TimePeriod.End)
+		}
+
+		out := []string{start, end}
+		for i := 0; i < groupColumns; i++ {
+			var k string
+			if i < len(keys) {
+				k = keys[i]
+			}
+			out = append(out, k)
+		}
+		out = append(out, strconv.FormatBool(aws.BoolValue(r.Estimated)))
+
+		for _, m := range metrics {
+			var amount, unit string
+			if v, ok := values[m]; ok && v != nil {
+				amount, unit = aws.StringValue(v.Amount), aws.StringValue(v.Unit)
+			}
+			out = append(out, amount, unit)
+		}
+
+		return out
+	}
+
+	for _, r := range results {
+		if len(r.Groups) == 0 {
+			t.rows = append(t.rows, row(r, nil, r.Total))
+			continue
+		}
+
+		for _, g := range r.Groups {
+			t.rows = append(t.rows, row(r, aws.StringValueSlice(g.Keys), g.Metrics))
+		}
+	}
+
+	return t
+}
+
+// writeCSV writes the table as CSV.  Cells that a spreadsheet would evaluate as a formula are
+// escaped, see csvCell.
+func (t *costTable) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	header := make([]string, len(t.header))
+	for i, h := range t.header {
+		header[i] = csvCell(h, false)
+	}
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range t.rows {
+		row := make([]string, len(r))
+		for i, v := range r {
+			row[i] = csvCell(v, i < len(t.numeric) && t.numeric[i])
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell escapes a cell starting with a formula character (=, +, -, @, tab or carriage return)
+// with a leading single quote to prevent CSV injection.  Numbers in numeric columns (ie. negative
+// amounts) are left as is.
+func csvCell(v string, numeric bool) string {
+	if v == "" || !strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return v
+	}
+
+	if numeric {
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return v
+		}
+	}
+
+	return "'" + v
+}
+
+// writeXLSX writes the table as a minimal single sheet XLSX workbook
+func (t *costTable) writeXLSX(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	parts := []struct {
+		name, content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(f, p.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+
+	if err := t.writeSheet(f); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeSheet writes the worksheet XML.  Strings are written inline and the numeric columns are
+// written as numbers when they can be parsed.
+func (t *costTable) writeSheet(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return err
+	}
+
+	writeRow := func(n int, values []string, numeric []bool) error {
+		var b strings.Builder
+		fmt.Fprintf(&b, `<row r="%d">`, n)
+		for i, v := range values {
+			ref := fmt.Sprintf("%s%d", xlsxColumn(i), n)
+			if numeric != nil && numeric[i] {
+				if _, err := strconv.ParseFloat(v, 64); err == nil {
+					fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, v)
+					continue
+				}
+			}
+
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>`, ref)
+			if err := xml.EscapeText(&b, []byte(v)); err != nil {
+				return err
+			}
+			b.WriteString(`</t></is></c>`)
+		}
+		b.WriteString(`</row>`)
+
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	if err := writeRow(1, t.header, nil); err != nil {
+		return err
+	}
+
+	for i, r := range t.rows {
+		if err := writeRow(i+2, r, t.numeric); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, `</sheetData></worksheet>`)
+	return err
+}
+
+// xlsxColumn returns the spreadsheet column name (A, B, ..., Z, AA, ...) for a zero based index
+func xlsxColumn(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// writeCostExport writes the cost and usage results in the given format as an attachment
+func writeCostExport(w http.ResponseWriter, format, filename string, results []*costexplorer.ResultByTime, groupBy []string) {
+	t := newCostTable(results, groupBy)
+
+	contentType := "text/csv"
+	write := t.writeCSV
+	if format == exportXLSX {
+		contentType = xlsxContentType
+		write = t.writeXLSX
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	w.WriteHeader(http.StatusOK)
+
+	if err := write(w); err != nil {
+		log.Errorf("failed to write %s export: %s", format, err)
+	}
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Costs" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+var testExportResults = []*costexplorer.ResultByTime{
+	{
+		Estimated: aws.Bool(false),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String("2021-04-01"),
+			End:   aws.String("2021-05-01"),
+		},
+		Groups: []*costexplorer.Group{
+			{
+				Keys: aws.StringSlice([]string{"Amazon Elastic Compute Cloud - Compute"}),
+				Metrics: map[string]*costexplorer.MetricValue{
+					"UnblendedCost": {Amount: aws.String("12.5"), Unit: aws.String("USD")},
+				},
+			},
+			{
+				Keys: aws.StringSlice([]string{"Amazon Simple Storage Service, \"S3\""}),
+				Metrics: map[string]*costexplorer.MetricValue{
+					"UnblendedCost": {Amount: aws.String("0.25"), Unit: aws.String("USD")},
+				},
+			},
+		},
+	},
+	{
+		Estimated: aws.Bool(true),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String("2021-05-01"),
+			End:   aws.String("2021-05-15"),
+		},
+		Groups: []*costexplorer.Group{
+			{
+				Keys: aws.StringSlice([]string{"Amazon Elastic Compute Cloud - Compute"}),
+				Metrics: map[string]*costexplorer.MetricValue{
+					"UnblendedCost": {Amount: aws.String("6"), Unit: aws.String("USD")},
+				},
+			},
+		},
+	},
+}
+
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		url     string
+		accept  string
+		want    string
+		wantErr bool
+	}{
+		{"/", "", exportJSON, false},
+		{"/", "application/json", exportJSON, false},
+		{"/", "text/csv", exportCSV, false},
+		{"/", "text/csv; charset=utf-8", exportCSV, false},
+		{"/", xlsxContentType, exportXLSX, false},
+		{"/", "text/html,application/xhtml+xml,*/*;q=0.8", exportJSON, false},
+		{"/?format=csv", "", exportCSV, false},
+		{"/?format=XLSX", "", exportXLSX, false},
+		{"/?format=json", "text/csv", exportJSON, false},
+		{"/?format=pdf", "", "", true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.url, nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+
+		got, err := exportFormat(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("exportFormat(%s, %s) error = %v, wantErr %v", tt.url, tt.accept, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("exportFormat(%s, %s) = %s, want %s", tt.url, tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNewCostTable(t *testing.T) {
+	table := newCostTable(testExportResults, []string{"SERVICE"})
+
+	expectedHeader := []string{"Start", "End", "SERVICE", "Estimated", "UnblendedCost", "UnblendedCostUnit"}
+	if !reflect.DeepEqual(table.header, expectedHeader) {
+		t.Errorf("expected header %v, got %v", expectedHeader, table.header)
+	}
+
+	expectedRows := [][]string{
+		{"2021-04-01", "2021-05-01", "Amazon Elastic Compute Cloud - Compute", "false", "12.5", "USD"},
+		{"2021-04-01", "2021-05-01", "Amazon Simple Storage Service, \"S3\"", "false", "0.25", "USD"},
+		{"2021-05-01", "2021-05-15", "Amazon Elastic Compute Cloud - Compute", "true", "6", "USD"},
+	}
+	if !reflect.DeepEqual(table.rows, expectedRows) {
+		t.Errorf("expected rows %v, got %v", expectedRows, table.rows)
+	}
+
+	expectedNumeric := []bool{false, false, false, false, true, false}
+	if !reflect.DeepEqual(table.numeric, expectedNumeric) {
+		t.Errorf("expected numeric columns %v, got %v", expectedNumeric, table.numeric)
+	}
+
+	// ungrouped results use the total
+	table = newCostTable([]*costexplorer.ResultByTime{
+		{
+			TimePeriod: &costexplorer.DateInterval{Start: aws.String("2021-04-01"), End: aws.String("2021-05-01")},
+			Total: map[string]*costexplorer.MetricValue{
+				"UsageQuantity": {Amount: aws.String("3"), Unit: aws.String("N/A")},
+				"BlendedCost":   {Amount: aws.String("1.5"), Unit: aws.String("USD")},
+			},
+		},
+	}, nil)
+
+	expectedHeader = []string{"Start", "End", "Estimated", "BlendedCost", "BlendedCostUnit", "UsageQuantity", "UsageQuantityUnit"}
+	if !reflect.DeepEqual(table.header, expectedHeader) {
+		t.Errorf("expected header %v, got %v", expectedHeader, table.header)
+	}
+
+	expectedRows = [][]string{{"2021-04-01", "2021-05-01", "false", "1.5", "USD", "3", "N/A"}}
+	if !reflect.DeepEqual(table.rows, expectedRows) {
+		t.Errorf("expected rows %v, got %v", expectedRows, table.rows)
+	}
+}
+
+func TestCostTableWriteCSV(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := newCostTable(testExportResults, []string{"SERVICE"}).writeCSV(&buf); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := `Start,End,SERVICE,Estimated,UnblendedCost,UnblendedCostUnit
+2021-04-01,2021-05-01,Amazon Elastic Compute Cloud - Compute,false,12.5,USD
+2021-04-01,2021-05-01,"Amazon Simple Storage Service, ""S3""",false,0.25,USD
+2021-05-01,2021-05-15,Amazon Elastic Compute Cloud - Compute,true,6,USD
+`
+	if buf.String() != expected {
+		t.Errorf("expected csv:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestCostTableWriteCSVInjection(t *testing.T) {
+	results := []*costexplorer.ResultByTime{
+		{
+			TimePeriod: &costexplorer.DateInterval{Start: aws.String("2021-04-01"), End: aws.String("2021-05-01")},
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    aws.StringSlice([]string{"=HYPERLINK(\"http://example.com\")"}),
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": {Amount: aws.String("-1.5"), Unit: aws.String("USD")}},
+				},
+				{
+					Keys:    aws.StringSlice([]string{"+1+1"}),
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": {Amount: aws.String("=1+1"), Unit: aws.String("@USD")}},
+				},
+				{
+					Keys:    aws.StringSlice([]string{"-2+3"}),
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": {Amount: aws.String("0"), Unit: aws.String("\tUSD")}},
+				},
+				{
+					Keys:    aws.StringSlice([]string{"\rcmd"}),
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": {Amount: aws.String("1"), Unit: aws.String("USD")}},
+				},
+			},
+		},
+	}
+
+	buf := bytes.Buffer{}
+	if err := newCostTable(results, []string{"TAG:@owner"}).writeCSV(&buf); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := "Start,End,TAG:@owner,Estimated,UnblendedCost,UnblendedCostUnit\n" +
+		"2021-04-01,2021-05-01,\"'=HYPERLINK(\"\"http://example.com\"\")\",false,-1.5,USD\n" +
+		"2021-04-01,2021-05-01,'+1+1,false,'=1+1,'@USD\n" +
+		"2021-04-01,2021-05-01,'-2+3,false,0,'\tUSD\n" +
+		"2021-04-01,2021-05-01,\"'\rcmd\",false,1,USD\n"
+	if buf.String() != expected {
+		t.Errorf("expected csv:\n%q\ngot:\n%q", expected, buf.String())
+	}
+}
+
+func TestCostTableWriteXLSX(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := newCostTable(testExportResults, []string{"SERVICE"}).writeXLSX(&buf); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected valid zip, got %s", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %s", f.Name, err)
+		}
+
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", f.Name, err)
+		}
+
+		// every part must be well formed xml
+		d := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			if _, err := d.Token(); err != nil {
+				if err != io.EOF {
+					t.Errorf("invalid xml in %s: %s", f.Name, err)
+				}
+				break
+			}
+		}
+
+		files[f.Name] = string(b)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in xlsx", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, s := range []string{
+		`<c r="A1" t="inlineStr"><is><t>Start</t></is></c>`,
+		`<c r="E2"><v>12.5</v></c>`,
+		`<c r="F2" t="inlineStr"><is><t>USD</t></is></c>`,
+		`<t>Amazon Simple Storage Service, &#34;S3&#34;</t>`,
+		`<row r="4">`,
+	} {
+		if !strings.Contains(sheet, s) {
+			t.Errorf("expected sheet to contain %s", s)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestWriteCostExport(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeCostExport(w, exportCSV, "spc-123-cost", testExportResults, []string{"SERVICE"})
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected text/csv content type, got %s", ct)
+	}
+
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="spc-123-cost.csv"` {
+		t.Errorf("unexpected content disposition %s", cd)
+	}
+
+	w = httptest.NewRecorder()
+	writeCostExport(w, exportXLSX, "spc-123-cost", testExportResults, []string{"SERVICE"})
+
+	if ct := w.Header().Get("Content-Type"); ct != xlsxContentType {
+		t.Errorf("expected %s content type, got %s", xlsxContentType, ct)
+	}
+
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("PK")) {
+		t.Errorf("expected xlsx body to be a zip file")
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/YaleSpinup/cost-api/resourcegroupstaggingapi"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// SpaceAnomaliesGetHandler gets the cost anomalies for the services used by a space.  By default,
+// it returns anomalies from the last 90 days.
+func (s *server) SpaceAnomaliesGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	queries := r.URL.Query()
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	inventorySession, err := s.assumeRole(
+		r.Context(),
+		s.session.ExternalID,
+		role,
+		"",
+		"arn:aws:iam::aws:policy/AWSResourceGroupsReadOnlyAccess",
+	)
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	inventoryOrch := newInventoryOrchestrator(
+		resourcegroupstaggingapi.New(resourcegroupstaggingapi.WithSession(inventorySession.Session)),
+		s.org,
+	)
+
+	inventory, err := inventoryOrch.GetResourceInventory(r.Context(), account, spaceID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, expire, err := orch.getAnomaliesForSpace(
+		r.Context(),
+		&anomaliesReq{
+			account: account,
+			spaceID: spaceID,
+			start:   queries.Get("start"),
+			end:     queries.Get("end"),
+		},
+		inventory,
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Items", strconv.Itoa(len(out)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
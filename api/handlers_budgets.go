@@ -19,7 +19,7 @@ func (s *server) SpaceBudgetsCreatehandler(w http.ResponseWriter, r *http.Reques
 	spaceID := vars["space"]
 
 	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
-	policy, err := budgetReadWritePolicy()
+	policy, err := budgetReadWritePolicy(s.budgetActionRoleArn(account))
 	if err != nil {
 		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
 		return
@@ -44,6 +44,11 @@ func (s *server) SpaceBudgetsCreatehandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if err := s.setBudgetActionDefaults(r.Context(), account, spaceID, req.Actions); err != nil {
+		handleError(w, err)
+		return
+	}
+
 	orch := newBudgetsOrchestrator(
 		budgets.New(budgets.WithSession(session.Session)),
 		sns.New(sns.WithSession(session.Session)),
@@ -75,7 +80,7 @@ func (s *server) SpaceBudgetsListHandler(w http.ResponseWriter, r *http.Request)
 	spaceID := vars["space"]
 
 	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
-	policy, err := budgetReadWritePolicy()
+	policy, err := budgetReadWritePolicy("")
 	if err != nil {
 		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
 		return
@@ -125,7 +130,7 @@ func (s *server) SpaceBudgetsShowHandler(w http.ResponseWriter, r *http.Request)
 	budget := vars["budget"]
 
 	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
-	policy, err := budgetReadWritePolicy()
+	policy, err := budgetReadWritePolicy("")
 	if err != nil {
 		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
 		return
@@ -167,6 +172,243 @@ func (s *server) SpaceBudgetsShowHandler(w http.ResponseWriter, r *http.Request)
 	w.Write(j)
 }
 
+// SpaceBudgetsHistoryHandler gets the budgeted versus actual amounts of a budget for each period
+func (s *server) SpaceBudgetsHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+	budget := vars["budget"]
+	queries := r.URL.Query()
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := budgetReadWritePolicy("")
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	session, err := s.assumeRole(
+		r.Context(),
+		s.session.ExternalID,
+		role,
+		policy,
+	)
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	orch := newBudgetsOrchestrator(
+		budgets.New(budgets.WithSession(session.Session)),
+		nil,
+		s.org,
+	)
+
+	out, err := orch.GetBudgetHistory(r.Context(), account, spaceID, budget, queries.Get("start"), queries.Get("end"))
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceBudgetActionsListHandler lists the actions for a budget
+func (s *server) SpaceBudgetActionsListHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+	budget := vars["budget"]
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := budgetReadWritePolicy("")
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	session, err := s.assumeRole(
+		r.Context(),
+		s.session.ExternalID,
+		role,
+		policy,
+	)
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	orch := newBudgetsOrchestrator(
+		budgets.New(budgets.WithSession(session.Session)),
+		nil,
+		s.org,
+	)
+
+	out, err := orch.ListBudgetActions(r.Context(), account, spaceID, budget)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceBudgetActionsExecuteHandler executes an action for a budget, ie. approves a pending action
+func (s *server) SpaceBudgetActionsExecuteHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+	budget := vars["budget"]
+	action := vars["action"]
+	execution := vars["execution"]
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := budgetReadWritePolicy(s.budgetActionRoleArn(account))
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	session, err := s.assumeRole(
+		r.Context(),
+		s.session.ExternalID,
+		role,
+		policy,
+	)
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	// the instances of the action are refreshed from the space before it's approved or retried
+	inventory, err := s.spaceInventory(r.Context(), account, spaceID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	orch := newBudgetsOrchestrator(
+		budgets.New(budgets.WithSession(session.Session)),
+		nil,
+		s.org,
+	)
+
+	out, err := orch.ExecuteBudgetAction(r.Context(), account, spaceID, budget, action, execution, inventory)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceBudgetsUpdateHandler updates the amount and the alerts of a budget in place, adds actions to the
+// budget and refreshes the instances of its actions
+func (s *server) SpaceBudgetsUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+	budget := vars["budget"]
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := budgetReadWritePolicy(s.budgetActionRoleArn(account))
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	session, err := s.assumeRole(
+		r.Context(),
+		s.session.ExternalID,
+		role,
+		policy,
+	)
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	req := BudgetUpdateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		msg := fmt.Sprintf("cannot decode body into update budget input: %s", err)
+		handleError(w, apierror.New(apierror.ErrBadRequest, msg, err))
+		return
+	}
+
+	if err := s.setBudgetActionDefaults(r.Context(), account, spaceID, req.Actions); err != nil {
+		handleError(w, err)
+		return
+	}
+
+	// the inventory is only needed to refresh the actions when budget actions are configured
+	var inventory []*InventoryResponse
+	if s.budgetActionRole != "" {
+		inventory, err = s.spaceInventory(r.Context(), account, spaceID)
+		if err != nil {
+			handleError(w, err)
+			return
+		}
+	}
+
+	orch := newBudgetsOrchestrator(
+		budgets.New(budgets.WithSession(session.Session)),
+		nil,
+		s.org,
+	)
+
+	out, err := orch.UpdateBudget(r.Context(), account, spaceID, budget, &req, inventory)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
 func (s *server) SpaceBudgetsDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	w = LogWriter{w}
 	vars := mux.Vars(r)
@@ -175,7 +417,7 @@ func (s *server) SpaceBudgetsDeleteHandler(w http.ResponseWriter, r *http.Reques
 	budget := vars["budget"]
 
 	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
-	policy, err := budgetReadWritePolicy()
+	policy, err := budgetReadWritePolicy("")
 	if err != nil {
 		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
 		return
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// ChargebackCreateHandler generates the chargeback report for a completed month in an account
+// and returns the manifest of the signed report artifacts
+func (s *server) ChargebackCreateHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	month := vars["month"]
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, err := orch.generateChargebackReport(r.Context(), &chargebackReq{
+		account: account,
+		month:   month,
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(j)
+}
+
+// ChargebackGetHandler gets a stored chargeback report as JSON (default) or CSV.  The signature
+// of the report is verified and returned in the X-Report-Signature header.
+func (s *server) ChargebackGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	month := vars["month"]
+
+	format, err := exportFormat(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	out, artifact, err := s.getChargebackArtifact(r.Context(), account, month, format)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Report-Signature", fmt.Sprintf("%s=%s", chargebackSignatureAlgorithm, artifact.Signature))
+	w.Header().Set("Content-Type", artifact.ContentType)
+	if format != exportJSON {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chargeback-%s-%s.%s"`, account, month, format))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommitmentsGetHandler gets the utilization or coverage report for savings plans or
+// reserved instances in an account.  By default, it pulls data from the start of the
+// month until now.
+func (s *server) CommitmentsGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	commitment := vars["commitment"]
+	report := vars["report"]
+
+	queries := r.URL.Query()
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, expire, err := orch.getCommitmentsReport(
+		r.Context(),
+		&commitmentsReq{
+			account:     account,
+			commitment:  commitment,
+			report:      report,
+			start:       queries.Get("start"),
+			end:         queries.Get("end"),
+			granularity: queries.Get("granularity"),
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
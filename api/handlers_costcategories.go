@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// CostCategoriesListHandler lists the cost category definitions in an account
+func (s *server) CostCategoriesListHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, expire, err := orch.listCostCategories(r.Context(), account)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Items", strconv.Itoa(len(out)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// CostCategoryValuesGetHandler gets the values of a cost category in an account.  By default,
+// it gets the values from the start of the month until now.
+func (s *server) CostCategoryValuesGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	name := vars["name"]
+
+	queries := r.URL.Query()
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, expire, err := orch.getCostCategoryValues(
+		r.Context(),
+		&costCategoryValuesReq{
+			account: account,
+			name:    name,
+			start:   queries.Get("start"),
+			end:     queries.Get("end"),
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Items", strconv.Itoa(len(out)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// SpaceDigestsCreateHandler subscribes to the scheduled cost digest for a space
+func (s *server) SpaceDigestsCreateHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	if s.digestStore == nil {
+		handleError(w, apierror.New(apierror.ErrBadRequest, "cost digests are not configured", nil))
+		return
+	}
+
+	req := DigestSubscriptionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		msg := fmt.Sprintf("cannot decode body into create digest subscription input: %s", err)
+		handleError(w, apierror.New(apierror.ErrBadRequest, msg, err))
+		return
+	}
+
+	if err := validateDigestRequest(account, &req); err != nil {
+		handleError(w, err)
+		return
+	}
+
+	out := &DigestSubscription{
+		ID:        uuid.New().String(),
+		Account:   account,
+		Space:     spaceID,
+		Type:      req.Type,
+		Endpoint:  req.Endpoint,
+		Frequency: req.Frequency,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := s.digestStore.put(out); err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(j)
+}
+
+// SpaceDigestsListHandler lists the cost digest subscriptions for a space
+func (s *server) SpaceDigestsListHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	if s.digestStore == nil {
+		handleError(w, apierror.New(apierror.ErrBadRequest, "cost digests are not configured", nil))
+		return
+	}
+
+	out := s.digestStore.list(account, spaceID)
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Items", fmt.Sprintf("%d", len(out)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceDigestsShowHandler gets a cost digest subscription for a space
+func (s *server) SpaceDigestsShowHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+	id := vars["digest"]
+
+	if s.digestStore == nil {
+		handleError(w, apierror.New(apierror.ErrBadRequest, "cost digests are not configured", nil))
+		return
+	}
+
+	out, err := s.digestStore.get(account, spaceID, id)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceDigestsUpdateHandler updates the delivery type, endpoint and frequency of a cost digest subscription
+func (s *server) SpaceDigestsUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+	id := vars["digest"]
+
+	if s.digestStore == nil {
+		handleError(w, apierror.New(apierror.ErrBadRequest, "cost digests are not configured", nil))
+		return
+	}
+
+	req := DigestSubscriptionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		msg := fmt.Sprintf("cannot decode body into update digest subscription input: %s", err)
+		handleError(w, apierror.New(apierror.ErrBadRequest, msg, err))
+		return
+	}
+
+	if err := validateDigestRequest(account, &req); err != nil {
+		handleError(w, err)
+		return
+	}
+
+	out, err := s.digestStore.update(account, spaceID, id, func(sub *DigestSubscription) {
+		sub.Type = req.Type
+		sub.Endpoint = req.Endpoint
+		sub.Frequency = req.Frequency
+		sub.LastError = ""
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceDigestsDeleteHandler unsubscribes from a cost digest for a space
+func (s *server) SpaceDigestsDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+	id := vars["digest"]
+
+	if s.digestStore == nil {
+		handleError(w, apierror.New(apierror.ErrBadRequest, "cost digests are not configured", nil))
+		return
+	}
+
+	if err := s.digestStore.delete(account, spaceID, id); err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
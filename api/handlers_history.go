@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// SpaceHistoryGetHandler gets the historical cost of a space from the collected cost snapshots
+func (s *server) SpaceHistoryGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	q := r.URL.Query()
+	out, err := s.getSpaceHistory(&historyReq{
+		account:     account,
+		spaceID:     spaceID,
+		start:       q.Get("start"),
+		end:         q.Get("end"),
+		granularity: q.Get("granularity"),
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
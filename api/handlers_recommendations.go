@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/YaleSpinup/cost-api/resourcegroupstaggingapi"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// PurchaseRecommendationsGetHandler gets the savings plans or reserved instance purchase
+// recommendations for an account.  By default, it gets one year, no upfront compute savings
+// plans recommendations based on the last thirty days of usage.
+func (s *server) PurchaseRecommendationsGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+
+	queries := r.URL.Query()
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, expire, err := orch.getPurchaseRecommendations(
+		r.Context(),
+		&purchaseRecommendationsReq{
+			account:          account,
+			kind:             queries.Get("type"),
+			savingsPlansType: queries.Get("savingsPlansType"),
+			service:          queries.Get("service"),
+			term:             queries.Get("term"),
+			payment:          queries.Get("payment"),
+			lookback:         queries.Get("lookback"),
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceRightsizingGetHandler gets the cost explorer rightsizing recommendations for the EC2 instances
+// in a space, with the estimated monthly savings for each instance.
+func (s *server) SpaceRightsizingGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	queries := r.URL.Query()
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	inventorySession, err := s.assumeRole(
+		r.Context(),
+		s.session.ExternalID,
+		role,
+		"",
+		"arn:aws:iam::aws:policy/AWSResourceGroupsReadOnlyAccess",
+	)
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	inventoryOrch := newInventoryOrchestrator(
+		resourcegroupstaggingapi.New(resourcegroupstaggingapi.WithSession(inventorySession.Session)),
+		s.org,
+	)
+
+	inventory, err := inventoryOrch.GetResourceInventory(r.Context(), account, spaceID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	benefitsConsidered := true
+	if b := queries.Get("benefits"); b != "" {
+		benefitsConsidered, err = strconv.ParseBool(b)
+		if err != nil {
+			msg := fmt.Sprintf("invalid benefits value '%s'", b)
+			handleError(w, apierror.New(apierror.ErrBadRequest, msg, err))
+			return
+		}
+	}
+
+	out, cached, expire, err := orch.getRightsizingForSpace(
+		r.Context(),
+		&rightsizingReq{
+			account:            account,
+			spaceID:            spaceID,
+			target:             queries.Get("target"),
+			benefitsConsidered: benefitsConsidered,
+		},
+		inventory,
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Items", strconv.Itoa(len(out)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
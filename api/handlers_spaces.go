@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
 	"github.com/gorilla/mux"
 
 	log "github.com/sirupsen/logrus"
@@ -20,7 +22,101 @@ func (s *server) SpaceGetHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := vars["start"]
 	endTime := vars["end"]
 	spaceID := vars["space"]
-	groupBy := vars["groupby"]
+	granularity := vars["granularity"]
+
+	groupBy := queryValues(r, "groupby")
+	metrics := queryValues(r, "metric")
+	costCategories := queryValues(r, "costCategory")
+
+	format, err := exportFormat(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+
+	out, cached, expire, err := orch.getCostAndUsageForSpace(
+		r.Context(),
+		&costAndUsageReq{
+			account:        account,
+			spaceID:        spaceID,
+			start:          startTime,
+			end:            endTime,
+			groupBy:        groupBy,
+			granularity:    granularity,
+			metrics:        metrics,
+			costCategories: costCategories,
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	if format != exportJSON {
+		writeCostExport(w, format, fmt.Sprintf("%s-cost", spaceID), out, groupBy)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+
+}
+
+// SpaceQueryHandler queries the cost for a space with the filter expression in the request body.
+// The filter is always ANDed with the space filter.  By default, it pulls data from the start of
+// the month until now.
+func (s *server) SpaceQueryHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	req := CostQueryRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		msg := fmt.Sprintf("cannot decode body into cost query input: %s", err)
+		handleError(w, apierror.New(apierror.ErrBadRequest, msg, err))
+		return
+	}
+
+	var filter *costexplorer.Expression
+	if req.Filter != nil {
+		var err error
+		if filter, err = compileFilter(req.Filter); err != nil {
+			handleError(w, apierror.New(apierror.ErrBadRequest, err.Error(), err))
+			return
+		}
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
 
 	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
 	policy, err := costExplorerReadPolicy()
@@ -33,15 +129,297 @@ func (s *server) SpaceGetHandler(w http.ResponseWriter, r *http.Request) {
 		inlinePolicy: policy,
 		role:         role,
 	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
 
 	out, cached, expire, err := orch.getCostAndUsageForSpace(
 		r.Context(),
 		&costAndUsageReq{
+			account:     account,
+			spaceID:     spaceID,
+			start:       req.Start,
+			end:         req.End,
+			groupBy:     req.GroupBy,
+			granularity: req.Granularity,
+			metrics:     req.Metrics,
+			filter:      filter,
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	if format != exportJSON {
+		writeCostExport(w, format, fmt.Sprintf("%s-cost", spaceID), out, req.GroupBy)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceForecastGetHandler gets the cost forecast for a space.  By default, it forecasts
+// the unblended cost from today until the end of the month.
+func (s *server) SpaceForecastGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	queries := r.URL.Query()
+
+	var interval int64
+	if i := queries.Get("interval"); i != "" {
+		var err error
+		if interval, err = strconv.ParseInt(i, 10, 64); err != nil {
+			msg := fmt.Sprintf("invalid prediction interval level '%s'", i)
+			handleError(w, apierror.New(apierror.ErrBadRequest, msg, err))
+			return
+		}
+	}
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, expire, err := orch.getCostForecastForSpace(
+		r.Context(),
+		&costForecastReq{
+			account:            account,
+			spaceID:            spaceID,
+			start:              queries.Get("start"),
+			end:                queries.Get("end"),
+			granularity:        queries.Get("granularity"),
+			metric:             queries.Get("metric"),
+			predictionInterval: interval,
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceUsageForecastGetHandler gets the usage forecast for a usage type group in a space.  By
+// default, it forecasts the EC2 running hours from today until the end of the month.
+func (s *server) SpaceUsageForecastGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	queries := r.URL.Query()
+
+	var interval int64
+	if i := queries.Get("interval"); i != "" {
+		var err error
+		if interval, err = strconv.ParseInt(i, 10, 64); err != nil {
+			msg := fmt.Sprintf("invalid prediction interval level '%s'", i)
+			handleError(w, apierror.New(apierror.ErrBadRequest, msg, err))
+			return
+		}
+	}
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, expire, err := orch.getUsageForecastForSpace(
+		r.Context(),
+		&usageForecastReq{
+			account:            account,
+			spaceID:            spaceID,
+			start:              queries.Get("start"),
+			end:                queries.Get("end"),
+			granularity:        queries.Get("granularity"),
+			metric:             queries.Get("metric"),
+			usageTypeGroup:     queries.Get("usageTypeGroup"),
+			predictionInterval: interval,
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceResourcesGetHandler gets the cost for a space over the last 14 days, grouped by
+// resource id.  By default, it returns the daily cost of EC2 instances.
+func (s *server) SpaceResourcesGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	queries := r.URL.Query()
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, expire, err := orch.getResourceCostsForSpace(
+		r.Context(),
+		&resourceCostReq{
+			account:     account,
+			spaceID:     spaceID,
+			service:     queries.Get("service"),
+			granularity: queries.Get("granularity"),
+			metrics:     queries["metric"],
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+	if cached {
+		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpacesGetHandler gets the cost for all of the spaces in the org.  By default, it pulls data
+// from the start of the month until now and returns the spaces sorted by cost, highest first.
+func (s *server) SpacesGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+
+	queries := r.URL.Query()
+
+	var top int
+	if t := queries.Get("top"); t != "" {
+		var err error
+		if top, err = strconv.Atoi(t); err != nil {
+			msg := fmt.Sprintf("invalid top '%s'", t)
+			handleError(w, apierror.New(apierror.ErrBadRequest, msg, err))
+			return
+		}
+	}
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	costs, cached, expire, err := orch.getCostForSpaces(
+		r.Context(),
+		&spacesCostReq{
 			account: account,
-			spaceID: spaceID,
-			start:   startTime,
-			end:     endTime,
-			groupBy: groupBy,
+			start:   queries.Get("start"),
+			end:     queries.Get("end"),
+			metric:  queries.Get("metric"),
 		},
 	)
 	if err != nil {
@@ -49,6 +427,12 @@ func (s *server) SpaceGetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	out, err := sortSpaceCosts(costs, queries.Get("sort"), queries.Get("order"), top)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
 	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
 	if cached {
 		w.Header().Set("X-Cache-Expire", fmt.Sprintf("%0.fs", expire.Seconds()))
@@ -61,8 +445,103 @@ func (s *server) SpaceGetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("X-Items", strconv.Itoa(len(out)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
+}
+
+// SpaceAccountsGetHandler gets the cost for a space in all of the configured accounts.  It supports
+// the same query parameters as SpaceGetHandler and returns the merged results with per-account totals.
+func (s *server) SpaceAccountsGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	spaceID := vars["space"]
+
+	groupBy := queryValues(r, "groupby")
+	metrics := queryValues(r, "metric")
+	costCategories := queryValues(r, "costCategory")
+
+	out, err := s.getCostAndUsageForSpaceInAccounts(
+		r.Context(),
+		&costAndUsageReq{
+			spaceID:        spaceID,
+			start:          vars["start"],
+			end:            vars["end"],
+			groupBy:        groupBy,
+			granularity:    vars["granularity"],
+			metrics:        metrics,
+			costCategories: costCategories,
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(j)
+}
 
+// SpaceCompareGetHandler compares the cost for a space in the current period to date with
+// the same span of the previous period, broken down by service.
+func (s *server) SpaceCompareGetHandler(w http.ResponseWriter, r *http.Request) {
+	w = LogWriter{w}
+	vars := mux.Vars(r)
+	account := s.mapAccountNumber(vars["account"])
+	spaceID := vars["space"]
+
+	queries := r.URL.Query()
+
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		handleError(w, apierror.New(apierror.ErrInternalError, "failed to generate policy", err))
+		return
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(r.Context(), &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		handleError(w, apierror.New(apierror.ErrForbidden, msg, nil))
+		return
+	}
+
+	out, cached, err := orch.compareCostsForSpace(
+		r.Context(),
+		&costComparisonReq{
+			account: account,
+			spaceID: spaceID,
+			period:  queries.Get("period"),
+			metric:  queries.Get("metric"),
+		},
+	)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("X-Cache-Hit", fmt.Sprintf("%t", cached))
+
+	j, err := json.Marshal(out)
+	if err != nil {
+		log.Errorf("cannot marshal response (%v) into JSON: %s", out, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(j)
 }
@@ -2,6 +2,8 @@ package api
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -116,6 +118,22 @@ func TestParseTime(t *testing.T) {
 
 }
 
+func TestQueryValues(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/cost/123/spaces/spc-1?groupby=TAG:team&groupby=&costCategory=Department:Research%2C+Teaching&costCategory=Env:prod", nil)
+
+	if out, expected := queryValues(r, "groupby"), []string{"TAG:team"}; !reflect.DeepEqual(expected, out) {
+		t.Errorf("expected %v, got %v", expected, out)
+	}
+
+	if out, expected := queryValues(r, "costCategory"), []string{"Department:Research, Teaching", "Env:prod"}; !reflect.DeepEqual(expected, out) {
+		t.Errorf("expected %v, got %v", expected, out)
+	}
+
+	if out := queryValues(r, "metric"); out != nil {
+		t.Errorf("expected nil, got %v", out)
+	}
+}
+
 func TestInSpace(t *testing.T) {
 	spaceIDS := []string{
 		"somespace-00012345",
@@ -204,3 +222,400 @@ func TestNotTryIT(t *testing.T) {
 		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
 	}
 }
+
+func TestParseForecastTime(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	firstOfNextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	// use defaults derived in code
+	start, end, err := parseForecastTime("", "")
+	if err != nil {
+		t.Errorf("unexpected error from parseForecastTime: %s", err)
+	}
+
+	if start != today.Format("2006-01-02") {
+		t.Errorf("expected default start %s, got %s", today.Format("2006-01-02"), start)
+	}
+
+	if end != firstOfNextMonth.Format("2006-01-02") {
+		t.Errorf("expected default end %s, got %s", firstOfNextMonth.Format("2006-01-02"), end)
+	}
+
+	// explicit future range
+	s := today.AddDate(0, 0, 1).Format("2006-01-02")
+	e := today.AddDate(0, 2, 0).Format("2006-01-02")
+	start, end, err = parseForecastTime(s, e)
+	if err != nil {
+		t.Errorf("unexpected error from parseForecastTime: %s", err)
+	}
+
+	if start != s || end != e {
+		t.Errorf("expected %s - %s, got %s - %s", s, e, start, end)
+	}
+
+	// start in the past fails
+	if _, _, err := parseForecastTime("2019-11-01", e); err == nil {
+		t.Error("expected error for start time in the past, got nil")
+	}
+
+	// end before start fails
+	if _, _, err := parseForecastTime(e, s); err == nil {
+		t.Error("expected error for end time before start time, got nil")
+	}
+
+	// bad date fails
+	if _, _, err := parseForecastTime("", "2006-13-40"); err == nil {
+		t.Error("expected error for invalid end time, got nil")
+	}
+}
+
+func TestValidForecastMetric(t *testing.T) {
+	for _, m := range []string{"UNBLENDED_COST", "BLENDED_COST", "AMORTIZED_COST", "NET_AMORTIZED_COST", "NET_UNBLENDED_COST"} {
+		if !validForecastMetric(m) {
+			t.Errorf("expected %s to be a valid forecast metric", m)
+		}
+	}
+
+	for _, m := range []string{"", "USAGE_QUANTITY", "NORMALIZED_USAGE_AMOUNT", "unblended_cost"} {
+		if validForecastMetric(m) {
+			t.Errorf("expected %s to be an invalid forecast metric", m)
+		}
+	}
+}
+
+func TestValidateForecastOptions(t *testing.T) {
+	tests := []struct {
+		granularity string
+		interval    int64
+		wantErr     bool
+	}{
+		{"MONTHLY", 80, false},
+		{"DAILY", 51, false},
+		{"DAILY", 99, false},
+		{"HOURLY", 80, true},
+		{"", 80, true},
+		{"MONTHLY", 50, true},
+		{"MONTHLY", 100, true},
+	}
+
+	for _, tt := range tests {
+		if err := validateForecastOptions(tt.granularity, tt.interval); (err != nil) != tt.wantErr {
+			t.Errorf("validateForecastOptions(%s, %d) error = %v, wantErr %v", tt.granularity, tt.interval, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateGranularity(t *testing.T) {
+	today := time.Now().UTC()
+	recent := today.AddDate(0, 0, -7).Format("2006-01-02")
+	old := today.AddDate(0, 0, -30).Format("2006-01-02")
+	end := today.Format("2006-01-02")
+
+	tests := []struct {
+		name        string
+		granularity string
+		start       string
+		end         string
+		wantErr     bool
+	}{
+		{name: "monthly", granularity: "MONTHLY", start: "2019-01-01", end: "2020-12-31"},
+		{name: "daily", granularity: "DAILY", start: "2019-01-01", end: "2019-03-31"},
+		{name: "daily too long", granularity: "DAILY", start: "2019-01-01", end: "2020-12-31", wantErr: true},
+		{name: "hourly", granularity: "HOURLY", start: recent, end: end},
+		{name: "hourly too old", granularity: "HOURLY", start: old, end: end, wantErr: true},
+		{name: "invalid granularity", granularity: "WEEKLY", start: "2019-01-01", end: "2019-03-31", wantErr: true},
+		{name: "invalid start", granularity: "DAILY", start: "2019-13-01", end: "2019-03-31", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateGranularity(tt.granularity, tt.start, tt.end); (err != nil) != tt.wantErr {
+				t.Errorf("validateGranularity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidCostMetric(t *testing.T) {
+	for _, m := range []string{"BLENDED_COST", "UNBLENDED_COST", "AMORTIZED_COST", "NET_AMORTIZED_COST", "NET_UNBLENDED_COST", "USAGE_QUANTITY", "NORMALIZED_USAGE_AMOUNT"} {
+		if !validCostMetric(m) {
+			t.Errorf("expected %s to be a valid cost metric", m)
+		}
+	}
+
+	for _, m := range []string{"", "BlendedCost", "COST"} {
+		if validCostMetric(m) {
+			t.Errorf("expected %s to be an invalid cost metric", m)
+		}
+	}
+}
+
+func TestSortedKey(t *testing.T) {
+	metrics := []string{"USAGE_QUANTITY", "AMORTIZED_COST", "BLENDED_COST"}
+	expected := "AMORTIZED_COST,BLENDED_COST,USAGE_QUANTITY"
+	if out := sortedKey(metrics); out != expected {
+		t.Errorf("expected %s, got %s", expected, out)
+	}
+
+	// the passed list should not be reordered
+	if metrics[0] != "USAGE_QUANTITY" {
+		t.Errorf("expected metrics list to be unmodified, got %v", metrics)
+	}
+
+	// cost categories share the key format
+	if out := sortedKey([]string{"team", "project"}); out != "project,team" {
+		t.Errorf("expected project,team, got %s", out)
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy []string
+		want    []*costexplorer.GroupDefinition
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			groupBy: nil,
+			want:    []*costexplorer.GroupDefinition{},
+		},
+		{
+			name:    "dimension",
+			groupBy: []string{"SERVICE"},
+			want: []*costexplorer.GroupDefinition{
+				{Key: aws.String("SERVICE"), Type: aws.String("DIMENSION")},
+			},
+		},
+		{
+			name:    "resource name",
+			groupBy: []string{"RESOURCE_NAME"},
+			want: []*costexplorer.GroupDefinition{
+				{Key: aws.String("Name"), Type: aws.String("TAG")},
+			},
+		},
+		{
+			name:    "dimension and tag",
+			groupBy: []string{"SERVICE", "TAG:spinup:owner"},
+			want: []*costexplorer.GroupDefinition{
+				{Key: aws.String("SERVICE"), Type: aws.String("DIMENSION")},
+				{Key: aws.String("spinup:owner"), Type: aws.String("TAG")},
+			},
+		},
+		{
+			name:    "cost category",
+			groupBy: []string{"COST_CATEGORY:Department"},
+			want: []*costexplorer.GroupDefinition{
+				{Key: aws.String("Department"), Type: aws.String("COST_CATEGORY")},
+			},
+		},
+		{
+			name:    "too many",
+			groupBy: []string{"SERVICE", "REGION", "TAG:Name"},
+			wantErr: true,
+		},
+		{
+			name:    "empty tag key",
+			groupBy: []string{"TAG:"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate",
+			groupBy: []string{"RESOURCE_NAME", "TAG:Name"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGroupBy(tt.groupBy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseGroupBy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !awsutil.DeepEqual(got, tt.want) {
+				t.Errorf("parseGroupBy() = %s, want %s", awsutil.Prettify(got), awsutil.Prettify(tt.want))
+			}
+		})
+	}
+}
+
+func TestParseCostCategories(t *testing.T) {
+	tests := []struct {
+		name           string
+		costCategories []string
+		want           []*costexplorer.Expression
+		wantErr        bool
+	}{
+		{
+			name:           "empty",
+			costCategories: nil,
+			want:           []*costexplorer.Expression{},
+		},
+		{
+			name:           "single",
+			costCategories: []string{"Department:Research"},
+			want: []*costexplorer.Expression{
+				{CostCategories: &costexplorer.CostCategoryValues{Key: aws.String("Department"), Values: aws.StringSlice([]string{"Research"})}},
+			},
+		},
+		{
+			name:           "multiple values and categories",
+			costCategories: []string{"Project:Alpha", "Department:Research", "Department:Teaching"},
+			want: []*costexplorer.Expression{
+				{CostCategories: &costexplorer.CostCategoryValues{Key: aws.String("Department"), Values: aws.StringSlice([]string{"Research", "Teaching"})}},
+				{CostCategories: &costexplorer.CostCategoryValues{Key: aws.String("Project"), Values: aws.StringSlice([]string{"Alpha"})}},
+			},
+		},
+		{
+			name:           "value with colon",
+			costCategories: []string{"Department:Research:Lab"},
+			want: []*costexplorer.Expression{
+				{CostCategories: &costexplorer.CostCategoryValues{Key: aws.String("Department"), Values: aws.StringSlice([]string{"Research:Lab"})}},
+			},
+		},
+		{
+			name:           "missing value",
+			costCategories: []string{"Department:"},
+			wantErr:        true,
+		},
+		{
+			name:           "missing separator",
+			costCategories: []string{"Department"},
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCostCategories(tt.costCategories)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseCostCategories() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !awsutil.DeepEqual(got, tt.want) {
+				t.Errorf("parseCostCategories() = %s, want %s", awsutil.Prettify(got), awsutil.Prettify(tt.want))
+			}
+		})
+	}
+}
+
+func TestToSpaceCosts(t *testing.T) {
+	results := []*costexplorer.ResultByTime{
+		{
+			Groups: []*costexplorer.Group{
+				{
+					Keys: []*string{aws.String("spinup:spaceid$space-1")},
+					Metrics: map[string]*costexplorer.MetricValue{
+						"UnblendedCost": {Amount: aws.String("1.5"), Unit: aws.String("USD")},
+					},
+				},
+				{
+					Keys: []*string{aws.String("spinup:spaceid$")},
+					Metrics: map[string]*costexplorer.MetricValue{
+						"UnblendedCost": {Amount: aws.String("100"), Unit: aws.String("USD")},
+					},
+				},
+			},
+		},
+		{
+			Groups: []*costexplorer.Group{
+				{
+					Keys: []*string{aws.String("spinup:spaceid$space-1")},
+					Metrics: map[string]*costexplorer.MetricValue{
+						"UnblendedCost": {Amount: aws.String("2.5"), Unit: aws.String("USD")},
+					},
+				},
+				{
+					Keys: []*string{aws.String("spinup:spaceid$space-2")},
+					Metrics: map[string]*costexplorer.MetricValue{
+						"UnblendedCost": {Amount: aws.String("3"), Unit: aws.String("USD")},
+					},
+				},
+			},
+		},
+	}
+
+	expected := []*SpaceCost{
+		{Space: "space-1", Amount: 4, Unit: "USD"},
+		{Space: "space-2", Amount: 3, Unit: "USD"},
+	}
+
+	out, err := toSpaceCosts(results, "UNBLENDED_COST")
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, out) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	// bad amount
+	results[0].Groups[0].Metrics["UnblendedCost"].Amount = aws.String("foo")
+	if _, err := toSpaceCosts(results, "UNBLENDED_COST"); err == nil {
+		t.Error("expected error for invalid amount, got nil")
+	}
+}
+
+func TestMetricResponseKey(t *testing.T) {
+	tests := map[string]string{
+		"UNBLENDED_COST":          "UnblendedCost",
+		"NET_AMORTIZED_COST":      "NetAmortizedCost",
+		"USAGE_QUANTITY":          "UsageQuantity",
+		"NORMALIZED_USAGE_AMOUNT": "NormalizedUsageAmount",
+	}
+
+	for metric, expected := range tests {
+		if out := metricResponseKey(metric); out != expected {
+			t.Errorf("expected %s, got %s", expected, out)
+		}
+	}
+}
+
+func TestSortSpaceCosts(t *testing.T) {
+	costs := []*SpaceCost{
+		{Space: "b", Amount: 2},
+		{Space: "c", Amount: 3},
+		{Space: "a", Amount: 1},
+	}
+
+	tests := []struct {
+		name    string
+		sortBy  string
+		order   string
+		top     int
+		want    []string
+		wantErr bool
+	}{
+		{name: "defaults", want: []string{"c", "b", "a"}},
+		{name: "amount asc", sortBy: "amount", order: "asc", want: []string{"a", "b", "c"}},
+		{name: "space", sortBy: "space", want: []string{"a", "b", "c"}},
+		{name: "space desc", sortBy: "space", order: "desc", want: []string{"c", "b", "a"}},
+		{name: "top 2", top: 2, want: []string{"c", "b"}},
+		{name: "top more than list", top: 10, want: []string{"c", "b", "a"}},
+		{name: "invalid sort", sortBy: "foo", wantErr: true},
+		{name: "invalid order", order: "up", wantErr: true},
+		{name: "negative top", top: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sortSpaceCosts(costs, tt.sortBy, tt.order, tt.top)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sortSpaceCosts() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			spaces := []string{}
+			for _, c := range got {
+				spaces = append(spaces, c.Space)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(spaces, tt.want) {
+				t.Errorf("sortSpaceCosts() = %v, want %v", spaces, tt.want)
+			}
+		})
+	}
+
+	// the original list should not be reordered
+	if costs[0].Space != "b" {
+		t.Errorf("expected costs to be unmodified, got %+v", costs)
+	}
+}
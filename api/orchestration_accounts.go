@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxAccountConcurrency is the maximum number of accounts queried at the same time
+var MaxAccountConcurrency = 5
+
+// AccountFanOutTimeout is the time allowed to query all of the accounts, it's shorter than the server
+// write timeout so a slow fan out returns an error instead of the connection being dropped
+var AccountFanOutTimeout = 12 * time.Second
+
+// getCostAndUsageForSpaceInAccounts fans out the space cost query to all of the accounts in the accounts
+// map and merges the results.  Accounts with more than one name are only queried once.  Each account query is cached individually by getCostAndUsageForSpace.
+func (s *server) getCostAndUsageForSpaceInAccounts(ctx context.Context, req *costAndUsageReq) (*MultiAccountCostResponse, error) {
+	if len(s.accountsMap) == 0 {
+		return nil, apierror.New(apierror.ErrBadRequest, "no accounts are configured", nil)
+	}
+
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		return nil, apierror.New(apierror.ErrInternalError, "failed to generate policy", err)
+	}
+
+	names := uniqueAccountNames(s.accountsMap)
+
+	ctx, cancel := context.WithTimeout(ctx, AccountFanOutTimeout)
+	defer cancel()
+
+	results := make([][]*costexplorer.ResultByTime, len(names))
+	sem := make(chan struct{}, MaxAccountConcurrency)
+
+	// keep the first error, the remaining queries are cancelled
+	var firstErr error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			account := s.accountsMap[name]
+			log.Debugf("getting cost and usage for space %s in account %s (%s)", req.spaceID, name, account)
+
+			orch, err := s.newCostExplorerOrchestrator(ctx, &sessionParams{
+				inlinePolicy: policy,
+				role:         fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName),
+			})
+			if err != nil {
+				msg := fmt.Sprintf("failed to assume role in account: %s", account)
+				fail(apierror.New(apierror.ErrForbidden, msg, nil))
+				return
+			}
+
+			// copy the request so defaults set by the orchestrator don't race
+			accountReq := *req
+			accountReq.account = account
+
+			out, _, _, err := orch.getCostAndUsageForSpace(ctx, &accountReq)
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			results[i] = out
+		}(i, name)
+	}
+	wg.Wait()
+
+	// queries failing because the deadline passed are reported as the timeout
+	if ctx.Err() == context.DeadlineExceeded {
+		msg := fmt.Sprintf("cost and usage request for %d accounts timed out after %s", len(names), AccountFanOutTimeout)
+		return nil, apierror.New(apierror.ErrServiceUnavailable, msg, ctx.Err())
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, apierror.New(apierror.ErrInternalError, "cost and usage request was cancelled", err)
+	}
+
+	resp := &MultiAccountCostResponse{
+		Accounts: []*AccountCost{},
+	}
+
+	for i, name := range names {
+		total, err := sumResultsByTime(results[i])
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Accounts = append(resp.Accounts, &AccountCost{
+			Name:      name,
+			AccountID: s.accountsMap[name],
+			Total:     total,
+		})
+	}
+
+	merged, err := mergeCostResults(results...)
+	if err != nil {
+		return nil, err
+	}
+	resp.ResultsByTime = merged
+
+	return resp, nil
+}
+
+// uniqueAccountNames returns the sorted names in the accounts map with one name for each account
+// number, the first name (sorted) is used for accounts with more than one name
+func uniqueAccountNames(accountsMap map[string]string) []string {
+	all := make([]string, 0, len(accountsMap))
+	for name := range accountsMap {
+		all = append(all, name)
+	}
+	sort.Strings(all)
+
+	names := []string{}
+	seen := map[string]struct{}{}
+	for _, name := range all {
+		if _, ok := seen[accountsMap[name]]; ok {
+			continue
+		}
+		seen[accountsMap[name]] = struct{}{}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// mergeCostResults merges the lists of results into a single list, adding together the totals for
+// each time period and the metrics for groups with matching keys
+func mergeCostResults(results ...[]*costexplorer.ResultByTime) ([]*costexplorer.ResultByTime, error) {
+	merged := []*costexplorer.ResultByTime{}
+	byPeriod := map[string]*costexplorer.ResultByTime{}
+	groups := map[string]map[string]*costexplorer.Group{}
+
+	for _, list := range results {
+		for _, r := range list {
+			period := timePeriodKey(r.TimePeriod)
+
+			m, ok := byPeriod[period]
+			if !ok {
+				m = &costexplorer.ResultByTime{
+					Estimated:  aws.Bool(false),
+					Groups:     []*costexplorer.Group{},
+					TimePeriod: r.TimePeriod,
+					Total:      map[string]*costexplorer.MetricValue{},
+				}
+				byPeriod[period] = m
+				groups[period] = map[string]*costexplorer.Group{}
+				merged = append(merged, m)
+			}
+
+			if aws.BoolValue(r.Estimated) {
+				m.Estimated = aws.Bool(true)
+			}
+
+			if err := addMetrics(m.Total, r.Total); err != nil {
+				return nil, err
+			}
+
+			for _, g := range r.Groups {
+				key := strings.Join(aws.StringValueSlice(g.Keys), "|")
+
+				mg, ok := groups[period][key]
+				if !ok {
+					mg = &costexplorer.Group{
+						Keys:    g.Keys,
+						Metrics: map[string]*costexplorer.MetricValue{},
+					}
+					groups[period][key] = mg
+					m.Groups = append(m.Groups, mg)
+				}
+
+				if err := addMetrics(mg.Metrics, g.Metrics); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return timePeriodKey(merged[i].TimePeriod) < timePeriodKey(merged[j].TimePeriod)
+	})
+
+	return merged, nil
+}
+
+// sumResultsByTime adds up the metrics across all time periods.  When results are grouped, cost
+// explorer doesn't return a total so the group metrics are added up instead.
+func sumResultsByTime(results []*costexplorer.ResultByTime) (map[string]*costexplorer.MetricValue, error) {
+	total := map[string]*costexplorer.MetricValue{}
+	for _, r := range results {
+		if len(r.Total) > 0 {
+			if err := addMetrics(total, r.Total); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, g := range r.Groups {
+			if err := addMetrics(total, g.Metrics); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// addMetrics adds the amounts of the metrics in src to the metrics in dst
+func addMetrics(dst, src map[string]*costexplorer.MetricValue) error {
+	for name, v := range src {
+		amount, err := strconv.ParseFloat(aws.StringValue(v.Amount), 64)
+		if err != nil {
+			msg := fmt.Sprintf("failed to parse amount '%s' for metric %s", aws.StringValue(v.Amount), name)
+			return apierror.New(apierror.ErrInternalError, msg, err)
+		}
+
+		existing, ok := dst[name]
+		if !ok {
+			dst[name] = &costexplorer.MetricValue{
+				Amount: aws.String(strconv.FormatFloat(amount, 'f', -1, 64)),
+				Unit:   v.Unit,
+			}
+			continue
+		}
+
+		current, err := strconv.ParseFloat(aws.StringValue(existing.Amount), 64)
+		if err != nil {
+			msg := fmt.Sprintf("failed to parse amount '%s' for metric %s", aws.StringValue(existing.Amount), name)
+			return apierror.New(apierror.ErrInternalError, msg, err)
+		}
+
+		existing.Amount = aws.String(strconv.FormatFloat(current+amount, 'f', -1, 64))
+	}
+
+	return nil
+}
+
+func timePeriodKey(period *costexplorer.DateInterval) string {
+	if period == nil {
+		return ""
+	}
+	return aws.StringValue(period.Start) + "/" + aws.StringValue(period.End)
+}
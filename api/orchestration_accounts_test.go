@@ -0,0 +1,161 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+func testMetric(amount string) *costexplorer.MetricValue {
+	return &costexplorer.MetricValue{
+		Amount: aws.String(amount),
+		Unit:   aws.String("USD"),
+	}
+}
+
+func TestUniqueAccountNames(t *testing.T) {
+	accountsMap := map[string]string{
+		"spinup":      "012345678901",
+		"spinupdev":   "111111111111",
+		"spinup-prod": "012345678901",
+		"default":     "222222222222",
+	}
+
+	expected := []string{"default", "spinup", "spinupdev"}
+	if out := uniqueAccountNames(accountsMap); !reflect.DeepEqual(expected, out) {
+		t.Errorf("expected %v, got %v", expected, out)
+	}
+
+	if out := uniqueAccountNames(map[string]string{}); len(out) != 0 {
+		t.Errorf("expected no names, got %v", out)
+	}
+}
+
+func TestMergeCostResults(t *testing.T) {
+	april := &costexplorer.DateInterval{Start: aws.String("2021-04-01"), End: aws.String("2021-05-01")}
+	may := &costexplorer.DateInterval{Start: aws.String("2021-05-01"), End: aws.String("2021-05-15")}
+
+	account1 := []*costexplorer.ResultByTime{
+		{
+			Estimated:  aws.Bool(false),
+			TimePeriod: april,
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    []*string{aws.String("Amazon Simple Storage Service")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("1.25")},
+				},
+			},
+		},
+		{
+			Estimated:  aws.Bool(true),
+			TimePeriod: may,
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    []*string{aws.String("Amazon Simple Storage Service")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("0.5")},
+				},
+			},
+		},
+	}
+
+	account2 := []*costexplorer.ResultByTime{
+		{
+			Estimated:  aws.Bool(false),
+			TimePeriod: april,
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    []*string{aws.String("Amazon Simple Storage Service")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("2.25")},
+				},
+				{
+					Keys:    []*string{aws.String("Amazon Elastic Compute Cloud - Compute")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("10")},
+				},
+			},
+		},
+	}
+
+	expected := []*costexplorer.ResultByTime{
+		{
+			Estimated:  aws.Bool(false),
+			TimePeriod: april,
+			Total:      map[string]*costexplorer.MetricValue{},
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    []*string{aws.String("Amazon Simple Storage Service")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("3.5")},
+				},
+				{
+					Keys:    []*string{aws.String("Amazon Elastic Compute Cloud - Compute")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("10")},
+				},
+			},
+		},
+		{
+			Estimated:  aws.Bool(true),
+			TimePeriod: may,
+			Total:      map[string]*costexplorer.MetricValue{},
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    []*string{aws.String("Amazon Simple Storage Service")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("0.5")},
+				},
+			},
+		},
+	}
+
+	out, err := mergeCostResults(account2, account1)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if !awsutil.DeepEqual(expected, out) {
+		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+
+	// bad amount
+	bad := []*costexplorer.ResultByTime{
+		{
+			TimePeriod: april,
+			Total:      map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("foo")},
+		},
+	}
+	if _, err := mergeCostResults(bad); err == nil {
+		t.Error("expected error for invalid amount, got nil")
+	}
+}
+
+func TestSumResultsByTime(t *testing.T) {
+	results := []*costexplorer.ResultByTime{
+		{
+			Total: map[string]*costexplorer.MetricValue{
+				"UnblendedCost": testMetric("1.5"),
+				"BlendedCost":   testMetric("1"),
+			},
+		},
+		{
+			Total: map[string]*costexplorer.MetricValue{},
+			Groups: []*costexplorer.Group{
+				{Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("2")}},
+				{Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("0.5")}},
+			},
+		},
+	}
+
+	expected := map[string]*costexplorer.MetricValue{
+		"UnblendedCost": testMetric("4"),
+		"BlendedCost":   testMetric("1"),
+	}
+
+	out, err := sumResultsByTime(results)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if !awsutil.DeepEqual(expected, out) {
+		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type anomaliesReq struct {
+	account, spaceID, start, end string
+}
+
+// anomalyDays is the default number of days of anomalies returned
+const anomalyDays = 90
+
+// costExplorerServices maps the service in a resource ARN to the names of the services in cost explorer
+var costExplorerServices = map[string][]string{
+	"apigateway":           {"Amazon API Gateway"},
+	"cloudfront":           {"Amazon CloudFront"},
+	"datasync":             {"AWS DataSync"},
+	"dynamodb":             {"Amazon DynamoDB"},
+	"ec2":                  {"Amazon Elastic Compute Cloud - Compute", "EC2 - Other", "Amazon Virtual Private Cloud"},
+	"ecr":                  {"Amazon EC2 Container Registry (ECR)"},
+	"ecs":                  {"Amazon Elastic Container Service"},
+	"elasticache":          {"Amazon ElastiCache"},
+	"elasticfilesystem":    {"Amazon Elastic File System"},
+	"elasticloadbalancing": {"Amazon Elastic Load Balancing"},
+	"es":                   {"Amazon OpenSearch Service", "Amazon Elasticsearch Service"},
+	"fsx":                  {"Amazon FSx"},
+	"kms":                  {"AWS Key Management Service"},
+	"lambda":               {"AWS Lambda"},
+	"logs":                 {"AmazonCloudWatch"},
+	"rds":                  {"Amazon Relational Database Service", "Amazon DocumentDB (with MongoDB compatibility)"},
+	"route53":              {"Amazon Route 53"},
+	"s3":                   {"Amazon Simple Storage Service"},
+	"secretsmanager":       {"AWS Secrets Manager"},
+	"sns":                  {"Amazon Simple Notification Service"},
+	"sqs":                  {"Amazon Simple Queue Service"},
+	"transfer":             {"AWS Transfer Family"},
+}
+
+// getAnomaliesForSpace gets the cost anomalies in the account and filters them to the anomalies that
+// can be attributed to the space, see filterAnomalies.  The anomalies for the account are cached.
+func (o *costExplorerOrchestrator) getAnomaliesForSpace(ctx context.Context, req *anomaliesReq, inventory []*InventoryResponse) ([]*costexplorer.Anomaly, bool, time.Duration, error) {
+	start, end, err := parseAnomalyTime(req.start, req.end)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	input := costexplorer.GetAnomaliesInput{
+		DateInterval: &costexplorer.AnomalyDateInterval{
+			StartDate: aws.String(start),
+		},
+	}
+
+	if end != "" {
+		input.DateInterval.EndDate = aws.String(end)
+	}
+
+	usage, err := o.getSpaceUsage(ctx, req.account, req.spaceID, start, end)
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	cacheKey := fmt.Sprintf("anomalies_%s_%s_%s", req.account, start, end)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	var anomalies []*costexplorer.Anomaly
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and anomalies-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetAnomalies(ctx, &input)
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return filterAnomalies(out, req.account, inventory, usage), false, 0, nil
+	}
+
+	anomalies, ok = c.([]*costexplorer.Anomaly)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a []*costexplorer.Anomaly!")
+	}
+
+	log.Debugf("found cached object: %s", anomalies)
+
+	return filterAnomalies(anomalies, req.account, inventory, usage), true, time.Until(expire), nil
+}
+
+// spaceUsageKey is a day the space had cost for a service and usage type, an empty usage type
+// is used for the cost of the service
+type spaceUsageKey struct {
+	service, usageType, day string
+}
+
+// getSpaceUsage gets the days the space had cost (using the space cost allocation tag) for each
+// service and usage type between the start and end dates.  The range is limited to the maximum
+// range for daily granularity, anomalies before the range can't be attributed to the space.
+func (o *costExplorerOrchestrator) getSpaceUsage(ctx context.Context, account, spaceID, start, end string) (map[spaceUsageKey]struct{}, error) {
+	usage := map[spaceUsageKey]struct{}{}
+
+	endStamp := time.Now().UTC().Truncate(24 * time.Hour)
+	if end != "" {
+		e, err := time.Parse("2006-01-02", end)
+		if err != nil {
+			return nil, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+		}
+
+		// the anomaly end date is inclusive
+		if e = e.AddDate(0, 0, 1); e.Before(endStamp) {
+			endStamp = e
+		}
+	}
+
+	startStamp, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return nil, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	if endStamp.Sub(startStamp) > maxDailyRange {
+		startStamp = endStamp.Add(-maxDailyRange)
+	}
+
+	// there is no cost data yet
+	if !endStamp.After(startStamp) {
+		return usage, nil
+	}
+
+	results, _, _, err := o.getCostAndUsageForSpace(ctx, &costAndUsageReq{
+		account:     account,
+		spaceID:     spaceID,
+		start:       startStamp.Format("2006-01-02"),
+		end:         endStamp.Format("2006-01-02"),
+		granularity: costexplorer.GranularityDaily,
+		groupBy:     []string{costexplorer.DimensionService, costexplorer.DimensionUsageType},
+		metrics:     []string{costexplorer.MetricUnblendedCost},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metricKey := metricResponseKey(costexplorer.MetricUnblendedCost)
+	for _, r := range results {
+		if r.TimePeriod == nil {
+			continue
+		}
+		day := aws.StringValue(r.TimePeriod.Start)
+
+		for _, g := range r.Groups {
+			if len(g.Keys) != 2 {
+				continue
+			}
+
+			m, ok := g.Metrics[metricKey]
+			if !ok {
+				continue
+			}
+
+			if amount, err := strconv.ParseFloat(aws.StringValue(m.Amount), 64); err != nil || amount == 0 {
+				continue
+			}
+
+			service := aws.StringValue(g.Keys[0])
+			usage[spaceUsageKey{service: service, usageType: aws.StringValue(g.Keys[1]), day: day}] = struct{}{}
+			usage[spaceUsageKey{service: service, day: day}] = struct{}{}
+		}
+	}
+
+	return usage, nil
+}
+
+// filterAnomalies returns the anomalies that can be attributed to the space.  An anomaly is attributed
+// to the space when one of its root causes is in the account, in the services and regions of the space
+// inventory and the space had cost (by the space cost allocation tag) for the root cause service and
+// usage type while the anomaly was active.  Anomalies without root causes can't be attributed.  The
+// anomalies are copies with only the root causes attributed to the space, the impact is cleared since
+// it's the cost of the anomaly across the account.
+func filterAnomalies(anomalies []*costexplorer.Anomaly, account string, inventory []*InventoryResponse, usage map[spaceUsageKey]struct{}) []*costexplorer.Anomaly {
+	// map of cost explorer service name to the regions the space has resources in,
+	// an empty region is used for global resources (ie. s3 buckets)
+	services := map[string]map[string]struct{}{}
+	for _, i := range inventory {
+		for _, name := range costExplorerServices[i.Service] {
+			if _, ok := services[name]; !ok {
+				services[name] = map[string]struct{}{}
+			}
+			services[name][i.Region] = struct{}{}
+		}
+	}
+
+	inSpace := func(service, region string) bool {
+		regions, ok := services[service]
+		if !ok {
+			return false
+		}
+
+		if region == "" {
+			return true
+		}
+
+		_, global := regions[""]
+		_, regional := regions[region]
+		return global || regional
+	}
+
+	filtered := []*costexplorer.Anomaly{}
+	for _, a := range anomalies {
+		start, end, ok := anomalyPeriod(a)
+		if !ok {
+			continue
+		}
+
+		rootCauses := []*costexplorer.RootCause{}
+		for _, rc := range a.RootCauses {
+			if la := aws.StringValue(rc.LinkedAccount); la != "" && la != account {
+				continue
+			}
+
+			service := aws.StringValue(rc.Service)
+			if !inSpace(service, aws.StringValue(rc.Region)) {
+				continue
+			}
+
+			if spaceHadUsage(usage, service, aws.StringValue(rc.UsageType), start, end) {
+				rootCauses = append(rootCauses, rc)
+			}
+		}
+
+		if len(rootCauses) == 0 {
+			continue
+		}
+
+		// the cached anomalies are shared between spaces, don't modify them
+		out := *a
+		out.RootCauses = rootCauses
+		out.Impact = nil
+		filtered = append(filtered, &out)
+	}
+
+	return filtered
+}
+
+// spaceHadUsage returns true if the space had cost for the service and usage type on any day from
+// start through end
+func spaceHadUsage(usage map[spaceUsageKey]struct{}, service, usageType string, start, end time.Time) bool {
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if _, ok := usage[spaceUsageKey{service: service, usageType: usageType, day: d.Format("2006-01-02")}]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// anomalyPeriod returns the first and last day of the anomaly, anomalies that haven't ended are
+// active through today
+func anomalyPeriod(a *costexplorer.Anomaly) (time.Time, time.Time, bool) {
+	day := func(s string) (time.Time, error) {
+		if len(s) > 10 {
+			s = s[:10]
+		}
+		return time.Parse("2006-01-02", s)
+	}
+
+	start, err := day(aws.StringValue(a.AnomalyStartDate))
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	if e := aws.StringValue(a.AnomalyEndDate); e != "" {
+		if end, err = day(e); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	return start, end, true
+}
+
+// parseAnomalyTime returns the start date anomalyDays ago and an empty end date if the passed values
+// are empty, otherwise it parses the strings and returns the values (or an error)
+func parseAnomalyTime(start, end string) (string, string, error) {
+	if start == "" {
+		start = time.Now().UTC().AddDate(0, 0, -anomalyDays).Format("2006-01-02")
+	}
+
+	startStamp, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return "", "", err
+	}
+
+	if end == "" {
+		return startStamp.Format("2006-01-02"), "", nil
+	}
+
+	endStamp, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !endStamp.After(startStamp) {
+		return "", "", fmt.Errorf("end time should be after start time")
+	}
+
+	return startStamp.Format("2006-01-02"), endStamp.Format("2006-01-02"), nil
+}
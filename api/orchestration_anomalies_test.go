@@ -0,0 +1,175 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+func TestFilterAnomalies(t *testing.T) {
+	inventory := []*InventoryResponse{
+		{Service: "ec2", Region: "us-east-1", AccountID: "0123456789"},
+		{Service: "s3", Region: "", AccountID: "0123456789"},
+	}
+
+	usage := map[spaceUsageKey]struct{}{
+		{service: "Amazon Elastic Compute Cloud - Compute", usageType: "USE1-BoxUsage:t3.micro", day: "2021-05-10"}: {},
+		{service: "Amazon Elastic Compute Cloud - Compute", day: "2021-05-10"}:                                      {},
+		{service: "Amazon Simple Storage Service", usageType: "USW2-TimedStorage-ByteHrs", day: "2021-05-11"}:       {},
+		{service: "Amazon Simple Storage Service", day: "2021-05-11"}:                                               {},
+	}
+
+	ec2 := "Amazon Elastic Compute Cloud - Compute"
+	anomalies := []*costexplorer.Anomaly{
+		{
+			AnomalyId:        aws.String("ec2-us-east-1"),
+			AnomalyStartDate: aws.String("2021-05-09"),
+			AnomalyEndDate:   aws.String("2021-05-10"),
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String(ec2), Region: aws.String("us-east-1"), LinkedAccount: aws.String("0123456789"), UsageType: aws.String("USE1-BoxUsage:t3.micro")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("ec2-other-usage-type"),
+			AnomalyStartDate: aws.String("2021-05-09"),
+			AnomalyEndDate:   aws.String("2021-05-10"),
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String(ec2), Region: aws.String("us-east-1"), LinkedAccount: aws.String("0123456789"), UsageType: aws.String("USE1-BoxUsage:m5.24xlarge")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("ec2-before-usage"),
+			AnomalyStartDate: aws.String("2021-05-01T00:00:00Z"),
+			AnomalyEndDate:   aws.String("2021-05-03T00:00:00Z"),
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String(ec2), Region: aws.String("us-east-1"), UsageType: aws.String("USE1-BoxUsage:t3.micro")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("ec2-service-only"),
+			AnomalyStartDate: aws.String("2021-05-10T00:00:00Z"),
+			AnomalyEndDate:   aws.String("2021-05-10T00:00:00Z"),
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String(ec2), Region: aws.String("us-east-1")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("ec2-us-west-2"),
+			AnomalyStartDate: aws.String("2021-05-10"),
+			AnomalyEndDate:   aws.String("2021-05-10"),
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String(ec2), Region: aws.String("us-west-2")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("ec2-other-account"),
+			AnomalyStartDate: aws.String("2021-05-10"),
+			AnomalyEndDate:   aws.String("2021-05-10"),
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String(ec2), Region: aws.String("us-east-1"), LinkedAccount: aws.String("9876543210"), UsageType: aws.String("USE1-BoxUsage:t3.micro")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("s3-any-region"),
+			AnomalyStartDate: aws.String("2021-05-11"),
+			AnomalyEndDate:   aws.String("2021-05-12"),
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String("Amazon Simple Storage Service"), Region: aws.String("us-west-2"), UsageType: aws.String("USW2-TimedStorage-ByteHrs")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("rds"),
+			AnomalyStartDate: aws.String("2021-05-10"),
+			AnomalyEndDate:   aws.String("2021-05-10"),
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String("Amazon Relational Database Service"), Region: aws.String("us-east-1")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("mixed-root-causes"),
+			AnomalyStartDate: aws.String("2021-05-10"),
+			AnomalyEndDate:   aws.String("2021-05-10"),
+			Impact:           &costexplorer.Impact{MaxImpact: aws.Float64(480), TotalImpact: aws.Float64(907)},
+			RootCauses: []*costexplorer.RootCause{
+				{Service: aws.String(ec2), Region: aws.String("us-east-1"), LinkedAccount: aws.String("9876543210"), UsageType: aws.String("USE1-BoxUsage:t3.micro")},
+				{Service: aws.String(ec2), Region: aws.String("us-east-1"), LinkedAccount: aws.String("0123456789"), UsageType: aws.String("USE1-BoxUsage:t3.micro")},
+				{Service: aws.String("Amazon Relational Database Service"), Region: aws.String("us-east-1")},
+			},
+		},
+		{
+			AnomalyId:        aws.String("no-root-cause"),
+			AnomalyStartDate: aws.String("2021-05-10"),
+			AnomalyEndDate:   aws.String("2021-05-10"),
+			DimensionValue:   aws.String(ec2),
+		},
+	}
+
+	expected := []string{"ec2-us-east-1", "ec2-service-only", "s3-any-region", "mixed-root-causes"}
+
+	filtered := filterAnomalies(anomalies, "0123456789", inventory, usage)
+
+	out := []string{}
+	for _, a := range filtered {
+		out = append(out, aws.StringValue(a.AnomalyId))
+	}
+
+	if !reflect.DeepEqual(expected, out) {
+		t.Errorf("expected %v, got %v", expected, out)
+	}
+
+	// only the root causes attributed to the space are returned, without the account wide impact
+	mixed := filtered[len(filtered)-1]
+	expectedRootCauses := []*costexplorer.RootCause{anomalies[8].RootCauses[1]}
+	if !reflect.DeepEqual(mixed.RootCauses, expectedRootCauses) {
+		t.Errorf("expected root causes %+v, got %+v", expectedRootCauses, mixed.RootCauses)
+	}
+
+	if mixed.Impact != nil {
+		t.Errorf("expected impact to be cleared, got %+v", mixed.Impact)
+	}
+
+	// the anomalies are shared in the cache and aren't modified
+	if len(anomalies[8].RootCauses) != 3 || anomalies[8].Impact == nil {
+		t.Errorf("expected the anomaly to be unchanged, got %+v", anomalies[8])
+	}
+
+	// empty inventory matches nothing
+	if out := filterAnomalies(anomalies, "0123456789", nil, usage); len(out) != 0 {
+		t.Errorf("expected no anomalies for empty inventory, got %d", len(out))
+	}
+
+	// no space usage matches nothing
+	if out := filterAnomalies(anomalies, "0123456789", inventory, nil); len(out) != 0 {
+		t.Errorf("expected no anomalies without space usage, got %d", len(out))
+	}
+}
+
+func TestParseAnomalyTime(t *testing.T) {
+	start, end, err := parseAnomalyTime("", "")
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if expected := time.Now().UTC().AddDate(0, 0, -anomalyDays).Format("2006-01-02"); start != expected {
+		t.Errorf("expected default start %s, got %s", expected, start)
+	}
+
+	if end != "" {
+		t.Errorf("expected empty default end, got %s", end)
+	}
+
+	if start, end, err = parseAnomalyTime("2021-04-01", "2021-05-01"); err != nil || start != "2021-04-01" || end != "2021-05-01" {
+		t.Errorf("expected 2021-04-01 - 2021-05-01, got %s - %s (%v)", start, end, err)
+	}
+
+	if _, _, err := parseAnomalyTime("2021-05-01", "2021-04-01"); err == nil {
+		t.Error("expected error for end before start, got nil")
+	}
+
+	if _, _, err := parseAnomalyTime("2021-13-01", ""); err == nil {
+		t.Error("expected error for invalid start, got nil")
+	}
+}
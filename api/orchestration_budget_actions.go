@@ -0,0 +1,355 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/YaleSpinup/cost-api/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/budgets"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBudgetActionRegion is the region of the instances stopped by budget actions by default
+const defaultBudgetActionRegion = "us-east-1"
+
+// budgetActionExecutions maps the executions in the api to the budget action execution types
+var budgetActionExecutions = map[string]string{
+	"approve": budgets.ExecutionTypeApproveBudgetAction,
+	"retry":   budgets.ExecutionTypeRetryBudgetAction,
+	"reverse": budgets.ExecutionTypeReverseBudgetAction,
+	"reset":   budgets.ExecutionTypeResetBudgetAction,
+}
+
+// ListBudgetActions lists the actions for a budget in the space
+func (o *budgetsOrchestrator) ListBudgetActions(ctx context.Context, account, spaceID, budget string) ([]*BudgetAction, error) {
+	if !strings.HasPrefix(budget, budgetPrefix(o.org, spaceID)) {
+		return nil, apierror.New(apierror.ErrBadRequest, "budget doesn't belong to provided space", nil)
+	}
+
+	out, err := o.client.DescribeBudgetActions(ctx, account, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]*BudgetAction, len(out))
+	for i, a := range out {
+		actions[i] = toBudgetAction(a)
+	}
+
+	return actions, nil
+}
+
+// ExecuteBudgetAction executes an action for a budget in the space, ie. approves a PENDING action with
+// the MANUAL approval model, and returns the updated action.  The instances of an action are a snapshot
+// of the space, so they're refreshed from the space inventory before the action is approved or retried.
+func (o *budgetsOrchestrator) ExecuteBudgetAction(ctx context.Context, account, spaceID, budget, action, execution string, inventory []*InventoryResponse) (*BudgetAction, error) {
+	executionType, ok := budgetActionExecutions[strings.ToLower(execution)]
+	if !ok {
+		msg := fmt.Sprintf("invalid budget action execution '%s', valid values approve, retry, reverse, reset", execution)
+		return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if _, err := o.getBudgetAction(ctx, account, spaceID, budget, action); err != nil {
+		return nil, err
+	}
+
+	if executionType == budgets.ExecutionTypeApproveBudgetAction || executionType == budgets.ExecutionTypeRetryBudgetAction {
+		if err := o.refreshBudgetActions(ctx, account, budget, action, inventory); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := o.client.ExecuteBudgetAction(ctx, account, budget, action, executionType); err != nil {
+		return nil, err
+	}
+
+	return o.getBudgetAction(ctx, account, spaceID, budget, action)
+}
+
+// refreshBudgetActions updates the instances of the budget actions that stop instances to the instances
+// currently tagged with the space, or only the instances of the given action if it's not empty.  AWS
+// requires at least one instance, so an action is left as is when the space has no instances left, unless
+// it's the given action since executing it wouldn't stop anything in the space.
+func (o *budgetsOrchestrator) refreshBudgetActions(ctx context.Context, account, budget, action string, inventory []*InventoryResponse) error {
+	actions, err := o.client.DescribeBudgetActions(ctx, account, budget)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range actions {
+		id := aws.StringValue(a.ActionId)
+		if action != "" && id != action {
+			continue
+		}
+
+		if a.Definition == nil || a.Definition.SsmActionDefinition == nil {
+			continue
+		}
+
+		ssm := toBudgetAction(a).Ssm
+		ids := spaceInstanceIds(ssm, inventory)
+		if len(ids) == 0 {
+			if action != "" {
+				msg := fmt.Sprintf("no instances tagged with the space to stop for action %s", id)
+				return apierror.New(apierror.ErrBadRequest, msg, nil)
+			}
+
+			log.Warnf("no instances tagged with the space left for action %s of budget %s, leaving it as is", id, budget)
+			continue
+		}
+
+		if sortedKey(ids) == sortedKey(ssm.InstanceIds) {
+			continue
+		}
+
+		log.Infof("refreshing instances of action %s of budget %s from %v to %v", id, budget, ssm.InstanceIds, ids)
+
+		if err := o.client.UpdateBudgetAction(ctx, &budgets.UpdateBudgetActionInput{
+			AccountId:  aws.String(account),
+			ActionId:   aws.String(id),
+			BudgetName: aws.String(budget),
+			Definition: &budgets.Definition{
+				SsmActionDefinition: &budgets.SsmActionDefinition{
+					ActionSubType: aws.String(ssm.ActionSubType),
+					InstanceIds:   aws.StringSlice(ids),
+					Region:        aws.String(ssm.Region),
+				},
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getBudgetAction gets an action for a budget in the space
+func (o *budgetsOrchestrator) getBudgetAction(ctx context.Context, account, spaceID, budget, action string) (*BudgetAction, error) {
+	actions, err := o.ListBudgetActions(ctx, account, spaceID, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range actions {
+		if a.ID == action {
+			return a, nil
+		}
+	}
+
+	msg := fmt.Sprintf("action %s not found for budget %s", action, budget)
+	return nil, apierror.New(apierror.ErrNotFound, msg, nil)
+}
+
+// budgetActionRoleArn returns the arn of the configured budget action role in the account, or an empty
+// string if budget actions aren't configured
+func (s *server) budgetActionRoleArn(account string) string {
+	if s.budgetActionRole == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.budgetActionRole)
+}
+
+// setBudgetActionDefaults sets the execution role of the actions to the configured budget action role and
+// the instances of the actions that stop instances to the instances tagged with the space.  Callers can't
+// pass their own execution role or instances, the instances are refreshed from the space inventory.
+func (s *server) setBudgetActionDefaults(ctx context.Context, account, spaceID string, actions []*BudgetAction) error {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	if s.budgetActionRole == "" {
+		return apierror.New(apierror.ErrBadRequest, "budget actions are not configured", nil)
+	}
+
+	var inventory []*InventoryResponse
+	for _, a := range actions {
+		if a.ExecutionRoleArn != "" {
+			return apierror.New(apierror.ErrBadRequest, "ExecutionRoleArn can't be set, budget actions run with the configured budget action role", nil)
+		}
+		a.ExecutionRoleArn = s.budgetActionRoleArn(account)
+
+		if a.Ssm == nil {
+			continue
+		}
+
+		if len(a.Ssm.InstanceIds) > 0 {
+			return apierror.New(apierror.ErrBadRequest, "InstanceIds can't be set, budget actions stop all of the instances tagged with the space", nil)
+		}
+
+		if inventory == nil {
+			var err error
+			if inventory, err = s.spaceInventory(ctx, account, spaceID); err != nil {
+				return err
+			}
+		}
+
+		a.Ssm.InstanceIds = spaceInstanceIds(a.Ssm, inventory)
+
+		log.Infof("budget action %s for space %s stops instances tagged with the space: %v", a.Ssm.ActionSubType, spaceID, a.Ssm.InstanceIds)
+	}
+
+	return nil
+}
+
+// spaceInventory returns the resources tagged with the space, the budget action instances are resolved from it
+func (s *server) spaceInventory(ctx context.Context, account, spaceID string) ([]*InventoryResponse, error) {
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName)
+	session, err := s.assumeRole(
+		ctx,
+		s.session.ExternalID,
+		role,
+		"",
+		"arn:aws:iam::aws:policy/AWSResourceGroupsReadOnlyAccess",
+	)
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", account)
+		return nil, apierror.New(apierror.ErrForbidden, msg, nil)
+	}
+
+	orch := newInventoryOrchestrator(
+		resourcegroupstaggingapi.New(resourcegroupstaggingapi.WithSession(session.Session)),
+		s.org,
+	)
+
+	return orch.GetResourceInventory(ctx, account, spaceID)
+}
+
+// spaceInstanceIds returns the ids of the instances in the space inventory stopped by the ssm action.  EC2
+// instances are identified by their instance id and RDS instances by their identifier.
+func spaceInstanceIds(a *BudgetSsmAction, inventory []*InventoryResponse) []string {
+	region := a.Region
+	if region == "" {
+		region = defaultBudgetActionRegion
+	}
+
+	ids := []string{}
+	for _, i := range inventory {
+		if i.Region != region {
+			continue
+		}
+
+		switch {
+		case a.ActionSubType == budgets.ActionSubTypeStopEc2Instances && i.Service == "ec2" && strings.HasPrefix(i.Resource, "instance/"):
+			ids = append(ids, strings.TrimPrefix(i.Resource, "instance/"))
+		case a.ActionSubType == budgets.ActionSubTypeStopRdsInstances && i.Service == "rds" && strings.HasPrefix(i.Resource, "db:"):
+			ids = append(ids, strings.TrimPrefix(i.Resource, "db:"))
+		}
+	}
+
+	return ids
+}
+
+// validateBudgetAction sets the defaults of a budget action and validates it
+func validateBudgetAction(a *BudgetAction) error {
+	// policy actions could attach any policy to any principal or target, only stopping the
+	// instances in the space is supported
+	if a.ActionType != budgets.ActionTypeRunSsmDocuments {
+		msg := fmt.Sprintf("invalid action type '%s', only RUN_SSM_DOCUMENTS is supported", a.ActionType)
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if a.ApprovalModel == "" {
+		a.ApprovalModel = budgets.ApprovalModelManual
+	}
+
+	if err := validateOption("approval model", a.ApprovalModel, budgets.ApprovalModel_Values()); err != nil {
+		return err
+	}
+
+	if a.NotificationType == "" {
+		a.NotificationType = budgets.NotificationTypeActual
+	}
+
+	if !validNotificationType(a.NotificationType) {
+		msg := fmt.Sprintf("invalid notification type '%s', valid values %s", a.NotificationType, strings.Join(budgets.NotificationType_Values(), ", "))
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if a.ThresholdType == "" {
+		a.ThresholdType = budgets.ThresholdTypePercentage
+	}
+
+	if !validThresholdType(a.ThresholdType) {
+		msg := fmt.Sprintf("invalid threshold type '%s', valid values %s", a.ThresholdType, strings.Join(budgets.ThresholdType_Values(), ", "))
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if a.Threshold <= 0 {
+		return apierror.New(apierror.ErrBadRequest, "action threshold must be greater than 0", nil)
+	}
+
+	if len(a.Addresses) > 10 {
+		return apierror.New(apierror.ErrBadRequest, "up to 10 email addresses per action are supported", nil)
+	}
+
+	if a.ExecutionRoleArn == "" {
+		return apierror.New(apierror.ErrBadRequest, "budget actions are not configured", nil)
+	}
+
+	if a.IamPolicy != nil || a.ScpPolicy != nil {
+		return apierror.New(apierror.ErrBadRequest, "IamPolicy and ScpPolicy actions are not supported", nil)
+	}
+
+	if a.Ssm == nil {
+		return apierror.New(apierror.ErrBadRequest, "Ssm is required for RUN_SSM_DOCUMENTS actions", nil)
+	}
+
+	if err := validateOption("action sub type", a.Ssm.ActionSubType, budgets.ActionSubType_Values()); err != nil {
+		return err
+	}
+
+	if a.Ssm.Region == "" {
+		a.Ssm.Region = defaultBudgetActionRegion
+	}
+
+	if len(a.Ssm.InstanceIds) == 0 {
+		return apierror.New(apierror.ErrBadRequest, "no instances tagged with the space to stop, add the action with a budget update once the space has instances", nil)
+	}
+
+	return nil
+}
+
+// toBudgetActionInput converts a budget action to the input to create it.  The action notifies the budget
+// topic and the email addresses of the action.
+func toBudgetActionInput(account, budget, topicArn string, a *BudgetAction) *budgets.CreateBudgetActionInput {
+	subscribers := []*budgets.Subscriber{
+		{
+			Address:          aws.String(topicArn),
+			SubscriptionType: aws.String("SNS"),
+		},
+	}
+
+	for _, s := range a.Addresses {
+		subscribers = append(subscribers, &budgets.Subscriber{
+			Address:          aws.String(s),
+			SubscriptionType: aws.String("EMAIL"),
+		})
+	}
+
+	definition := &budgets.Definition{
+		SsmActionDefinition: &budgets.SsmActionDefinition{
+			ActionSubType: aws.String(a.Ssm.ActionSubType),
+			InstanceIds:   aws.StringSlice(a.Ssm.InstanceIds),
+			Region:        aws.String(a.Ssm.Region),
+		},
+	}
+
+	return &budgets.CreateBudgetActionInput{
+		AccountId:  aws.String(account),
+		BudgetName: aws.String(budget),
+		ActionThreshold: &budgets.ActionThreshold{
+			ActionThresholdType:  aws.String(a.ThresholdType),
+			ActionThresholdValue: aws.Float64(a.Threshold),
+		},
+		ActionType:       aws.String(a.ActionType),
+		ApprovalModel:    aws.String(a.ApprovalModel),
+		Definition:       definition,
+		ExecutionRoleArn: aws.String(a.ExecutionRoleArn),
+		NotificationType: aws.String(a.NotificationType),
+		Subscribers:      subscribers,
+	}
+}
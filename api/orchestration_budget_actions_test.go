@@ -0,0 +1,376 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+	budgetsapi "github.com/YaleSpinup/cost-api/budgets"
+	snsapi "github.com/YaleSpinup/cost-api/sns"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/budgets"
+)
+
+func testStopInstancesAction() *BudgetAction {
+	return &BudgetAction{
+		ActionType:       "RUN_SSM_DOCUMENTS",
+		ExecutionRoleArn: "arn:aws:iam::012345678901:role/budget-actions",
+		Threshold:        100,
+		Addresses:        []string{"a@example.com"},
+		Ssm: &BudgetSsmAction{
+			ActionSubType: "STOP_EC2_INSTANCES",
+			InstanceIds:   []string{"i-0123456789abcdef0"},
+		},
+	}
+}
+
+func TestCreateBudgetWithActions(t *testing.T) {
+	client := newMockBudgetsClient(t, nil)
+	snsClient := newMockSNSClient(t)
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: snsClient}, "testorg")
+
+	req := testBudgetCreateRequest()
+	req.Actions = []*BudgetAction{testStopInstancesAction()}
+
+	out, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := &BudgetAction{
+		ID:               "action-1",
+		ActionType:       "RUN_SSM_DOCUMENTS",
+		ApprovalModel:    "MANUAL",
+		ExecutionRoleArn: "arn:aws:iam::012345678901:role/budget-actions",
+		NotificationType: "ACTUAL",
+		Threshold:        100,
+		ThresholdType:    "PERCENTAGE",
+		Addresses:        []string{"a@example.com"},
+		Ssm: &BudgetSsmAction{
+			ActionSubType: "STOP_EC2_INSTANCES",
+			InstanceIds:   []string{"i-0123456789abcdef0"},
+			Region:        "us-east-1",
+		},
+	}
+
+	if len(out.Actions) != 1 || !reflect.DeepEqual(out.Actions[0], expected) {
+		t.Errorf("expected actions [%+v], got %+v", expected, out.Actions)
+	}
+
+	// the action notifies the budget topic and the addresses
+	expectedSubscribers := []*budgets.Subscriber{testTopicSubscriber, emailSubscriber("a@example.com")}
+	if subs := client.actions[0].Subscribers; !reflect.DeepEqual(subs, expectedSubscribers) {
+		t.Errorf("expected action subscribers %+v, got %+v", expectedSubscribers, subs)
+	}
+
+	expectedCalls := []string{"CreateBudget", "CreateBudgetAction RUN_SSM_DOCUMENTS"}
+	if !reflect.DeepEqual(client.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, client.calls)
+	}
+}
+
+func TestCreateBudgetWithActionsRollback(t *testing.T) {
+	client := newMockBudgetsClient(t, nil)
+	client.actionErr = apierror.New(apierror.ErrBadRequest, "invalid execution role", nil)
+	snsClient := newMockSNSClient(t)
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: snsClient}, "testorg")
+
+	req := testBudgetCreateRequest()
+	req.Actions = []*BudgetAction{testStopInstancesAction()}
+
+	if _, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", req); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// the budget and the topic are deleted
+	expectedCalls := []string{"CreateBudget", "CreateBudgetAction RUN_SSM_DOCUMENTS", "DeleteBudget"}
+	if !reflect.DeepEqual(client.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, client.calls)
+	}
+
+	if len(snsClient.topics) != 0 {
+		t.Errorf("expected no orphaned topics, got %v", snsClient.topics)
+	}
+}
+
+func TestValidateBudgetAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(a *BudgetAction)
+		wantErr bool
+	}{
+		{"valid", func(a *BudgetAction) {}, false},
+		{"invalid action type", func(a *BudgetAction) { a.ActionType = "STOP" }, true},
+		{"invalid approval model", func(a *BudgetAction) { a.ApprovalModel = "NEVER" }, true},
+		{"invalid notification type", func(a *BudgetAction) { a.NotificationType = "GUESS" }, true},
+		{"no threshold", func(a *BudgetAction) { a.Threshold = 0 }, true},
+		{"no execution role", func(a *BudgetAction) { a.ExecutionRoleArn = "" }, true},
+		{"no definition", func(a *BudgetAction) { a.Ssm = nil }, true},
+		{"policy action", func(a *BudgetAction) {
+			a.ScpPolicy = &BudgetScpPolicyAction{PolicyId: "p-12345678", TargetIds: []string{"012345678901"}}
+		}, true},
+		{"invalid action sub type", func(a *BudgetAction) { a.Ssm.ActionSubType = "TERMINATE_EC2_INSTANCES" }, true},
+		{"no instances", func(a *BudgetAction) { a.Ssm.InstanceIds = nil }, true},
+		{"iam policy", func(a *BudgetAction) {
+			a.ActionType = "APPLY_IAM_POLICY"
+			a.Ssm = nil
+			a.IamPolicy = &BudgetIamPolicyAction{PolicyArn: "arn:aws:iam::aws:policy/AdministratorAccess", Roles: []string{"spc-123-role"}}
+		}, true},
+		{"scp policy", func(a *BudgetAction) {
+			a.ActionType = "APPLY_SCP_POLICY"
+			a.Ssm = nil
+			a.ScpPolicy = &BudgetScpPolicyAction{PolicyId: "p-12345678", TargetIds: []string{"ou-abcd-12345678"}}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		a := testStopInstancesAction()
+		tt.modify(a)
+
+		err := validateBudgetAction(a)
+		if !tt.wantErr {
+			if err != nil {
+				t.Errorf("%s: expected nil error, got %s", tt.name, err)
+			}
+			continue
+		}
+
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected error code %s, got: %s", tt.name, apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", tt.name, err)
+		}
+	}
+}
+
+func TestSpaceInstanceIds(t *testing.T) {
+	inventory := []*InventoryResponse{
+		{Service: "ec2", Region: "us-east-1", Resource: "instance/i-0123456789abcdef0"},
+		{Service: "ec2", Region: "us-east-1", Resource: "volume/vol-0123456789abcdef0"},
+		{Service: "ec2", Region: "us-west-2", Resource: "instance/i-0fedcba9876543210"},
+		{Service: "rds", Region: "us-east-1", Resource: "db:spc-123-db"},
+		{Service: "rds", Region: "us-east-1", Resource: "snapshot:spc-123-snap"},
+	}
+
+	tests := []struct {
+		action *BudgetSsmAction
+		want   []string
+	}{
+		{&BudgetSsmAction{ActionSubType: "STOP_EC2_INSTANCES"}, []string{"i-0123456789abcdef0"}},
+		{&BudgetSsmAction{ActionSubType: "STOP_EC2_INSTANCES", Region: "us-west-2"}, []string{"i-0fedcba9876543210"}},
+		{&BudgetSsmAction{ActionSubType: "STOP_RDS_INSTANCES"}, []string{"spc-123-db"}},
+		{&BudgetSsmAction{ActionSubType: "STOP_RDS_INSTANCES", Region: "us-west-2"}, []string{}},
+		// existing instances are replaced by the instances in the space
+		{&BudgetSsmAction{ActionSubType: "STOP_EC2_INSTANCES", InstanceIds: []string{"i-0aaaaaaaaaaaaaaaa"}}, []string{"i-0123456789abcdef0"}},
+	}
+
+	for _, tt := range tests {
+		if got := spaceInstanceIds(tt.action, inventory); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("spaceInstanceIds(%+v) = %v, want %v", tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestSetBudgetActionDefaults(t *testing.T) {
+	s := &server{}
+
+	// no actions doesn't need budget actions to be configured
+	if err := s.setBudgetActionDefaults(context.TODO(), "012345678901", "spc-123", nil); err != nil {
+		t.Errorf("expected nil error without actions, got %s", err)
+	}
+
+	if err := s.setBudgetActionDefaults(context.TODO(), "012345678901", "spc-123", []*BudgetAction{{ActionType: "RUN_SSM_DOCUMENTS"}}); err == nil {
+		t.Error("expected error when budget actions aren't configured, got nil")
+	}
+
+	s.budgetActionRole = "budget-actions"
+
+	// the execution role can't be passed
+	err := s.setBudgetActionDefaults(context.TODO(), "012345678901", "spc-123", []*BudgetAction{{ExecutionRoleArn: "arn:aws:iam::012345678901:role/admin"}})
+	if aerr, ok := err.(apierror.Error); !ok || aerr.Code != apierror.ErrBadRequest {
+		t.Errorf("expected bad request error for execution role, got %v", err)
+	}
+
+	// the instances can't be passed
+	err = s.setBudgetActionDefaults(context.TODO(), "012345678901", "spc-123", []*BudgetAction{testStopInstancesAction()})
+	if aerr, ok := err.(apierror.Error); !ok || aerr.Code != apierror.ErrBadRequest {
+		t.Errorf("expected bad request error for instance ids, got %v", err)
+	}
+
+	a := &BudgetAction{ActionType: "RUN_SSM_DOCUMENTS"}
+	if err := s.setBudgetActionDefaults(context.TODO(), "012345678901", "spc-123", []*BudgetAction{a}); err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if a.ExecutionRoleArn != "arn:aws:iam::012345678901:role/budget-actions" {
+		t.Errorf("expected execution role to be the budget action role, got %s", a.ExecutionRoleArn)
+	}
+}
+
+func TestExecuteBudgetAction(t *testing.T) {
+	client := newMockBudgetsClient(t, testBudget())
+	client.actions = []*budgets.Action{
+		{
+			ActionId:   aws.String("action-1"),
+			ActionType: aws.String("RUN_SSM_DOCUMENTS"),
+			BudgetName: aws.String(testBudgetName),
+			Definition: &budgets.Definition{
+				SsmActionDefinition: &budgets.SsmActionDefinition{
+					ActionSubType: aws.String("STOP_EC2_INSTANCES"),
+					InstanceIds:   aws.StringSlice([]string{"i-0123456789abcdef0"}),
+					Region:        aws.String("us-east-1"),
+				},
+			},
+			Status:      aws.String("PENDING"),
+			Subscribers: []*budgets.Subscriber{testTopicSubscriber},
+		},
+	}
+
+	// an instance was launched in the space since the action was created
+	inventory := []*InventoryResponse{
+		{Service: "ec2", Region: "us-east-1", Resource: "instance/i-0123456789abcdef0"},
+		{Service: "ec2", Region: "us-east-1", Resource: "instance/i-0fedcba9876543210"},
+	}
+
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+	out, err := o.ExecuteBudgetAction(context.TODO(), "012345678901", "spc-123", testBudgetName, "action-1", "approve", inventory)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if out.Status != "EXECUTION_IN_PROGRESS" {
+		t.Errorf("expected action status EXECUTION_IN_PROGRESS, got %s", out.Status)
+	}
+
+	expectedIds := []string{"i-0123456789abcdef0", "i-0fedcba9876543210"}
+	if !reflect.DeepEqual(out.Ssm.InstanceIds, expectedIds) {
+		t.Errorf("expected action instances to be refreshed to %v, got %v", expectedIds, out.Ssm.InstanceIds)
+	}
+
+	expectedCalls := []string{"UpdateBudgetAction action-1", "ExecuteBudgetAction APPROVE_BUDGET_ACTION"}
+	if !reflect.DeepEqual(client.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, client.calls)
+	}
+
+	// unchanged instances aren't updated and reversing an action doesn't refresh it
+	for _, execution := range []string{"retry", "reverse"} {
+		client.calls = nil
+
+		if _, err := o.ExecuteBudgetAction(context.TODO(), "012345678901", "spc-123", testBudgetName, "action-1", execution, inventory); err != nil {
+			t.Errorf("%s: expected nil error, got %s", execution, err)
+		}
+
+		expectedCalls := []string{"ExecuteBudgetAction " + budgetActionExecutions[execution]}
+		if !reflect.DeepEqual(client.calls, expectedCalls) {
+			t.Errorf("%s: expected calls %v, got %v", execution, expectedCalls, client.calls)
+		}
+	}
+
+	tests := []struct {
+		name, budget, action, execution string
+		inventory                       []*InventoryResponse
+		code                            string
+	}{
+		{"other space", "spinup_testorg_spc-999_MONTHLY-01", "action-1", "approve", inventory, apierror.ErrBadRequest},
+		{"invalid execution", testBudgetName, "action-1", "delete", inventory, apierror.ErrBadRequest},
+		{"missing action", testBudgetName, "action-2", "approve", inventory, apierror.ErrNotFound},
+		{"no instances in the space", testBudgetName, "action-1", "retry", []*InventoryResponse{}, apierror.ErrBadRequest},
+	}
+
+	for _, tt := range tests {
+		client.calls = nil
+
+		_, err := o.ExecuteBudgetAction(context.TODO(), "012345678901", "spc-123", tt.budget, tt.action, tt.execution, tt.inventory)
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != tt.code {
+				t.Errorf("%s: expected error code %s, got: %s", tt.name, tt.code, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", tt.name, err)
+		}
+
+		if len(client.calls) != 0 {
+			t.Errorf("%s: expected no calls, got %v", tt.name, client.calls)
+		}
+	}
+}
+
+func TestUpdateBudgetActions(t *testing.T) {
+	client := newMockBudgetsClient(t, testBudget())
+	client.actions = []*budgets.Action{
+		{
+			ActionId:   aws.String("action-1"),
+			ActionType: aws.String("RUN_SSM_DOCUMENTS"),
+			BudgetName: aws.String(testBudgetName),
+			Definition: &budgets.Definition{
+				SsmActionDefinition: &budgets.SsmActionDefinition{
+					ActionSubType: aws.String("STOP_EC2_INSTANCES"),
+					InstanceIds:   aws.StringSlice([]string{"i-0aaaaaaaaaaaaaaaa"}),
+					Region:        aws.String("us-east-1"),
+				},
+			},
+			Status: aws.String("STANDBY"),
+		},
+	}
+
+	inventory := []*InventoryResponse{
+		{Service: "ec2", Region: "us-east-1", Resource: "instance/i-0123456789abcdef0"},
+		{Service: "rds", Region: "us-east-1", Resource: "db:spc-123-db"},
+	}
+
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+	// an empty update refreshes the existing actions
+	out, err := o.UpdateBudget(context.TODO(), "012345678901", "spc-123", testBudgetName, &BudgetUpdateRequest{}, inventory)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expectedCalls := []string{"UpdateBudgetAction action-1"}
+	if !reflect.DeepEqual(client.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, client.calls)
+	}
+
+	if len(out.Actions) != 1 || !reflect.DeepEqual(out.Actions[0].Ssm.InstanceIds, []string{"i-0123456789abcdef0"}) {
+		t.Errorf("expected refreshed action instances, got %+v", out.Actions)
+	}
+
+	// actions can be added once the space has instances
+	client.calls = nil
+	action := testStopInstancesAction()
+	action.Ssm = &BudgetSsmAction{ActionSubType: "STOP_RDS_INSTANCES", InstanceIds: spaceInstanceIds(&BudgetSsmAction{ActionSubType: "STOP_RDS_INSTANCES"}, inventory)}
+
+	out, err = o.UpdateBudget(context.TODO(), "012345678901", "spc-123", testBudgetName, &BudgetUpdateRequest{Actions: []*BudgetAction{action}}, inventory)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expectedCalls = []string{"CreateBudgetAction RUN_SSM_DOCUMENTS"}
+	if !reflect.DeepEqual(client.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, client.calls)
+	}
+
+	if len(out.Actions) != 2 {
+		t.Fatalf("expected 2 actions, got %+v", out.Actions)
+	}
+
+	// the added action notifies the budget topic
+	expectedSubscribers := []*budgets.Subscriber{testTopicSubscriber, emailSubscriber("a@example.com")}
+	if subs := client.actions[1].Subscribers; !reflect.DeepEqual(subs, expectedSubscribers) {
+		t.Errorf("expected action subscribers %+v, got %+v", expectedSubscribers, subs)
+	}
+
+	// actions are left as is when the space has no instances left
+	client.calls = nil
+	if _, err := o.UpdateBudget(context.TODO(), "012345678901", "spc-123", testBudgetName, &BudgetUpdateRequest{}, []*InventoryResponse{}); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if len(client.calls) != 0 {
+		t.Errorf("expected no calls, got %v", client.calls)
+	}
+}
@@ -3,7 +3,9 @@ package api
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/YaleSpinup/apierror"
 	"github.com/aws/aws-sdk-go/aws"
@@ -12,9 +14,17 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func (o *budgetsOrchestrator) CreateBudget(ctx context.Context, account, spaceID string, req *BudgetCreateRequest) (*BudgetResponse, error) {
-	if req.Amount == "" {
-		return nil, apierror.New(apierror.ErrBadRequest, "Amount is required", nil)
+// CreateBudget creates a budget for the space with an SNS topic for the alerts and the budget actions.
+// Everything is validated before any resources are created and the resources created are rolled back
+// (ie. the topic is deleted) if the budget can't be created, so a failed request doesn't leave an
+// orphaned topic behind.
+func (o *budgetsOrchestrator) CreateBudget(ctx context.Context, account, spaceID string, req *BudgetCreateRequest) (resp *BudgetResponse, err error) {
+	if req.Type == "" {
+		req.Type = budgets.BudgetTypeCost
+	}
+
+	if err := validateOption("budget type", req.Type, budgets.BudgetType_Values()); err != nil {
+		return nil, err
 	}
 
 	if req.TimeUnit == "" {
@@ -26,37 +36,19 @@ func (o *budgetsOrchestrator) CreateBudget(ctx context.Context, account, spaceID
 		return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
 	}
 
-	// budget name spinup_org_spaceid_TIMEUNIT-01
-	budgetName := fmt.Sprintf("spinup_%s_%s_%s-01", o.org, spaceID, req.TimeUnit)
-	spaceTagFilterValue := fmt.Sprintf("user:spinup:spaceid$%s", spaceID)
+	limit, err := budgetLimit(req)
+	if err != nil {
+		return nil, err
+	}
 
-	// set some reasonable defaults for budgets
-	budget := budgets.Budget{
-		BudgetName: aws.String(budgetName),
-		BudgetLimit: &budgets.Spend{
-			Amount: aws.String(req.Amount),
-			Unit:   aws.String("USD"),
-		},
-		BudgetType: aws.String("COST"),
-		CostFilters: map[string][]*string{
-			"TagKeyValue": {
-				aws.String(spaceTagFilterValue),
-			},
-		},
-		CostTypes: &budgets.CostTypes{
-			IncludeCredit:            aws.Bool(false),
-			IncludeDiscount:          aws.Bool(true),
-			IncludeOtherSubscription: aws.Bool(false),
-			IncludeRecurring:         aws.Bool(true),
-			IncludeRefund:            aws.Bool(false),
-			IncludeSubscription:      aws.Bool(true),
-			IncludeSupport:           aws.Bool(false),
-			IncludeTax:               aws.Bool(false),
-			IncludeUpfront:           aws.Bool(false),
-			UseAmortized:             aws.Bool(false),
-			UseBlended:               aws.Bool(false),
-		},
-		TimeUnit: aws.String(req.TimeUnit),
+	costTypes, err := budgetCostTypes(req.Type, req.CostTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	costFilters, err := budgetCostFilters(req.Type, spaceID, req.CostFilters)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(req.Alerts) == 0 {
@@ -65,6 +57,47 @@ func (o *budgetsOrchestrator) CreateBudget(ctx context.Context, account, spaceID
 		return nil, apierror.New(apierror.ErrBadRequest, "up to 5 Alerts per budget are supported", nil)
 	}
 
+	for _, a := range req.Alerts {
+		log.Debugf("validating alert %+v", a)
+
+		if err := validateBudgetAlert(a); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range req.Actions {
+		log.Debugf("validating action %+v", a)
+
+		if err := validateBudgetAction(a); err != nil {
+			return nil, err
+		}
+	}
+
+	// budget name spinup_org_spaceid_TIMEUNIT-01 for cost budgets and spinup_org_spaceid_TYPE_TIMEUNIT-01
+	// for the other types, so a space can have a budget of each type
+	budgetName := fmt.Sprintf("spinup_%s_%s_%s-01", o.org, spaceID, req.TimeUnit)
+	if req.Type != budgets.BudgetTypeCost {
+		budgetName = fmt.Sprintf("spinup_%s_%s_%s_%s-01", o.org, spaceID, req.Type, req.TimeUnit)
+	}
+
+	budget := budgets.Budget{
+		BudgetName:  aws.String(budgetName),
+		BudgetLimit: limit,
+		BudgetType:  aws.String(req.Type),
+		CostFilters: costFilters,
+		CostTypes:   costTypes,
+		TimeUnit:    aws.String(req.TimeUnit),
+	}
+
+	// creating a topic returns the topic of an existing budget, so make sure the budget doesn't exist
+	// before creating the topic or the rollback would delete the topic of the existing budget
+	if _, err := o.client.DescribeBudget(ctx, account, budgetName); err == nil {
+		msg := fmt.Sprintf("budget %s already exists", budgetName)
+		return nil, apierror.New(apierror.ErrConflict, msg, nil)
+	} else if aerr, ok := err.(apierror.Error); !ok || aerr.Code != apierror.ErrNotFound {
+		return nil, err
+	}
+
 	// create a topic with the name budgets-spinup_org_spaceid_TIMEUNIT-01
 	topicName := fmt.Sprintf("budgets-%s", budgetName)
 	arn := fmt.Sprintf("arn:aws:sns:us-east-1:%s:%s", account, topicName)
@@ -73,6 +106,15 @@ func (o *budgetsOrchestrator) CreateBudget(ctx context.Context, account, spaceID
 		return nil, err
 	}
 
+	// setup rollback of the resources created if the budget fails
+	var rollBackTasks []rollbackFunc
+	defer func() {
+		if err != nil {
+			log.Errorf("recovering from error creating budget %s: %s", budgetName, err)
+			rollBack(&rollBackTasks)
+		}
+	}()
+
 	topic, err := o.snsClient.CreateTopic(ctx, &sns.CreateTopicInput{
 		Name: aws.String(topicName),
 		Attributes: map[string]*string{
@@ -84,10 +126,14 @@ func (o *budgetsOrchestrator) CreateBudget(ctx context.Context, account, spaceID
 		return nil, err
 	}
 
+	topicArn := aws.StringValue(topic.TopicArn)
+	rollBackTasks = append(rollBackTasks, func(ctx context.Context) error {
+		log.Infof("rollback: deleting budget sns topic %s", topicArn)
+		return o.snsClient.DeleteTopic(ctx, topicArn)
+	})
+
 	notifications := []*budgets.NotificationWithSubscribers{}
 	for _, a := range req.Alerts {
-		log.Debugf("processing alert %+v", a)
-
 		subscribers := []*budgets.Subscriber{
 			{
 				Address:          topic.TopicArn,
@@ -95,12 +141,6 @@ func (o *budgetsOrchestrator) CreateBudget(ctx context.Context, account, spaceID
 			},
 		}
 
-		if len(a.Addresses) == 0 {
-			return nil, apierror.New(apierror.ErrBadRequest, "at least 1 email address is required per alert", nil)
-		} else if len(a.Addresses) > 10 {
-			return nil, apierror.New(apierror.ErrBadRequest, "up to 10 email addresses per alert are supported", nil)
-		}
-
 		for _, s := range a.Addresses {
 			subscribers = append(subscribers, &budgets.Subscriber{
 				Address:          aws.String(s),
@@ -108,43 +148,45 @@ func (o *budgetsOrchestrator) CreateBudget(ctx context.Context, account, spaceID
 			})
 		}
 
-		if !validComparisonOperator(a.ComparisonOperator) {
-			msg := fmt.Sprintf("invalid comparison operator '%s', valid values %s", a.NotificationType, strings.Join(budgets.ComparisonOperator_Values(), ", "))
-			return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
-		}
-
-		if !validNotificationType(a.NotificationType) {
-			msg := fmt.Sprintf("invalid notification type '%s', valid values %s", a.NotificationType, strings.Join(budgets.NotificationType_Values(), ", "))
-			return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
-		}
-
-		if !validThresholdType(a.ThresholdType) {
-			msg := fmt.Sprintf("invalid threshold type '%s', valid values %s", a.ThresholdType, strings.Join(budgets.ThresholdType_Values(), ", "))
-			return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
-		}
-
-		notification := &budgets.Notification{
-			ComparisonOperator: aws.String(a.ComparisonOperator),
-			NotificationType:   aws.String(a.NotificationType),
-			Threshold:          aws.Float64(a.Threshold),
-			ThresholdType:      aws.String(a.ThresholdType),
-		}
-
 		notifications = append(notifications, &budgets.NotificationWithSubscribers{
-			Notification: notification,
+			Notification: toNotification(a),
 			Subscribers:  subscribers,
 		})
 	}
 
-	if err := o.client.CreateBudget(ctx, &budgets.CreateBudgetInput{
+	if err = o.client.CreateBudget(ctx, &budgets.CreateBudgetInput{
 		AccountId:                    aws.String(account),
 		Budget:                       &budget,
 		NotificationsWithSubscribers: notifications,
 	}); err != nil {
+		// the budget was created concurrently, the topic belongs to that budget
+		if aerr, ok := err.(apierror.Error); ok && aerr.Code == apierror.ErrConflict {
+			rollBackTasks = nil
+		}
+
 		return nil, err
 	}
 
-	return toBudgetResponse(&budget, req.Alerts), nil
+	rollBackTasks = append(rollBackTasks, func(ctx context.Context) error {
+		log.Infof("rollback: deleting budget %s", budgetName)
+		return o.client.DeleteBudget(ctx, account, budgetName)
+	})
+
+	for _, a := range req.Actions {
+		var id string
+		id, err = o.client.CreateBudgetAction(ctx, toBudgetActionInput(account, budgetName, topicArn, a))
+		if err != nil {
+			return nil, err
+		}
+		a.ID = id
+	}
+
+	resp = toBudgetResponse(&budget, req.Alerts)
+	if len(req.Actions) > 0 {
+		resp.Actions = req.Actions
+	}
+
+	return resp, nil
 }
 
 func (o *budgetsOrchestrator) GetBudget(ctx context.Context, account, spaceID, budget string) (*BudgetResponse, error) {
@@ -175,6 +217,280 @@ func (o *budgetsOrchestrator) GetBudget(ctx context.Context, account, spaceID, b
 	return toBudgetResponse(budgetOut, alerts), nil
 }
 
+// GetBudgetHistory gets the budgeted and actual amounts of a budget for each period from start to end.
+// By default the history for the last year, including the current period, is returned.
+func (o *budgetsOrchestrator) GetBudgetHistory(ctx context.Context, account, spaceID, budget, start, end string) (*BudgetHistoryResponse, error) {
+	if !strings.HasPrefix(budget, budgetPrefix(o.org, spaceID)) {
+		return nil, apierror.New(apierror.ErrBadRequest, "budget doesn't belong to provided space", nil)
+	}
+
+	startTime, endTime, err := parseBudgetHistoryTime(start, end, time.Now())
+	if err != nil {
+		return nil, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	amounts, err := o.client.DescribeBudgetPerformanceHistory(ctx, account, budget, &budgets.TimePeriod{
+		Start: aws.Time(startTime),
+		End:   aws.Time(endTime),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toBudgetHistoryResponse(budget, startTime.Format("2006-01-02"), endTime.Format("2006-01-02"), amounts), nil
+}
+
+// UpdateBudget updates the amount and the alerts of a budget in place.  The alerts are diffed against
+// the existing notifications, so the state of unchanged notifications is kept.  Notifications matching
+// an alert keep their subscribers in sync with the alert addresses, the remaining notifications are
+// updated to match the remaining alerts and any left over are deleted or created.  When budget actions
+// are configured, the inventory of the space is passed, the instances of the existing actions are
+// refreshed from it and the actions of the request are added to the budget.
+func (o *budgetsOrchestrator) UpdateBudget(ctx context.Context, account, spaceID, budget string, req *BudgetUpdateRequest, inventory []*InventoryResponse) (*BudgetResponse, error) {
+	if !strings.HasPrefix(budget, budgetPrefix(o.org, spaceID)) {
+		return nil, apierror.New(apierror.ErrBadRequest, "budget doesn't belong to provided space", nil)
+	}
+
+	// an empty update only refreshes the budget actions
+	if req.Amount == "" && req.Alerts == nil && len(req.Actions) == 0 && inventory == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "Amount, Alerts or Actions are required", nil)
+	}
+
+	if len(req.Actions) > 0 && inventory == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "budget actions are not configured", nil)
+	}
+
+	if req.Alerts != nil {
+		if len(req.Alerts) == 0 {
+			return nil, apierror.New(apierror.ErrBadRequest, "at least 1 Alert is required", nil)
+		} else if len(req.Alerts) > 5 {
+			return nil, apierror.New(apierror.ErrBadRequest, "up to 5 Alerts per budget are supported", nil)
+		}
+
+		for _, a := range req.Alerts {
+			if err := validateBudgetAlert(a); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, a := range req.Actions {
+		if err := validateBudgetAction(a); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Amount != "" {
+		existing, err := o.client.DescribeBudget(ctx, account, budget)
+		if err != nil {
+			return nil, err
+		}
+
+		budgetType := aws.StringValue(existing.BudgetType)
+		if budgetType == "" {
+			budgetType = budgets.BudgetTypeCost
+		}
+
+		if err := validateBudgetAmount(budgetType, req.Amount); err != nil {
+			return nil, err
+		}
+
+		// the unit of the budget can't be changed
+		unit := "USD"
+		if existing.BudgetLimit != nil && existing.BudgetLimit.Unit != nil {
+			unit = aws.StringValue(existing.BudgetLimit.Unit)
+		}
+
+		// calculated spend and the last updated time are managed by AWS
+		existing.CalculatedSpend = nil
+		existing.LastUpdatedTime = nil
+		existing.BudgetLimit = &budgets.Spend{
+			Amount: aws.String(req.Amount),
+			Unit:   aws.String(unit),
+		}
+
+		if err := o.client.UpdateBudget(ctx, &budgets.UpdateBudgetInput{
+			AccountId: aws.String(account),
+			NewBudget: existing,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Alerts != nil {
+		if err := o.updateBudgetAlerts(ctx, account, budget, req.Alerts); err != nil {
+			return nil, err
+		}
+	}
+
+	if inventory == nil {
+		return o.GetBudget(ctx, account, spaceID, budget)
+	}
+
+	if err := o.refreshBudgetActions(ctx, account, budget, "", inventory); err != nil {
+		return nil, err
+	}
+
+	// the actions notify the topic created with the budget
+	topicArn := fmt.Sprintf("arn:aws:sns:us-east-1:%s:budgets-%s", account, budget)
+	for _, a := range req.Actions {
+		if _, err := o.client.CreateBudgetAction(ctx, toBudgetActionInput(account, budget, topicArn, a)); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := o.GetBudget(ctx, account, spaceID, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := o.ListBudgetActions(ctx, account, spaceID, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(actions) > 0 {
+		resp.Actions = actions
+	}
+
+	return resp, nil
+}
+
+// updateBudgetAlerts diffs the alerts against the existing notifications and subscribers of the budget
+func (o *budgetsOrchestrator) updateBudgetAlerts(ctx context.Context, account, budget string, alerts []*BudgetAlert) error {
+	notifications, err := o.client.DescribeNotifications(ctx, account, budget)
+	if err != nil {
+		return err
+	}
+
+	// match the alerts to the existing notifications with the same settings
+	matched := make([]*budgets.Notification, len(alerts))
+	unmatched := []*budgets.Notification{}
+	for _, n := range notifications {
+		found := false
+		for i, a := range alerts {
+			if matched[i] == nil && notificationKey(n) == notificationKey(toNotification(a)) {
+				matched[i] = n
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unmatched = append(unmatched, n)
+		}
+	}
+
+	// remove the notifications that aren't reused first, budgets are limited to 5 notifications
+	reuse := 0
+	for _, m := range matched {
+		if m == nil {
+			reuse++
+		}
+	}
+
+	for len(unmatched) > reuse {
+		n := unmatched[len(unmatched)-1]
+		if err := o.client.DeleteNotification(ctx, account, budget, n); err != nil {
+			return err
+		}
+		unmatched = unmatched[:len(unmatched)-1]
+	}
+
+	topicArn := fmt.Sprintf("arn:aws:sns:us-east-1:%s:budgets-%s", account, budget)
+
+	for i, a := range alerts {
+		notification := toNotification(a)
+
+		existing := matched[i]
+		if existing == nil && len(unmatched) > 0 {
+			existing, unmatched = unmatched[0], unmatched[1:]
+
+			log.Debugf("updating notification %s to %s", notificationKey(existing), notificationKey(notification))
+
+			if err := o.client.UpdateNotification(ctx, account, budget, existing, notification); err != nil {
+				return err
+			}
+		} else if existing == nil {
+			log.Debugf("creating notification %s", notificationKey(notification))
+
+			subscribers := []*budgets.Subscriber{
+				{
+					Address:          aws.String(topicArn),
+					SubscriptionType: aws.String("SNS"),
+				},
+			}
+
+			for _, address := range a.Addresses {
+				subscribers = append(subscribers, &budgets.Subscriber{
+					Address:          aws.String(address),
+					SubscriptionType: aws.String("EMAIL"),
+				})
+			}
+
+			if err := o.client.CreateNotification(ctx, account, budget, notification, subscribers); err != nil {
+				return err
+			}
+
+			continue
+		} else {
+			// use the existing notification as it's stored to manage the subscribers
+			notification = existing
+		}
+
+		subscribers, err := o.client.DescribeSubscribers(ctx, account, budget, notification)
+		if err != nil {
+			return err
+		}
+
+		if err := o.updateAlertSubscribers(ctx, account, budget, notification, subscribers, a.Addresses); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateAlertSubscribers creates and deletes the email subscribers of a notification to match the
+// addresses.  Other subscribers (ie. the budget SNS topic) are left alone.
+func (o *budgetsOrchestrator) updateAlertSubscribers(ctx context.Context, account, budget string, notification *budgets.Notification, subscribers []*budgets.Subscriber, addresses []string) error {
+	want := map[string]struct{}{}
+	for _, a := range addresses {
+		want[a] = struct{}{}
+	}
+
+	have := map[string]struct{}{}
+	for _, s := range subscribers {
+		if aws.StringValue(s.SubscriptionType) != "EMAIL" {
+			continue
+		}
+
+		address := aws.StringValue(s.Address)
+		have[address] = struct{}{}
+
+		if _, ok := want[address]; !ok {
+			if err := o.client.DeleteSubscriber(ctx, account, budget, notification, s); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, a := range addresses {
+		if _, ok := have[a]; ok {
+			continue
+		}
+
+		if err := o.client.CreateSubscriber(ctx, account, budget, notification, &budgets.Subscriber{
+			Address:          aws.String(a),
+			SubscriptionType: aws.String("EMAIL"),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (o *budgetsOrchestrator) ListBudgets(ctx context.Context, account, spaceID string) ([]string, error) {
 	out, err := o.client.ListBudgetsWithPrefix(ctx, account, budgetPrefix(o.org, spaceID))
 	if err != nil {
@@ -207,6 +523,266 @@ func (o *budgetsOrchestrator) DeleteBudget(ctx context.Context, account, spaceID
 	return nil
 }
 
+// validateBudgetAlert validates the addresses and the notification settings of an alert
+func validateBudgetAlert(a *BudgetAlert) error {
+	if len(a.Addresses) == 0 {
+		return apierror.New(apierror.ErrBadRequest, "at least 1 email address is required per alert", nil)
+	} else if len(a.Addresses) > 10 {
+		return apierror.New(apierror.ErrBadRequest, "up to 10 email addresses per alert are supported", nil)
+	}
+
+	if !validComparisonOperator(a.ComparisonOperator) {
+		msg := fmt.Sprintf("invalid comparison operator '%s', valid values %s", a.ComparisonOperator, strings.Join(budgets.ComparisonOperator_Values(), ", "))
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if !validNotificationType(a.NotificationType) {
+		msg := fmt.Sprintf("invalid notification type '%s', valid values %s", a.NotificationType, strings.Join(budgets.NotificationType_Values(), ", "))
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if !validThresholdType(a.ThresholdType) {
+		msg := fmt.Sprintf("invalid threshold type '%s', valid values %s", a.ThresholdType, strings.Join(budgets.ThresholdType_Values(), ", "))
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	return nil
+}
+
+// toNotification converts an alert to a budget notification
+func toNotification(a *BudgetAlert) *budgets.Notification {
+	return &budgets.Notification{
+		ComparisonOperator: aws.String(a.ComparisonOperator),
+		NotificationType:   aws.String(a.NotificationType),
+		Threshold:          aws.Float64(a.Threshold),
+		ThresholdType:      aws.String(a.ThresholdType),
+	}
+}
+
+// notificationKey identifies a notification by its settings, the percentage threshold type comes back unset
+func notificationKey(n *budgets.Notification) string {
+	thresholdType := aws.StringValue(n.ThresholdType)
+	if thresholdType == "" {
+		thresholdType = budgets.ThresholdTypePercentage
+	}
+
+	return fmt.Sprintf("%s_%s_%s_%g", aws.StringValue(n.NotificationType), aws.StringValue(n.ComparisonOperator), thresholdType, aws.Float64Value(n.Threshold))
+}
+
+// parseBudgetHistoryTime parses the start and end dates of the budget history.  The end defaults to the
+// beginning of next month and the start defaults to a year before the end.
+func parseBudgetHistoryTime(start, end string, now time.Time) (time.Time, time.Time, error) {
+	endTime := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	if end != "" {
+		e, err := time.Parse("2006-01-02", end)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end date '%s', expected YYYY-MM-DD", end)
+		}
+		endTime = e
+	}
+
+	startTime := endTime.AddDate(-1, 0, 0)
+	if start != "" {
+		s, err := time.Parse("2006-01-02", start)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start date '%s', expected YYYY-MM-DD", start)
+		}
+		startTime = s
+	}
+
+	if !endTime.After(startTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end time should be after start time")
+	}
+
+	return startTime, endTime, nil
+}
+
+// budgetCostFilterKeys are the cost filters that can be added to a budget, the space tag filter
+// is always set
+var budgetCostFilterKeys = []string{
+	"AZ",
+	"BillingEntity",
+	"InstanceType",
+	"InstanceTypeFamily",
+	"LinkedAccount",
+	"Operation",
+	"PurchaseType",
+	"Region",
+	"Service",
+	"UsageType",
+	"UsageTypeGroup",
+}
+
+// percentageBudgetType returns true for the RI and savings plans utilization and coverage budgets
+func percentageBudgetType(budgetType string) bool {
+	switch budgetType {
+	case budgets.BudgetTypeRiUtilization,
+		budgets.BudgetTypeRiCoverage,
+		budgets.BudgetTypeSavingsPlansUtilization,
+		budgets.BudgetTypeSavingsPlansCoverage:
+		return true
+	}
+	return false
+}
+
+// budgetLimit returns the limit of a new budget.  Cost budgets are in USD and usage budgets are in
+// the unit of the usage (ie. Hrs).  RI and savings plans budgets are always 100 percent, the alert
+// thresholds are used to track utilization or coverage.
+func budgetLimit(req *BudgetCreateRequest) (*budgets.Spend, error) {
+	if percentageBudgetType(req.Type) {
+		if req.Amount == "" {
+			req.Amount = "100"
+		}
+
+		if req.Unit != "" && req.Unit != "PERCENTAGE" {
+			msg := fmt.Sprintf("invalid unit '%s' for %s budget, only PERCENTAGE is supported", req.Unit, req.Type)
+			return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+		req.Unit = "PERCENTAGE"
+	}
+
+	if req.Amount == "" {
+		return nil, apierror.New(apierror.ErrBadRequest, "Amount is required", nil)
+	}
+
+	if err := validateBudgetAmount(req.Type, req.Amount); err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case budgets.BudgetTypeCost:
+		if req.Unit == "" {
+			req.Unit = "USD"
+		}
+
+		if req.Unit != "USD" {
+			msg := fmt.Sprintf("invalid unit '%s' for COST budget, only USD is supported", req.Unit)
+			return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+	case budgets.BudgetTypeUsage:
+		if req.Unit == "" {
+			return nil, apierror.New(apierror.ErrBadRequest, "Unit is required for USAGE budgets, ie. Hrs or GB", nil)
+		}
+	}
+
+	return &budgets.Spend{
+		Amount: aws.String(req.Amount),
+		Unit:   aws.String(req.Unit),
+	}, nil
+}
+
+// validateBudgetAmount validates the amount of a budget of the given type
+func validateBudgetAmount(budgetType, amount string) error {
+	a, err := strconv.ParseFloat(amount, 64)
+	if err != nil || a <= 0 {
+		msg := fmt.Sprintf("invalid amount '%s', expected a number greater than 0", amount)
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if percentageBudgetType(budgetType) && a != 100 {
+		msg := fmt.Sprintf("invalid amount '%s' for %s budget, the amount is always 100 (percent)", amount, budgetType)
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	return nil
+}
+
+// budgetCostTypes returns the cost types of a new budget, the defaults can be overridden for cost
+// budgets.  Only cost budgets have cost types.
+func budgetCostTypes(budgetType string, override *BudgetCostTypes) (*budgets.CostTypes, error) {
+	if budgetType != budgets.BudgetTypeCost {
+		if override != nil {
+			msg := fmt.Sprintf("CostTypes are only supported for COST budgets, not %s", budgetType)
+			return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+		return nil, nil
+	}
+
+	// set some reasonable defaults for budgets
+	costTypes := &budgets.CostTypes{
+		IncludeCredit:            aws.Bool(false),
+		IncludeDiscount:          aws.Bool(true),
+		IncludeOtherSubscription: aws.Bool(false),
+		IncludeRecurring:         aws.Bool(true),
+		IncludeRefund:            aws.Bool(false),
+		IncludeSubscription:      aws.Bool(true),
+		IncludeSupport:           aws.Bool(false),
+		IncludeTax:               aws.Bool(false),
+		IncludeUpfront:           aws.Bool(false),
+		UseAmortized:             aws.Bool(false),
+		UseBlended:               aws.Bool(false),
+	}
+
+	if override == nil {
+		return costTypes, nil
+	}
+
+	for _, o := range []struct {
+		value *bool
+		field **bool
+	}{
+		{override.IncludeCredit, &costTypes.IncludeCredit},
+		{override.IncludeDiscount, &costTypes.IncludeDiscount},
+		{override.IncludeOtherSubscription, &costTypes.IncludeOtherSubscription},
+		{override.IncludeRecurring, &costTypes.IncludeRecurring},
+		{override.IncludeRefund, &costTypes.IncludeRefund},
+		{override.IncludeSubscription, &costTypes.IncludeSubscription},
+		{override.IncludeSupport, &costTypes.IncludeSupport},
+		{override.IncludeTax, &costTypes.IncludeTax},
+		{override.IncludeUpfront, &costTypes.IncludeUpfront},
+		{override.UseAmortized, &costTypes.UseAmortized},
+		{override.UseBlended, &costTypes.UseBlended},
+	} {
+		if o.value != nil {
+			*o.field = aws.Bool(*o.value)
+		}
+	}
+
+	if aws.BoolValue(costTypes.UseAmortized) && aws.BoolValue(costTypes.UseBlended) {
+		return nil, apierror.New(apierror.ErrBadRequest, "UseAmortized and UseBlended can't both be set", nil)
+	}
+
+	return costTypes, nil
+}
+
+// budgetCostFilters returns the cost filters of a new budget, the space tag filter and any additional
+// filters.  Usage budgets need a usage type filter so the usage is in one unit and RI budgets are for
+// a single service.
+func budgetCostFilters(budgetType, spaceID string, filters map[string][]string) (map[string][]*string, error) {
+	costFilters := map[string][]*string{
+		"TagKeyValue": {
+			aws.String(fmt.Sprintf("user:spinup:spaceid$%s", spaceID)),
+		},
+	}
+
+	for k, v := range filters {
+		if err := validateOption("cost filter", k, budgetCostFilterKeys); err != nil {
+			return nil, err
+		}
+
+		if len(v) == 0 {
+			msg := fmt.Sprintf("at least 1 value is required for cost filter %s", k)
+			return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+
+		costFilters[k] = aws.StringSlice(v)
+	}
+
+	switch budgetType {
+	case budgets.BudgetTypeUsage:
+		if len(filters["UsageType"]) == 0 && len(filters["UsageTypeGroup"]) == 0 {
+			return nil, apierror.New(apierror.ErrBadRequest, "a UsageType or UsageTypeGroup cost filter is required for USAGE budgets", nil)
+		}
+	case budgets.BudgetTypeRiUtilization, budgets.BudgetTypeRiCoverage:
+		if len(filters["Service"]) != 1 {
+			msg := fmt.Sprintf("a Service cost filter with 1 service is required for %s budgets", budgetType)
+			return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+	}
+
+	return costFilters, nil
+}
+
 func budgetPrefix(org, spaceID string) string {
 	return fmt.Sprintf("spinup_%s_%s", org, spaceID)
 }
@@ -0,0 +1,861 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	budgetsapi "github.com/YaleSpinup/cost-api/budgets"
+	snsapi "github.com/YaleSpinup/cost-api/sns"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/budgets"
+	"github.com/aws/aws-sdk-go/service/budgets/budgetsiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+// mockBudgetsClient is a fake budgets client that keeps a single budget and its notifications in memory
+type mockBudgetsClient struct {
+	budgetsiface.BudgetsAPI
+	t             *testing.T
+	budget        *budgets.Budget
+	notifications map[string]*budgets.Notification
+	subscribers   map[string][]*budgets.Subscriber
+	calls         []string
+	createErr     error
+	actionErr     error
+	history       []*budgets.BudgetedAndActualAmounts
+	actions       []*budgets.Action
+}
+
+func newMockBudgetsClient(t *testing.T, budget *budgets.Budget) *mockBudgetsClient {
+	return &mockBudgetsClient{
+		t:             t,
+		budget:        budget,
+		notifications: map[string]*budgets.Notification{},
+		subscribers:   map[string][]*budgets.Subscriber{},
+	}
+}
+
+func (m *mockBudgetsClient) addNotification(n *budgets.Notification, subscribers ...*budgets.Subscriber) {
+	m.notifications[notificationKey(n)] = n
+	m.subscribers[notificationKey(n)] = subscribers
+}
+
+func (m *mockBudgetsClient) DescribeBudgetWithContext(ctx context.Context, input *budgets.DescribeBudgetInput, opts ...request.Option) (*budgets.DescribeBudgetOutput, error) {
+	if m.budget == nil || aws.StringValue(input.BudgetName) != aws.StringValue(m.budget.BudgetName) {
+		return nil, awserr.New(budgets.ErrCodeNotFoundException, "budget not found", nil)
+	}
+
+	b := *m.budget
+	return &budgets.DescribeBudgetOutput{Budget: &b}, nil
+}
+
+func (m *mockBudgetsClient) CreateBudgetWithContext(ctx context.Context, input *budgets.CreateBudgetInput, opts ...request.Option) (*budgets.CreateBudgetOutput, error) {
+	m.calls = append(m.calls, "CreateBudget")
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+
+	if m.budget != nil && aws.StringValue(m.budget.BudgetName) == aws.StringValue(input.Budget.BudgetName) {
+		return nil, awserr.New(budgets.ErrCodeDuplicateRecordException, "budget already exists", nil)
+	}
+
+	m.budget = input.Budget
+	for _, n := range input.NotificationsWithSubscribers {
+		m.addNotification(n.Notification, n.Subscribers...)
+	}
+
+	return &budgets.CreateBudgetOutput{}, nil
+}
+
+func (m *mockBudgetsClient) DescribeBudgetPerformanceHistoryWithContext(ctx context.Context, input *budgets.DescribeBudgetPerformanceHistoryInput, opts ...request.Option) (*budgets.DescribeBudgetPerformanceHistoryOutput, error) {
+	if m.budget == nil || aws.StringValue(input.BudgetName) != aws.StringValue(m.budget.BudgetName) {
+		return nil, apierror.New(apierror.ErrNotFound, "budget not found", nil)
+	}
+
+	m.calls = append(m.calls, fmt.Sprintf("DescribeBudgetPerformanceHistory %s %s", input.TimePeriod.Start.Format("2006-01-02"), input.TimePeriod.End.Format("2006-01-02")))
+	return &budgets.DescribeBudgetPerformanceHistoryOutput{
+		BudgetPerformanceHistory: &budgets.BudgetPerformanceHistory{
+			BudgetName:                   input.BudgetName,
+			BudgetedAndActualAmountsList: m.history,
+		},
+	}, nil
+}
+
+func (m *mockBudgetsClient) DeleteBudgetWithContext(ctx context.Context, input *budgets.DeleteBudgetInput, opts ...request.Option) (*budgets.DeleteBudgetOutput, error) {
+	m.calls = append(m.calls, "DeleteBudget")
+	m.budget = nil
+	m.actions = nil
+	return &budgets.DeleteBudgetOutput{}, nil
+}
+
+func (m *mockBudgetsClient) CreateBudgetActionWithContext(ctx context.Context, input *budgets.CreateBudgetActionInput, opts ...request.Option) (*budgets.CreateBudgetActionOutput, error) {
+	m.calls = append(m.calls, "CreateBudgetAction "+aws.StringValue(input.ActionType))
+	if m.actionErr != nil {
+		return nil, m.actionErr
+	}
+
+	id := fmt.Sprintf("action-%d", len(m.actions)+1)
+	m.actions = append(m.actions, &budgets.Action{
+		ActionId:         aws.String(id),
+		ActionThreshold:  input.ActionThreshold,
+		ActionType:       input.ActionType,
+		ApprovalModel:    input.ApprovalModel,
+		BudgetName:       input.BudgetName,
+		Definition:       input.Definition,
+		ExecutionRoleArn: input.ExecutionRoleArn,
+		NotificationType: input.NotificationType,
+		Status:           aws.String(budgets.ActionStatusStandby),
+		Subscribers:      input.Subscribers,
+	})
+
+	return &budgets.CreateBudgetActionOutput{AccountId: input.AccountId, ActionId: aws.String(id), BudgetName: input.BudgetName}, nil
+}
+
+func (m *mockBudgetsClient) DescribeBudgetActionsForBudgetWithContext(ctx context.Context, input *budgets.DescribeBudgetActionsForBudgetInput, opts ...request.Option) (*budgets.DescribeBudgetActionsForBudgetOutput, error) {
+	return &budgets.DescribeBudgetActionsForBudgetOutput{Actions: m.actions}, nil
+}
+
+func (m *mockBudgetsClient) ExecuteBudgetActionWithContext(ctx context.Context, input *budgets.ExecuteBudgetActionInput, opts ...request.Option) (*budgets.ExecuteBudgetActionOutput, error) {
+	m.calls = append(m.calls, "ExecuteBudgetAction "+aws.StringValue(input.ExecutionType))
+	for _, a := range m.actions {
+		if aws.StringValue(a.ActionId) == aws.StringValue(input.ActionId) {
+			a.Status = aws.String(budgets.ActionStatusExecutionInProgress)
+		}
+	}
+
+	return &budgets.ExecuteBudgetActionOutput{}, nil
+}
+
+func (m *mockBudgetsClient) UpdateBudgetActionWithContext(ctx context.Context, input *budgets.UpdateBudgetActionInput, opts ...request.Option) (*budgets.UpdateBudgetActionOutput, error) {
+	m.calls = append(m.calls, "UpdateBudgetAction "+aws.StringValue(input.ActionId))
+	for _, a := range m.actions {
+		if aws.StringValue(a.ActionId) == aws.StringValue(input.ActionId) {
+			a.Definition = input.Definition
+		}
+	}
+
+	return &budgets.UpdateBudgetActionOutput{}, nil
+}
+
+func (m *mockBudgetsClient) UpdateBudgetWithContext(ctx context.Context, input *budgets.UpdateBudgetInput, opts ...request.Option) (*budgets.UpdateBudgetOutput, error) {
+	m.calls = append(m.calls, "UpdateBudget")
+	m.budget = input.NewBudget
+	return &budgets.UpdateBudgetOutput{}, nil
+}
+
+func (m *mockBudgetsClient) DescribeNotificationsForBudgetWithContext(ctx context.Context, input *budgets.DescribeNotificationsForBudgetInput, opts ...request.Option) (*budgets.DescribeNotificationsForBudgetOutput, error) {
+	keys := []string{}
+	for k := range m.notifications {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := []*budgets.Notification{}
+	for _, k := range keys {
+		out = append(out, m.notifications[k])
+	}
+
+	return &budgets.DescribeNotificationsForBudgetOutput{Notifications: out}, nil
+}
+
+func (m *mockBudgetsClient) DescribeSubscribersForNotificationWithContext(ctx context.Context, input *budgets.DescribeSubscribersForNotificationInput, opts ...request.Option) (*budgets.DescribeSubscribersForNotificationOutput, error) {
+	subscribers, ok := m.subscribers[notificationKey(input.Notification)]
+	if !ok {
+		m.t.Errorf("unexpected describe subscribers for missing notification %s", notificationKey(input.Notification))
+	}
+
+	return &budgets.DescribeSubscribersForNotificationOutput{Subscribers: subscribers}, nil
+}
+
+func (m *mockBudgetsClient) CreateNotificationWithContext(ctx context.Context, input *budgets.CreateNotificationInput, opts ...request.Option) (*budgets.CreateNotificationOutput, error) {
+	m.calls = append(m.calls, "CreateNotification "+notificationKey(input.Notification))
+	m.addNotification(input.Notification, input.Subscribers...)
+	return &budgets.CreateNotificationOutput{}, nil
+}
+
+func (m *mockBudgetsClient) UpdateNotificationWithContext(ctx context.Context, input *budgets.UpdateNotificationInput, opts ...request.Option) (*budgets.UpdateNotificationOutput, error) {
+	oldKey, newKey := notificationKey(input.OldNotification), notificationKey(input.NewNotification)
+	m.calls = append(m.calls, "UpdateNotification "+oldKey+" "+newKey)
+
+	subscribers := m.subscribers[oldKey]
+	delete(m.notifications, oldKey)
+	delete(m.subscribers, oldKey)
+	m.addNotification(input.NewNotification, subscribers...)
+
+	return &budgets.UpdateNotificationOutput{}, nil
+}
+
+func (m *mockBudgetsClient) DeleteNotificationWithContext(ctx context.Context, input *budgets.DeleteNotificationInput, opts ...request.Option) (*budgets.DeleteNotificationOutput, error) {
+	key := notificationKey(input.Notification)
+	m.calls = append(m.calls, "DeleteNotification "+key)
+	delete(m.notifications, key)
+	delete(m.subscribers, key)
+	return &budgets.DeleteNotificationOutput{}, nil
+}
+
+func (m *mockBudgetsClient) CreateSubscriberWithContext(ctx context.Context, input *budgets.CreateSubscriberInput, opts ...request.Option) (*budgets.CreateSubscriberOutput, error) {
+	key := notificationKey(input.Notification)
+	m.calls = append(m.calls, "CreateSubscriber "+key+" "+aws.StringValue(input.Subscriber.Address))
+	m.subscribers[key] = append(m.subscribers[key], input.Subscriber)
+	return &budgets.CreateSubscriberOutput{}, nil
+}
+
+func (m *mockBudgetsClient) DeleteSubscriberWithContext(ctx context.Context, input *budgets.DeleteSubscriberInput, opts ...request.Option) (*budgets.DeleteSubscriberOutput, error) {
+	key := notificationKey(input.Notification)
+	m.calls = append(m.calls, "DeleteSubscriber "+key+" "+aws.StringValue(input.Subscriber.Address))
+
+	subscribers := []*budgets.Subscriber{}
+	for _, s := range m.subscribers[key] {
+		if aws.StringValue(s.Address) != aws.StringValue(input.Subscriber.Address) {
+			subscribers = append(subscribers, s)
+		}
+	}
+	m.subscribers[key] = subscribers
+
+	return &budgets.DeleteSubscriberOutput{}, nil
+}
+
+// mockSNSClient is a fake sns client that keeps track of the topics that exist
+type mockSNSClient struct {
+	snsiface.SNSAPI
+	t      *testing.T
+	topics map[string]struct{}
+	calls  []string
+}
+
+func newMockSNSClient(t *testing.T) *mockSNSClient {
+	return &mockSNSClient{
+		t:      t,
+		topics: map[string]struct{}{},
+	}
+}
+
+func (m *mockSNSClient) CreateTopicWithContext(ctx context.Context, input *sns.CreateTopicInput, opts ...request.Option) (*sns.CreateTopicOutput, error) {
+	arn := "arn:aws:sns:us-east-1:012345678901:" + aws.StringValue(input.Name)
+	m.calls = append(m.calls, "CreateTopic "+aws.StringValue(input.Name))
+	m.topics[arn] = struct{}{}
+	return &sns.CreateTopicOutput{TopicArn: aws.String(arn)}, nil
+}
+
+func (m *mockSNSClient) DeleteTopicWithContext(ctx context.Context, input *sns.DeleteTopicInput, opts ...request.Option) (*sns.DeleteTopicOutput, error) {
+	m.calls = append(m.calls, "DeleteTopic "+aws.StringValue(input.TopicArn))
+	delete(m.topics, aws.StringValue(input.TopicArn))
+	return &sns.DeleteTopicOutput{}, nil
+}
+
+const testBudgetName = "spinup_testorg_spc-123_MONTHLY-01"
+
+func testBudget() *budgets.Budget {
+	return &budgets.Budget{
+		BudgetName:      aws.String(testBudgetName),
+		BudgetLimit:     &budgets.Spend{Amount: aws.String("100.0"), Unit: aws.String("USD")},
+		BudgetType:      aws.String("COST"),
+		CalculatedSpend: &budgets.CalculatedSpend{ActualSpend: &budgets.Spend{Amount: aws.String("12.0"), Unit: aws.String("USD")}},
+		TimeUnit:        aws.String("MONTHLY"),
+	}
+}
+
+func testAlertNotification(notificationType string, threshold float64) *budgets.Notification {
+	return &budgets.Notification{
+		ComparisonOperator: aws.String("GREATER_THAN"),
+		NotificationType:   aws.String(notificationType),
+		NotificationState:  aws.String("OK"),
+		Threshold:          aws.Float64(threshold),
+	}
+}
+
+func emailSubscriber(address string) *budgets.Subscriber {
+	return &budgets.Subscriber{Address: aws.String(address), SubscriptionType: aws.String("EMAIL")}
+}
+
+var testTopicSubscriber = &budgets.Subscriber{
+	Address:          aws.String("arn:aws:sns:us-east-1:012345678901:budgets-" + testBudgetName),
+	SubscriptionType: aws.String("SNS"),
+}
+
+func TestUpdateBudget(t *testing.T) {
+	client := newMockBudgetsClient(t, testBudget())
+	client.addNotification(testAlertNotification("ACTUAL", 80), testTopicSubscriber, emailSubscriber("a@example.com"), emailSubscriber("b@example.com"))
+	client.addNotification(testAlertNotification("ACTUAL", 100), testTopicSubscriber, emailSubscriber("a@example.com"))
+	client.addNotification(testAlertNotification("FORECASTED", 100), testTopicSubscriber, emailSubscriber("a@example.com"))
+
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+	out, err := o.UpdateBudget(context.TODO(), "012345678901", "spc-123", testBudgetName, &BudgetUpdateRequest{
+		Amount: "250",
+		Alerts: []*BudgetAlert{
+			// unchanged settings, subscribers are diffed
+			{Addresses: []string{"a@example.com", "c@example.com"}, ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", Threshold: 80, ThresholdType: "PERCENTAGE"},
+			// new settings, an unmatched notification is updated in place
+			{Addresses: []string{"a@example.com"}, ComparisonOperator: "GREATER_THAN", NotificationType: "FORECASTED", Threshold: 120, ThresholdType: "PERCENTAGE"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expectedCalls := []string{
+		"UpdateBudget",
+		"DeleteNotification FORECASTED_GREATER_THAN_PERCENTAGE_100",
+		"DeleteSubscriber ACTUAL_GREATER_THAN_PERCENTAGE_80 b@example.com",
+		"CreateSubscriber ACTUAL_GREATER_THAN_PERCENTAGE_80 c@example.com",
+		"UpdateNotification ACTUAL_GREATER_THAN_PERCENTAGE_100 FORECASTED_GREATER_THAN_PERCENTAGE_120",
+	}
+	if !reflect.DeepEqual(client.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, client.calls)
+	}
+
+	if client.budget.CalculatedSpend != nil {
+		t.Error("expected calculated spend to be cleared when updating the budget")
+	}
+
+	if out.Amount != "250" {
+		t.Errorf("expected amount 250, got %s", out.Amount)
+	}
+
+	if len(out.Alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(out.Alerts))
+	}
+
+	for _, a := range out.Alerts {
+		if a.NotificationType == "ACTUAL" && !reflect.DeepEqual(a.Addresses, []string{"a@example.com", "c@example.com"}) {
+			t.Errorf("expected ACTUAL alert addresses to be updated, got %v", a.Addresses)
+		}
+	}
+
+	// the updated notification keeps the topic subscriber
+	if subs := client.subscribers["FORECASTED_GREATER_THAN_PERCENTAGE_120"]; len(subs) != 2 || aws.StringValue(subs[0].SubscriptionType) != "SNS" {
+		t.Errorf("expected updated notification to keep its subscribers, got %+v", subs)
+	}
+}
+
+func TestUpdateBudgetCreatesAlerts(t *testing.T) {
+	client := newMockBudgetsClient(t, testBudget())
+	client.addNotification(testAlertNotification("ACTUAL", 80), testTopicSubscriber, emailSubscriber("a@example.com"))
+
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+	if _, err := o.UpdateBudget(context.TODO(), "012345678901", "spc-123", testBudgetName, &BudgetUpdateRequest{
+		Alerts: []*BudgetAlert{
+			{Addresses: []string{"a@example.com"}, ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", Threshold: 80, ThresholdType: "PERCENTAGE"},
+			{Addresses: []string{"b@example.com"}, ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", Threshold: 100, ThresholdType: "PERCENTAGE"},
+		},
+	}, nil); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	// the amount isn't updated and the new alert is created with the budget topic
+	expectedCalls := []string{"CreateNotification ACTUAL_GREATER_THAN_PERCENTAGE_100"}
+	if !reflect.DeepEqual(client.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, client.calls)
+	}
+
+	expectedSubscribers := []*budgets.Subscriber{testTopicSubscriber, emailSubscriber("b@example.com")}
+	if subs := client.subscribers["ACTUAL_GREATER_THAN_PERCENTAGE_100"]; !reflect.DeepEqual(subs, expectedSubscribers) {
+		t.Errorf("expected subscribers %+v, got %+v", expectedSubscribers, subs)
+	}
+}
+
+func TestUpdateBudgetInvalid(t *testing.T) {
+	validAlert := &BudgetAlert{Addresses: []string{"a@example.com"}, ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", Threshold: 80, ThresholdType: "PERCENTAGE"}
+
+	tests := []struct {
+		name   string
+		budget string
+		req    *BudgetUpdateRequest
+	}{
+		{"other space", "spinup_testorg_spc-999_MONTHLY-01", &BudgetUpdateRequest{Amount: "10"}},
+		{"empty request", testBudgetName, &BudgetUpdateRequest{}},
+		{"actions not configured", testBudgetName, &BudgetUpdateRequest{Actions: []*BudgetAction{testStopInstancesAction()}}},
+		{"empty alerts", testBudgetName, &BudgetUpdateRequest{Alerts: []*BudgetAlert{}}},
+		{"too many alerts", testBudgetName, &BudgetUpdateRequest{Alerts: []*BudgetAlert{validAlert, validAlert, validAlert, validAlert, validAlert, validAlert}}},
+		{"no addresses", testBudgetName, &BudgetUpdateRequest{Alerts: []*BudgetAlert{{ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", ThresholdType: "PERCENTAGE"}}}},
+		{"invalid threshold type", testBudgetName, &BudgetUpdateRequest{Alerts: []*BudgetAlert{{Addresses: []string{"a@example.com"}, ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", ThresholdType: "PERCENT"}}}},
+	}
+
+	for _, tt := range tests {
+		client := newMockBudgetsClient(t, testBudget())
+		o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+		_, err := o.UpdateBudget(context.TODO(), "012345678901", "spc-123", tt.budget, tt.req, nil)
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected error code %s, got: %s", tt.name, apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", tt.name, err)
+		}
+
+		if len(client.calls) != 0 {
+			t.Errorf("%s: expected no changes, got %v", tt.name, client.calls)
+		}
+	}
+}
+
+func testBudgetCreateRequest() *BudgetCreateRequest {
+	return &BudgetCreateRequest{
+		Amount:   "100",
+		TimeUnit: "MONTHLY",
+		Alerts: []*BudgetAlert{
+			{Addresses: []string{"a@example.com"}, ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", Threshold: 80, ThresholdType: "PERCENTAGE"},
+		},
+	}
+}
+
+func TestCreateBudget(t *testing.T) {
+	client := newMockBudgetsClient(t, nil)
+	snsClient := newMockSNSClient(t)
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: snsClient}, "testorg")
+
+	out, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", testBudgetCreateRequest())
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if out.Name != testBudgetName {
+		t.Errorf("expected budget name %s, got %s", testBudgetName, out.Name)
+	}
+
+	expectedCalls := []string{"CreateTopic budgets-" + testBudgetName}
+	if !reflect.DeepEqual(snsClient.calls, expectedCalls) {
+		t.Errorf("expected sns calls %v, got %v", expectedCalls, snsClient.calls)
+	}
+
+	expectedSubscribers := []*budgets.Subscriber{testTopicSubscriber, emailSubscriber("a@example.com")}
+	if subs := client.subscribers["ACTUAL_GREATER_THAN_PERCENTAGE_80"]; !reflect.DeepEqual(subs, expectedSubscribers) {
+		t.Errorf("expected subscribers %+v, got %+v", expectedSubscribers, subs)
+	}
+}
+
+func TestCreateBudgetRollback(t *testing.T) {
+	client := newMockBudgetsClient(t, nil)
+	client.createErr = apierror.New(apierror.ErrConflict, "budget already exists", nil)
+	snsClient := newMockSNSClient(t)
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: snsClient}, "testorg")
+
+	if _, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", testBudgetCreateRequest()); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// the topic created for the budget is deleted
+	expectedCalls := []string{
+		"CreateTopic budgets-" + testBudgetName,
+		"DeleteTopic " + aws.StringValue(testTopicSubscriber.Address),
+	}
+	if !reflect.DeepEqual(snsClient.calls, expectedCalls) {
+		t.Errorf("expected sns calls %v, got %v", expectedCalls, snsClient.calls)
+	}
+
+	if len(snsClient.topics) != 0 {
+		t.Errorf("expected no orphaned topics, got %v", snsClient.topics)
+	}
+}
+
+func TestCreateBudgetDuplicate(t *testing.T) {
+	client := newMockBudgetsClient(t, testBudget())
+	snsClient := newMockSNSClient(t)
+	snsClient.topics[aws.StringValue(testTopicSubscriber.Address)] = struct{}{}
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: snsClient}, "testorg")
+
+	_, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", testBudgetCreateRequest())
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrConflict {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrConflict, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %v", err)
+	}
+
+	// the topic of the existing budget is left alone
+	if len(snsClient.calls) != 0 || len(client.calls) != 0 {
+		t.Errorf("expected no changes, got %v %v", snsClient.calls, client.calls)
+	}
+
+	if _, ok := snsClient.topics[aws.StringValue(testTopicSubscriber.Address)]; !ok {
+		t.Error("expected the topic of the existing budget to exist")
+	}
+}
+
+func TestCreateBudgetConcurrentDuplicate(t *testing.T) {
+	client := newMockBudgetsClient(t, nil)
+	client.createErr = awserr.New(budgets.ErrCodeDuplicateRecordException, "budget already exists", nil)
+	snsClient := newMockSNSClient(t)
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: snsClient}, "testorg")
+
+	_, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", testBudgetCreateRequest())
+	if aerr, ok := err.(apierror.Error); !ok || aerr.Code != apierror.ErrConflict {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+
+	// the budget created concurrently keeps its topic
+	expectedCalls := []string{"CreateTopic budgets-" + testBudgetName}
+	if !reflect.DeepEqual(snsClient.calls, expectedCalls) {
+		t.Errorf("expected sns calls %v, got %v", expectedCalls, snsClient.calls)
+	}
+}
+
+func TestCreateBudgetInvalid(t *testing.T) {
+	validAlert := &BudgetAlert{Addresses: []string{"a@example.com"}, ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", Threshold: 80, ThresholdType: "PERCENTAGE"}
+
+	tests := []struct {
+		name string
+		req  *BudgetCreateRequest
+	}{
+		{"no amount", &BudgetCreateRequest{Alerts: []*BudgetAlert{validAlert}}},
+		{"invalid time unit", &BudgetCreateRequest{Amount: "10", TimeUnit: "WEEKLY", Alerts: []*BudgetAlert{validAlert}}},
+		{"no alerts", &BudgetCreateRequest{Amount: "10"}},
+		{"too many alerts", &BudgetCreateRequest{Amount: "10", Alerts: []*BudgetAlert{validAlert, validAlert, validAlert, validAlert, validAlert, validAlert}}},
+		{"no addresses", &BudgetCreateRequest{Amount: "10", Alerts: []*BudgetAlert{validAlert, {ComparisonOperator: "GREATER_THAN", NotificationType: "ACTUAL", ThresholdType: "PERCENTAGE"}}}},
+		{"invalid comparison operator", &BudgetCreateRequest{Amount: "10", Alerts: []*BudgetAlert{{Addresses: []string{"a@example.com"}, ComparisonOperator: "GREATER", NotificationType: "ACTUAL", ThresholdType: "PERCENTAGE"}}}},
+	}
+
+	for _, tt := range tests {
+		client := newMockBudgetsClient(t, nil)
+		snsClient := newMockSNSClient(t)
+		o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: snsClient}, "testorg")
+
+		_, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", tt.req)
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected error code %s, got: %s", tt.name, apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", tt.name, err)
+		}
+
+		// nothing is created for an invalid request
+		if len(snsClient.calls) != 0 || len(client.calls) != 0 {
+			t.Errorf("%s: expected no resources to be created, got %v %v", tt.name, snsClient.calls, client.calls)
+		}
+	}
+}
+
+func testBudgetPeriod(start, budgeted, actual string) *budgets.BudgetedAndActualAmounts {
+	s, _ := time.Parse("2006-01-02", start)
+	return &budgets.BudgetedAndActualAmounts{
+		ActualAmount:   &budgets.Spend{Amount: aws.String(actual), Unit: aws.String("USD")},
+		BudgetedAmount: &budgets.Spend{Amount: aws.String(budgeted), Unit: aws.String("USD")},
+		TimePeriod:     &budgets.TimePeriod{Start: aws.Time(s), End: aws.Time(s.AddDate(0, 1, 0))},
+	}
+}
+
+func TestGetBudgetHistory(t *testing.T) {
+	client := newMockBudgetsClient(t, testBudget())
+	client.history = []*budgets.BudgetedAndActualAmounts{
+		testBudgetPeriod("2021-01-01", "100.0", "80.5"),
+		testBudgetPeriod("2021-02-01", "100.0", "120.25"),
+		testBudgetPeriod("2021-03-01", "100.0", "100.0"),
+	}
+
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+	out, err := o.GetBudgetHistory(context.TODO(), "012345678901", "spc-123", testBudgetName, "2021-01-01", "2021-04-01")
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := &BudgetHistoryResponse{
+		Name:     testBudgetName,
+		Start:    "2021-01-01",
+		End:      "2021-04-01",
+		Exceeded: 1,
+		Periods: []*BudgetPeriod{
+			{Start: "2021-01-01", End: "2021-02-01", Budgeted: "100.0", Actual: "80.5", Unit: "USD"},
+			{Start: "2021-02-01", End: "2021-03-01", Budgeted: "100.0", Actual: "120.25", Unit: "USD", Exceeded: true},
+			{Start: "2021-03-01", End: "2021-04-01", Budgeted: "100.0", Actual: "100.0", Unit: "USD"},
+		},
+	}
+
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	expectedCalls := []string{"DescribeBudgetPerformanceHistory 2021-01-01 2021-04-01"}
+	if !reflect.DeepEqual(client.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, client.calls)
+	}
+
+	tests := []struct {
+		name, budget, start, end string
+	}{
+		{"other space", "spinup_testorg_spc-999_MONTHLY-01", "", ""},
+		{"other org", "spinup_otherorg_spc-123_MONTHLY-01", "", ""},
+		{"invalid start", testBudgetName, "2021-01", ""},
+		{"end before start", testBudgetName, "2021-04-01", "2021-01-01"},
+	}
+
+	for _, tt := range tests {
+		client := newMockBudgetsClient(t, testBudget())
+		o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+		_, err := o.GetBudgetHistory(context.TODO(), "012345678901", "spc-123", tt.budget, tt.start, tt.end)
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected error code %s, got: %s", tt.name, apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", tt.name, err)
+		}
+
+		if len(client.calls) != 0 {
+			t.Errorf("%s: expected no calls, got %v", tt.name, client.calls)
+		}
+	}
+}
+
+func TestParseBudgetHistoryTime(t *testing.T) {
+	now := time.Date(2021, time.May, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		start, end         string
+		wantStart, wantEnd string
+		wantErr            bool
+	}{
+		{"", "", "2020-06-01", "2021-06-01", false},
+		{"2021-01-01", "", "2021-01-01", "2021-06-01", false},
+		{"", "2021-01-01", "2020-01-01", "2021-01-01", false},
+		{"2021-01-01", "2021-01-01", "", "", true},
+		{"", "2021-01", "", "", true},
+	}
+
+	for _, tt := range tests {
+		start, end, err := parseBudgetHistoryTime(tt.start, tt.end, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBudgetHistoryTime(%s, %s) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+			continue
+		}
+
+		if tt.wantErr {
+			continue
+		}
+
+		if s, e := start.Format("2006-01-02"), end.Format("2006-01-02"); s != tt.wantStart || e != tt.wantEnd {
+			t.Errorf("parseBudgetHistoryTime(%s, %s) = %s, %s, want %s, %s", tt.start, tt.end, s, e, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
+
+func TestCreateBudgetTypes(t *testing.T) {
+	tests := []struct {
+		name            string
+		req             func(r *BudgetCreateRequest)
+		wantName        string
+		wantLimit       *budgets.Spend
+		wantCostTypes   *budgets.CostTypes
+		wantCostFilters map[string][]*string
+	}{
+		{
+			name: "cost budget with cost type overrides",
+			req: func(r *BudgetCreateRequest) {
+				r.CostTypes = &BudgetCostTypes{IncludeCredit: aws.Bool(true), UseAmortized: aws.Bool(true)}
+			},
+			wantName:  testBudgetName,
+			wantLimit: &budgets.Spend{Amount: aws.String("100"), Unit: aws.String("USD")},
+			wantCostTypes: &budgets.CostTypes{
+				IncludeCredit:            aws.Bool(true),
+				IncludeDiscount:          aws.Bool(true),
+				IncludeOtherSubscription: aws.Bool(false),
+				IncludeRecurring:         aws.Bool(true),
+				IncludeRefund:            aws.Bool(false),
+				IncludeSubscription:      aws.Bool(true),
+				IncludeSupport:           aws.Bool(false),
+				IncludeTax:               aws.Bool(false),
+				IncludeUpfront:           aws.Bool(false),
+				UseAmortized:             aws.Bool(true),
+				UseBlended:               aws.Bool(false),
+			},
+			wantCostFilters: map[string][]*string{
+				"TagKeyValue": aws.StringSlice([]string{"user:spinup:spaceid$spc-123"}),
+			},
+		},
+		{
+			name: "usage budget",
+			req: func(r *BudgetCreateRequest) {
+				r.Type = "USAGE"
+				r.Amount = "500"
+				r.Unit = "Hrs"
+				r.CostFilters = map[string][]string{
+					"UsageTypeGroup": {"EC2: Running Hours"},
+					"InstanceType":   {"p3.2xlarge", "g4dn.xlarge"},
+				}
+			},
+			wantName:  "spinup_testorg_spc-123_USAGE_MONTHLY-01",
+			wantLimit: &budgets.Spend{Amount: aws.String("500"), Unit: aws.String("Hrs")},
+			wantCostFilters: map[string][]*string{
+				"TagKeyValue":    aws.StringSlice([]string{"user:spinup:spaceid$spc-123"}),
+				"UsageTypeGroup": aws.StringSlice([]string{"EC2: Running Hours"}),
+				"InstanceType":   aws.StringSlice([]string{"p3.2xlarge", "g4dn.xlarge"}),
+			},
+		},
+		{
+			name: "ri utilization budget",
+			req: func(r *BudgetCreateRequest) {
+				r.Type = "RI_UTILIZATION"
+				r.Amount = ""
+				r.CostFilters = map[string][]string{"Service": {"Amazon Elastic Compute Cloud - Compute"}}
+			},
+			wantName:  "spinup_testorg_spc-123_RI_UTILIZATION_MONTHLY-01",
+			wantLimit: &budgets.Spend{Amount: aws.String("100"), Unit: aws.String("PERCENTAGE")},
+			wantCostFilters: map[string][]*string{
+				"TagKeyValue": aws.StringSlice([]string{"user:spinup:spaceid$spc-123"}),
+				"Service":     aws.StringSlice([]string{"Amazon Elastic Compute Cloud - Compute"}),
+			},
+		},
+		{
+			name: "savings plans coverage budget",
+			req: func(r *BudgetCreateRequest) {
+				r.Type = "SAVINGS_PLANS_COVERAGE"
+			},
+			wantName:  "spinup_testorg_spc-123_SAVINGS_PLANS_COVERAGE_MONTHLY-01",
+			wantLimit: &budgets.Spend{Amount: aws.String("100"), Unit: aws.String("PERCENTAGE")},
+			wantCostFilters: map[string][]*string{
+				"TagKeyValue": aws.StringSlice([]string{"user:spinup:spaceid$spc-123"}),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		client := newMockBudgetsClient(t, nil)
+		o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: newMockSNSClient(t)}, "testorg")
+
+		req := testBudgetCreateRequest()
+		tt.req(req)
+
+		out, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", req)
+		if err != nil {
+			t.Errorf("%s: expected nil error, got %s", tt.name, err)
+			continue
+		}
+
+		if out.Name != tt.wantName || out.Type != req.Type || out.Unit != aws.StringValue(tt.wantLimit.Unit) {
+			t.Errorf("%s: unexpected response %+v", tt.name, out)
+		}
+
+		if name := aws.StringValue(client.budget.BudgetName); name != tt.wantName {
+			t.Errorf("%s: expected budget name %s, got %s", tt.name, tt.wantName, name)
+		}
+
+		if !reflect.DeepEqual(client.budget.BudgetLimit, tt.wantLimit) {
+			t.Errorf("%s: expected budget limit %+v, got %+v", tt.name, tt.wantLimit, client.budget.BudgetLimit)
+		}
+
+		if !reflect.DeepEqual(client.budget.CostTypes, tt.wantCostTypes) {
+			t.Errorf("%s: expected cost types %+v, got %+v", tt.name, tt.wantCostTypes, client.budget.CostTypes)
+		}
+
+		if !reflect.DeepEqual(client.budget.CostFilters, tt.wantCostFilters) {
+			t.Errorf("%s: expected cost filters %+v, got %+v", tt.name, tt.wantCostFilters, client.budget.CostFilters)
+		}
+	}
+}
+
+func TestCreateBudgetTypesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		req  func(r *BudgetCreateRequest)
+	}{
+		{"invalid type", func(r *BudgetCreateRequest) { r.Type = "GPU" }},
+		{"invalid amount", func(r *BudgetCreateRequest) { r.Amount = "lots" }},
+		{"negative amount", func(r *BudgetCreateRequest) { r.Amount = "-10" }},
+		{"cost budget in another unit", func(r *BudgetCreateRequest) { r.Unit = "EUR" }},
+		{"amortized and blended", func(r *BudgetCreateRequest) {
+			r.CostTypes = &BudgetCostTypes{UseAmortized: aws.Bool(true), UseBlended: aws.Bool(true)}
+		}},
+		{"space tag filter", func(r *BudgetCreateRequest) {
+			r.CostFilters = map[string][]string{"TagKeyValue": {"user:spinup:spaceid$spc-999"}}
+		}},
+		{"empty filter", func(r *BudgetCreateRequest) { r.CostFilters = map[string][]string{"Region": {}} }},
+		{"usage budget without unit", func(r *BudgetCreateRequest) {
+			r.Type = "USAGE"
+			r.CostFilters = map[string][]string{"UsageTypeGroup": {"EC2: Running Hours"}}
+		}},
+		{"usage budget without usage type", func(r *BudgetCreateRequest) {
+			r.Type = "USAGE"
+			r.Unit = "Hrs"
+		}},
+		{"usage budget with cost types", func(r *BudgetCreateRequest) {
+			r.Type = "USAGE"
+			r.Unit = "Hrs"
+			r.CostFilters = map[string][]string{"UsageTypeGroup": {"EC2: Running Hours"}}
+			r.CostTypes = &BudgetCostTypes{IncludeCredit: aws.Bool(true)}
+		}},
+		{"ri budget without service", func(r *BudgetCreateRequest) { r.Type = "RI_COVERAGE" }},
+		{"savings plans budget amount", func(r *BudgetCreateRequest) {
+			r.Type = "SAVINGS_PLANS_UTILIZATION"
+			r.Amount = "80"
+		}},
+		{"savings plans budget unit", func(r *BudgetCreateRequest) {
+			r.Type = "SAVINGS_PLANS_UTILIZATION"
+			r.Unit = "USD"
+		}},
+	}
+
+	for _, tt := range tests {
+		client := newMockBudgetsClient(t, nil)
+		snsClient := newMockSNSClient(t)
+		o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, &snsapi.SNS{Service: snsClient}, "testorg")
+
+		req := testBudgetCreateRequest()
+		tt.req(req)
+
+		_, err := o.CreateBudget(context.TODO(), "012345678901", "spc-123", req)
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected error code %s, got: %s", tt.name, apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", tt.name, err)
+		}
+
+		if len(snsClient.calls) != 0 || len(client.calls) != 0 {
+			t.Errorf("%s: expected no resources to be created, got %v %v", tt.name, snsClient.calls, client.calls)
+		}
+	}
+}
+
+func TestUpdateBudgetTypes(t *testing.T) {
+	usage := testBudget()
+	usage.BudgetType = aws.String("USAGE")
+	usage.BudgetLimit = &budgets.Spend{Amount: aws.String("500"), Unit: aws.String("Hrs")}
+
+	client := newMockBudgetsClient(t, usage)
+	o := newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+	if _, err := o.UpdateBudget(context.TODO(), "012345678901", "spc-123", testBudgetName, &BudgetUpdateRequest{Amount: "750"}, nil); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	// the unit of the budget is kept
+	expected := &budgets.Spend{Amount: aws.String("750"), Unit: aws.String("Hrs")}
+	if !reflect.DeepEqual(client.budget.BudgetLimit, expected) {
+		t.Errorf("expected budget limit %+v, got %+v", expected, client.budget.BudgetLimit)
+	}
+
+	coverage := testBudget()
+	coverage.BudgetType = aws.String("SAVINGS_PLANS_COVERAGE")
+	coverage.BudgetLimit = &budgets.Spend{Amount: aws.String("100"), Unit: aws.String("PERCENTAGE")}
+
+	client = newMockBudgetsClient(t, coverage)
+	o = newBudgetsOrchestrator(&budgetsapi.Budgets{Service: client}, nil, "testorg")
+
+	_, err := o.UpdateBudget(context.TODO(), "012345678901", "spc-123", testBudgetName, &BudgetUpdateRequest{Amount: "80"}, nil)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %v", err)
+	}
+
+	if len(client.calls) != 0 {
+		t.Errorf("expected no changes, got %v", client.calls)
+	}
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// unassignedCostCenter is the cost center for spaces without a configured cost center
+const unassignedCostCenter = "UNASSIGNED"
+
+// chargebackSignatureAlgorithm is the algorithm used to sign the chargeback report artifacts
+const chargebackSignatureAlgorithm = "HMAC-SHA256"
+
+type chargebackReq struct {
+	account, month string
+}
+
+// generateChargebackReport computes the cost for each configured cost center for a completed month
+// and stores the signed JSON and CSV report artifacts.  Reports are immutable, a conflict is returned
+// if the report for the month already exists.  The artifacts are written under a new generation for
+// each attempt and the manifest is written last, so the manifest is the only commit point.  A failed
+// attempt leaves unreferenced artifacts behind, but doesn't prevent a retry.
+func (o *costExplorerOrchestrator) generateChargebackReport(ctx context.Context, req *chargebackReq) (*ChargebackManifest, error) {
+	if o.server.chargeback == nil || o.server.reportStore == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "chargeback reports are not configured", nil)
+	}
+
+	start, end, err := parseChargebackMonth(req.month, time.Now())
+	if err != nil {
+		return nil, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	metric := o.server.chargeback.Metric
+	if metric == "" {
+		metric = costexplorer.MetricUnblendedCost
+	}
+
+	// reports are in currency, so only the cost (not usage) metrics are supported
+	if err := validateOption("chargeback metric", metric, forecastMetrics); err != nil {
+		return nil, err
+	}
+
+	manifestKey := chargebackKey(req.account, req.month, "manifest.json")
+	if _, err := o.server.reportStore.Get(ctx, manifestKey); err == nil {
+		msg := fmt.Sprintf("chargeback report for %s in account %s already exists", req.month, req.account)
+		return nil, apierror.New(apierror.ErrConflict, msg, nil)
+	} else if aerr, ok := errors.Cause(err).(apierror.Error); !ok || aerr.Code != apierror.ErrNotFound {
+		return nil, err
+	}
+
+	costs, _, _, err := o.getCostForSpaces(ctx, &spacesCostReq{
+		account: req.account,
+		start:   start,
+		end:     end,
+		metric:  metric,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := newChargebackReport(costs, o.server.chargeback.CostCenters)
+	report.Account = req.account
+	report.Month = req.month
+	report.Metric = metric
+	report.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	report.TimePeriod = &costexplorer.DateInterval{
+		Start: aws.String(start),
+		End:   aws.String(end),
+	}
+
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, apierror.New(apierror.ErrInternalError, "failed to marshal chargeback report", err)
+	}
+
+	reportCSV, err := chargebackCSV(report)
+	if err != nil {
+		return nil, apierror.New(apierror.ErrInternalError, "failed to generate chargeback report csv", err)
+	}
+
+	manifest := &ChargebackManifest{
+		Account:    req.account,
+		Month:      req.month,
+		Generation: uuid.New().String(),
+		Algorithm:  chargebackSignatureAlgorithm,
+	}
+
+	key := []byte(o.server.chargeback.SigningKey)
+	for _, a := range []struct {
+		format, contentType string
+		body                []byte
+	}{
+		{exportJSON, "application/json", reportJSON},
+		{exportCSV, "text/csv", reportCSV},
+	} {
+		artifact := &ChargebackArtifact{
+			Key:         chargebackKey(req.account, req.month, manifest.Generation+"/report."+a.format),
+			Format:      a.format,
+			ContentType: a.contentType,
+			SHA256:      fmt.Sprintf("%x", sha256.Sum256(a.body)),
+			Signature:   signArtifact(key, a.body),
+		}
+
+		if err := o.server.reportStore.Put(ctx, artifact.Key, a.body, a.contentType); err != nil {
+			return nil, err
+		}
+
+		manifest.Artifacts = append(manifest.Artifacts, artifact)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, apierror.New(apierror.ErrInternalError, "failed to marshal chargeback manifest", err)
+	}
+
+	if err := o.server.reportStore.Put(ctx, manifestKey, manifestJSON, "application/json"); err != nil {
+		return nil, err
+	}
+
+	log.Infof("generated chargeback report for %s in account %s", req.month, req.account)
+
+	return manifest, nil
+}
+
+// getChargebackArtifact gets a stored chargeback report artifact in the given format and verifies its signature
+func (s *server) getChargebackArtifact(ctx context.Context, account, month, format string) ([]byte, *ChargebackArtifact, error) {
+	if s.chargeback == nil || s.reportStore == nil {
+		return nil, nil, apierror.New(apierror.ErrBadRequest, "chargeback reports are not configured", nil)
+	}
+
+	if _, _, err := parseChargebackMonth(month, time.Now()); err != nil {
+		return nil, nil, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	m, err := s.reportStore.Get(ctx, chargebackKey(account, month, "manifest.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := ChargebackManifest{}
+	if err := json.Unmarshal(m, &manifest); err != nil {
+		return nil, nil, apierror.New(apierror.ErrInternalError, "failed to unmarshal chargeback manifest", err)
+	}
+
+	var artifact *ChargebackArtifact
+	for _, a := range manifest.Artifacts {
+		if a.Format == format {
+			artifact = a
+		}
+	}
+
+	if artifact == nil {
+		msg := fmt.Sprintf("chargeback report for %s is not available as %s", month, format)
+		return nil, nil, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	body, err := s.reportStore.Get(ctx, artifact.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !verifyArtifact([]byte(s.chargeback.SigningKey), body, artifact.Signature) {
+		msg := fmt.Sprintf("chargeback report artifact %s failed signature verification", artifact.Key)
+		return nil, nil, apierror.New(apierror.ErrInternalError, msg, nil)
+	}
+
+	return body, artifact, nil
+}
+
+// newChargebackReport sums the cost of the spaces for each cost center.  Spaces without a cost
+// center are billed to UNASSIGNED.  Cost centers and their spaces are sorted by name.
+func newChargebackReport(costs []*SpaceCost, costCenters map[string]string) *ChargebackReport {
+	report := &ChargebackReport{CostCenters: []*CostCenterCost{}}
+
+	byCostCenter := map[string]*CostCenterCost{}
+	for _, c := range costs {
+		name, ok := costCenters[c.Space]
+		if !ok || name == "" {
+			name = unassignedCostCenter
+		}
+
+		cc, ok := byCostCenter[name]
+		if !ok {
+			cc = &CostCenterCost{CostCenter: name, Spaces: []*SpaceCost{}}
+			byCostCenter[name] = cc
+			report.CostCenters = append(report.CostCenters, cc)
+		}
+
+		cc.Spaces = append(cc.Spaces, c)
+		cc.Amount += c.Amount
+		report.Total += c.Amount
+
+		if report.Unit == "" {
+			report.Unit = c.Unit
+		}
+	}
+
+	sort.Slice(report.CostCenters, func(i, j int) bool {
+		return report.CostCenters[i].CostCenter < report.CostCenters[j].CostCenter
+	})
+
+	for _, cc := range report.CostCenters {
+		sort.Slice(cc.Spaces, func(i, j int) bool {
+			return cc.Spaces[i].Space < cc.Spaces[j].Space
+		})
+		cc.Amount = roundCents(cc.Amount)
+	}
+	report.Total = roundCents(report.Total)
+
+	return report
+}
+
+// chargebackCSV flattens the report into one row per space.  Space ids come from the space tag, so
+// cells are escaped with csvCell to prevent CSV injection.
+func chargebackCSV(report *ChargebackReport) ([]byte, error) {
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"Month", "CostCenter", "Space", "Amount", "Unit"}); err != nil {
+		return nil, err
+	}
+
+	for _, cc := range report.CostCenters {
+		for _, s := range cc.Spaces {
+			row := []string{
+				csvCell(report.Month, false),
+				csvCell(cc.CostCenter, false),
+				csvCell(s.Space, false),
+				csvCell(strconv.FormatFloat(s.Amount, 'f', -1, 64), true),
+				csvCell(s.Unit, false),
+			}
+
+			if err := w.Write(row); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parseChargebackMonth parses the month (YYYY-MM) and returns the start and end dates.  Only
+// completed months are supported since the reports are immutable.
+func parseChargebackMonth(month string, now time.Time) (string, string, error) {
+	m, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid month '%s', expected YYYY-MM", month)
+	}
+
+	start := time.Date(m.Year(), m.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	if end.After(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)) {
+		return "", "", fmt.Errorf("chargeback reports are only available for completed months")
+	}
+
+	return start.Format("2006-01-02"), end.Format("2006-01-02"), nil
+}
+
+// chargebackKey returns the storage key for a chargeback report artifact
+func chargebackKey(account, month, name string) string {
+	return fmt.Sprintf("chargeback/%s/%s/%s", account, month, name)
+}
+
+// signArtifact returns the hex encoded HMAC-SHA256 signature of the artifact
+func signArtifact(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyArtifact verifies the hex encoded HMAC-SHA256 signature of the artifact
+func verifyArtifact(key, body []byte, signature string) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
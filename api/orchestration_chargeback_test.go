@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/YaleSpinup/cost-api/common"
+	ce "github.com/YaleSpinup/cost-api/costexplorer"
+	"github.com/YaleSpinup/cost-api/reportstore"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/costexplorer/costexploreriface"
+	"github.com/patrickmn/go-cache"
+)
+
+// mockChargebackCEClient returns the cost of two spaces
+type mockChargebackCEClient struct {
+	costexploreriface.CostExplorerAPI
+}
+
+func (m *mockChargebackCEClient) GetCostAndUsageWithContext(ctx context.Context, input *costexplorer.GetCostAndUsageInput, opts ...request.Option) (*costexplorer.GetCostAndUsageOutput, error) {
+	group := func(space, amount string) *costexplorer.Group {
+		return &costexplorer.Group{
+			Keys: aws.StringSlice([]string{spaceTagKey + "$" + space}),
+			Metrics: map[string]*costexplorer.MetricValue{
+				"UnblendedCost": {Amount: aws.String(amount), Unit: aws.String("USD")},
+			},
+		}
+	}
+
+	return &costexplorer.GetCostAndUsageOutput{
+		ResultsByTime: []*costexplorer.ResultByTime{
+			{
+				TimePeriod: input.TimePeriod,
+				Groups:     []*costexplorer.Group{group("spc-1", "10.00"), group("spc-2", "5.50")},
+			},
+		},
+	}, nil
+}
+
+// failingReportStore fails to put artifacts with the key suffix until it's reset
+type failingReportStore struct {
+	reportstore.ReportStore
+	failSuffix string
+}
+
+func (f *failingReportStore) Put(ctx context.Context, key string, obj []byte, contentType string) error {
+	if f.failSuffix != "" && strings.HasSuffix(key, f.failSuffix) {
+		return apierror.New(apierror.ErrInternalError, fmt.Sprintf("failed to save report artifact %s", key), nil)
+	}
+	return f.ReportStore.Put(ctx, key, obj, contentType)
+}
+
+func TestParseChargebackMonth(t *testing.T) {
+	now := time.Date(2021, time.May, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		month      string
+		start, end string
+		wantErr    bool
+	}{
+		{"2021-04", "2021-04-01", "2021-05-01", false},
+		{"2020-12", "2020-12-01", "2021-01-01", false},
+		{"2021-05", "", "", true},
+		{"2021-06", "", "", true},
+		{"2021-4", "", "", true},
+		{"april", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tt := range tests {
+		start, end, err := parseChargebackMonth(tt.month, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseChargebackMonth(%s) error = %v, wantErr %v", tt.month, err, tt.wantErr)
+			continue
+		}
+
+		if start != tt.start || end != tt.end {
+			t.Errorf("parseChargebackMonth(%s) = %s, %s, want %s, %s", tt.month, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestNewChargebackReport(t *testing.T) {
+	costs := []*SpaceCost{
+		{Space: "spc-3", Amount: 0.005, Unit: "USD"},
+		{Space: "spc-2", Amount: 10.111, Unit: "USD"},
+		{Space: "spc-1", Amount: 5.222, Unit: "USD"},
+		{Space: "spc-4", Amount: 1, Unit: "USD"},
+	}
+
+	report := newChargebackReport(costs, map[string]string{
+		"spc-1": "Teaching",
+		"spc-2": "Research",
+		"spc-3": "Research",
+		"spc-4": "",
+	})
+
+	expected := &ChargebackReport{
+		Unit:  "USD",
+		Total: 16.34,
+		CostCenters: []*CostCenterCost{
+			{
+				CostCenter: "Research",
+				Amount:     10.12,
+				Spaces: []*SpaceCost{
+					{Space: "spc-2", Amount: 10.111, Unit: "USD"},
+					{Space: "spc-3", Amount: 0.005, Unit: "USD"},
+				},
+			},
+			{
+				CostCenter: "Teaching",
+				Amount:     5.22,
+				Spaces: []*SpaceCost{
+					{Space: "spc-1", Amount: 5.222, Unit: "USD"},
+				},
+			},
+			{
+				CostCenter: "UNASSIGNED",
+				Amount:     1,
+				Spaces: []*SpaceCost{
+					{Space: "spc-4", Amount: 1, Unit: "USD"},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+
+	// no costs still returns an empty list of cost centers
+	report = newChargebackReport(nil, nil)
+	if report.CostCenters == nil || len(report.CostCenters) != 0 || report.Total != 0 {
+		t.Errorf("expected empty report, got %+v", report)
+	}
+}
+
+func TestChargebackCSV(t *testing.T) {
+	report := &ChargebackReport{
+		Month: "2021-04",
+		CostCenters: []*CostCenterCost{
+			{
+				CostCenter: "Research, Lab",
+				Spaces: []*SpaceCost{
+					{Space: "spc-2", Amount: 10.111, Unit: "USD"},
+					{Space: "spc-3", Amount: 0.005, Unit: "USD"},
+				},
+			},
+		},
+	}
+
+	out, err := chargebackCSV(report)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := `Month,CostCenter,Space,Amount,Unit
+2021-04,"Research, Lab",spc-2,10.111,USD
+2021-04,"Research, Lab",spc-3,0.005,USD
+`
+	if string(out) != expected {
+		t.Errorf("expected csv:\n%s\ngot:\n%s", expected, string(out))
+	}
+}
+
+func TestChargebackCSVInjection(t *testing.T) {
+	report := &ChargebackReport{
+		Month: "2021-04",
+		CostCenters: []*CostCenterCost{
+			{
+				CostCenter: unassignedCostCenter,
+				Spaces: []*SpaceCost{
+					{Space: "=HYPERLINK(\"http://example.com\")", Amount: 1, Unit: "USD"},
+					{Space: "+1+1", Amount: -2.5, Unit: "USD"},
+					{Space: "-1+1", Amount: 0, Unit: "USD"},
+					{Space: "@SUM(A1)", Amount: 3, Unit: "USD"},
+				},
+			},
+		},
+	}
+
+	out, err := chargebackCSV(report)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := `Month,CostCenter,Space,Amount,Unit
+2021-04,UNASSIGNED,"'=HYPERLINK(""http://example.com"")",1,USD
+2021-04,UNASSIGNED,'+1+1,-2.5,USD
+2021-04,UNASSIGNED,'-1+1,0,USD
+2021-04,UNASSIGNED,'@SUM(A1),3,USD
+`
+	if string(out) != expected {
+		t.Errorf("expected csv:\n%s\ngot:\n%s", expected, string(out))
+	}
+}
+
+func TestSignArtifact(t *testing.T) {
+	key := []byte("secret")
+	body := []byte(`{"Month":"2021-04"}`)
+
+	sig := signArtifact(key, body)
+	if len(sig) != 64 {
+		t.Errorf("expected 64 character hex signature, got %s", sig)
+	}
+
+	if !verifyArtifact(key, body, sig) {
+		t.Error("expected signature to verify")
+	}
+
+	if verifyArtifact([]byte("other"), body, sig) {
+		t.Error("expected signature with different key to fail verification")
+	}
+
+	if verifyArtifact(key, []byte(`{"Month":"2021-03"}`), sig) {
+		t.Error("expected signature of modified body to fail verification")
+	}
+
+	if verifyArtifact(key, body, "not-hex") {
+		t.Error("expected invalid signature to fail verification")
+	}
+}
+
+func TestGenerateChargebackReportRetry(t *testing.T) {
+	fs, err := reportstore.NewFileStore(filepath.Join(t.TempDir(), "reports"))
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+	store := &failingReportStore{ReportStore: fs, failSuffix: "report.csv"}
+
+	o := &costExplorerOrchestrator{
+		client: &ce.CostExplorer{Service: &mockChargebackCEClient{}},
+		server: &server{
+			org:         "testorg",
+			resultCache: cache.New(CacheExpireTime, CachePurgeTime),
+			reportStore: store,
+			chargeback: &common.Chargeback{
+				CostCenters: map[string]string{"spc-1": "cc-1"},
+				SigningKey:  "secret",
+			},
+		},
+	}
+
+	req := &chargebackReq{account: "123", month: "2021-04"}
+
+	// the json artifact is stored before the csv artifact fails
+	if _, err := o.generateChargebackReport(context.TODO(), req); err == nil {
+		t.Fatal("expected error when saving the csv artifact, got nil")
+	}
+
+	if _, _, err := o.server.getChargebackArtifact(context.TODO(), "123", "2021-04", exportJSON); err == nil {
+		t.Error("expected error getting an uncommitted report, got nil")
+	}
+
+	// retrying after the failure succeeds with a new generation
+	store.failSuffix = ""
+	manifest, err := o.generateChargebackReport(context.TODO(), req)
+	if err != nil {
+		t.Fatalf("expected nil error on retry, got %s", err)
+	}
+
+	if manifest.Generation == "" || len(manifest.Artifacts) != 2 {
+		t.Fatalf("expected a manifest with a generation and 2 artifacts, got %+v", manifest)
+	}
+
+	for _, a := range manifest.Artifacts {
+		expected := fmt.Sprintf("chargeback/123/2021-04/%s/report.%s", manifest.Generation, a.Format)
+		if a.Key != expected {
+			t.Errorf("expected artifact key %s, got %s", expected, a.Key)
+		}
+	}
+
+	body, _, err := o.server.getChargebackArtifact(context.TODO(), "123", "2021-04", exportJSON)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	report := ChargebackReport{}
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if report.Total != 15.5 || len(report.CostCenters) != 2 {
+		t.Errorf("expected a report with a total of 15.5 for 2 cost centers, got %+v", report)
+	}
+
+	// once the manifest is committed the report is immutable
+	_, err = o.generateChargebackReport(context.TODO(), req)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrConflict {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrConflict, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %v", err)
+	}
+}
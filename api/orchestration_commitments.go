@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+type commitmentsReq struct {
+	account, commitment, report, start, end, granularity string
+}
+
+// getCommitmentsReport gets the account level utilization or coverage report for savings plans
+// or reserved instances.  By default, it reports from the start of the month until now.
+func (o *costExplorerOrchestrator) getCommitmentsReport(ctx context.Context, req *commitmentsReq) (interface{}, bool, time.Duration, error) {
+	start, end, err := parseTime(req.start, req.end)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	if req.granularity == "" {
+		req.granularity = costexplorer.GranularityMonthly
+	}
+
+	if req.granularity != costexplorer.GranularityDaily && req.granularity != costexplorer.GranularityMonthly {
+		msg := fmt.Sprintf("invalid granularity '%s', valid values DAILY, MONTHLY", req.granularity)
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	timePeriod := &costexplorer.DateInterval{
+		Start: aws.String(start),
+		End:   aws.String(end),
+	}
+
+	var fetch func() (interface{}, error)
+	switch {
+	case req.commitment == "savingsplans" && req.report == "utilization":
+		fetch = func() (interface{}, error) {
+			return o.client.GetSavingsPlansUtilization(ctx, &costexplorer.GetSavingsPlansUtilizationInput{
+				Granularity: aws.String(req.granularity),
+				TimePeriod:  timePeriod,
+			})
+		}
+	case req.commitment == "savingsplans" && req.report == "coverage":
+		fetch = func() (interface{}, error) {
+			return o.client.GetSavingsPlansCoverage(ctx, &costexplorer.GetSavingsPlansCoverageInput{
+				Granularity: aws.String(req.granularity),
+				TimePeriod:  timePeriod,
+			})
+		}
+	case req.commitment == "reservations" && req.report == "utilization":
+		fetch = func() (interface{}, error) {
+			return o.client.GetReservationUtilization(ctx, &costexplorer.GetReservationUtilizationInput{
+				Granularity: aws.String(req.granularity),
+				TimePeriod:  timePeriod,
+			})
+		}
+	case req.commitment == "reservations" && req.report == "coverage":
+		fetch = func() (interface{}, error) {
+			return o.client.GetReservationCoverage(ctx, &costexplorer.GetReservationCoverageInput{
+				Granularity: aws.String(req.granularity),
+				TimePeriod:  timePeriod,
+			})
+		}
+	default:
+		msg := fmt.Sprintf("invalid commitments report %s/%s", req.commitment, req.report)
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	cacheKey := fmt.Sprintf("commitments_%s_%s_%s_%s_%s_%s", req.account, req.commitment, req.report, start, end, req.granularity)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and commitments-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := fetch()
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return out, false, 0, nil
+	}
+
+	log.Debugf("found cached object: %s", c)
+
+	return c, true, time.Until(expire), nil
+}
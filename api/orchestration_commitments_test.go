@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+	ce "github.com/YaleSpinup/cost-api/costexplorer"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/aws/aws-sdk-go/service/costexplorer/costexploreriface"
+	"github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
+)
+
+// mockCommitmentsCEClient records the commitments reports requested with their granularity
+type mockCommitmentsCEClient struct {
+	costexploreriface.CostExplorerAPI
+	calls []string
+}
+
+func (m *mockCommitmentsCEClient) GetSavingsPlansUtilizationWithContext(ctx context.Context, input *costexplorer.GetSavingsPlansUtilizationInput, opts ...request.Option) (*costexplorer.GetSavingsPlansUtilizationOutput, error) {
+	m.calls = append(m.calls, "GetSavingsPlansUtilization "+aws.StringValue(input.Granularity))
+	return &costexplorer.GetSavingsPlansUtilizationOutput{}, nil
+}
+
+func (m *mockCommitmentsCEClient) GetSavingsPlansCoverageWithContext(ctx context.Context, input *costexplorer.GetSavingsPlansCoverageInput, opts ...request.Option) (*costexplorer.GetSavingsPlansCoverageOutput, error) {
+	m.calls = append(m.calls, "GetSavingsPlansCoverage "+aws.StringValue(input.Granularity))
+	return &costexplorer.GetSavingsPlansCoverageOutput{}, nil
+}
+
+func (m *mockCommitmentsCEClient) GetReservationUtilizationWithContext(ctx context.Context, input *costexplorer.GetReservationUtilizationInput, opts ...request.Option) (*costexplorer.GetReservationUtilizationOutput, error) {
+	m.calls = append(m.calls, "GetReservationUtilization "+aws.StringValue(input.Granularity))
+	return &costexplorer.GetReservationUtilizationOutput{}, nil
+}
+
+func (m *mockCommitmentsCEClient) GetReservationCoverageWithContext(ctx context.Context, input *costexplorer.GetReservationCoverageInput, opts ...request.Option) (*costexplorer.GetReservationCoverageOutput, error) {
+	m.calls = append(m.calls, "GetReservationCoverage "+aws.StringValue(input.Granularity))
+	return &costexplorer.GetReservationCoverageOutput{}, nil
+}
+
+func TestGetCommitmentsReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      commitmentsReq
+		wantCall string
+		wantType interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "savings plans utilization",
+			req:      commitmentsReq{commitment: "savingsplans", report: "utilization"},
+			wantCall: "GetSavingsPlansUtilization MONTHLY",
+			wantType: &costexplorer.GetSavingsPlansUtilizationOutput{},
+		},
+		{
+			name:     "savings plans coverage",
+			req:      commitmentsReq{commitment: "savingsplans", report: "coverage", granularity: "DAILY"},
+			wantCall: "GetSavingsPlansCoverage DAILY",
+			wantType: []*costexplorer.SavingsPlansCoverage{},
+		},
+		{
+			name:     "reservations utilization",
+			req:      commitmentsReq{commitment: "reservations", report: "utilization"},
+			wantCall: "GetReservationUtilization MONTHLY",
+			wantType: &costexplorer.GetReservationUtilizationOutput{},
+		},
+		{
+			name:     "reservations coverage",
+			req:      commitmentsReq{commitment: "reservations", report: "coverage", granularity: "DAILY"},
+			wantCall: "GetReservationCoverage DAILY",
+			wantType: &costexplorer.GetReservationCoverageOutput{},
+		},
+		{
+			name:    "invalid commitment",
+			req:     commitmentsReq{commitment: "spotinstances", report: "utilization"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid report",
+			req:     commitmentsReq{commitment: "savingsplans", report: "recommendations"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid granularity",
+			req:     commitmentsReq{commitment: "savingsplans", report: "utilization", granularity: "HOURLY"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid start",
+			req:     commitmentsReq{commitment: "savingsplans", report: "utilization", start: "2021-05"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		client := &mockCommitmentsCEClient{}
+		o := &costExplorerOrchestrator{
+			client: &ce.CostExplorer{Service: client},
+			server: &server{resultCache: cache.New(CacheExpireTime, CachePurgeTime)},
+		}
+
+		req := tt.req
+		req.account = "012345678901"
+
+		out, cached, _, err := o.getCommitmentsReport(context.TODO(), &req)
+		if tt.wantErr {
+			if aerr, ok := errors.Cause(err).(apierror.Error); !ok || aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected bad request apierror, got %v", tt.name, err)
+			}
+
+			if len(client.calls) != 0 {
+				t.Errorf("%s: expected no calls, got %v", tt.name, client.calls)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: expected nil error, got %s", tt.name, err)
+			continue
+		}
+
+		if cached {
+			t.Errorf("%s: expected uncached response", tt.name)
+		}
+
+		if reflect.TypeOf(out) != reflect.TypeOf(tt.wantType) {
+			t.Errorf("%s: expected %T, got %T", tt.name, tt.wantType, out)
+		}
+
+		// the same report is cached
+		if _, cached, _, err := o.getCommitmentsReport(context.TODO(), &req); err != nil || !cached {
+			t.Errorf("%s: expected cached response, got cached %t, error %v", tt.name, cached, err)
+		}
+
+		expectedCalls := []string{tt.wantCall}
+		if !reflect.DeepEqual(client.calls, expectedCalls) {
+			t.Errorf("%s: expected calls %v, got %v", tt.name, expectedCalls, client.calls)
+		}
+	}
+}
+
+func TestGetCommitmentsReportCacheKey(t *testing.T) {
+	client := &mockCommitmentsCEClient{}
+	o := &costExplorerOrchestrator{
+		client: &ce.CostExplorer{Service: client},
+		server: &server{resultCache: cache.New(CacheExpireTime, CachePurgeTime)},
+	}
+
+	// each part of the request splits the cache
+	reqs := []commitmentsReq{
+		{account: "012345678901", commitment: "savingsplans", report: "utilization", start: "2021-04-01", end: "2021-05-01"},
+		{account: "012345678901", commitment: "savingsplans", report: "utilization", start: "2021-04-01", end: "2021-05-01", granularity: "DAILY"},
+		{account: "012345678901", commitment: "savingsplans", report: "utilization", start: "2021-03-01", end: "2021-05-01"},
+		{account: "012345678901", commitment: "savingsplans", report: "utilization", start: "2021-04-01", end: "2021-04-15"},
+		{account: "012345678901", commitment: "savingsplans", report: "coverage", start: "2021-04-01", end: "2021-05-01"},
+		{account: "012345678901", commitment: "reservations", report: "utilization", start: "2021-04-01", end: "2021-05-01"},
+		{account: "999999999999", commitment: "savingsplans", report: "utilization", start: "2021-04-01", end: "2021-05-01"},
+	}
+
+	for _, r := range reqs {
+		req := r
+		if _, cached, _, err := o.getCommitmentsReport(context.TODO(), &req); err != nil || cached {
+			t.Errorf("%+v: expected uncached response, got cached %t, error %v", r, cached, err)
+		}
+	}
+
+	if len(client.calls) != len(reqs) {
+		t.Errorf("expected %d calls, got %v", len(reqs), client.calls)
+	}
+
+	// the default granularity shares the cache with the explicit MONTHLY granularity
+	req := commitmentsReq{account: "012345678901", commitment: "savingsplans", report: "utilization", start: "2021-04-01", end: "2021-05-01", granularity: "MONTHLY"}
+	if _, cached, _, err := o.getCommitmentsReport(context.TODO(), &req); err != nil || !cached {
+		t.Errorf("expected cached response for the default granularity, got cached %t, error %v", cached, err)
+	}
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+type costComparisonReq struct {
+	account, spaceID, period, metric string
+}
+
+// compareCostsForSpace compares the cost for the current period to date with the same span of
+// the previous period, broken down by service.  Both cost queries are cached by getCostAndUsageForSpace.
+func (o *costExplorerOrchestrator) compareCostsForSpace(ctx context.Context, req *costComparisonReq) (*CostComparisonResponse, bool, error) {
+	if req.period == "" {
+		req.period = "month"
+	}
+
+	if req.metric == "" {
+		req.metric = costexplorer.MetricUnblendedCost
+	}
+
+	if !validCostMetric(req.metric) {
+		msg := fmt.Sprintf("invalid metric '%s', valid values %s", req.metric, strings.Join(costexplorer.Metric_Values(), ", "))
+		return nil, false, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	current, previous, err := comparisonPeriods(req.period, time.Now().UTC())
+	if err != nil {
+		return nil, false, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	currentOut, currentCached, _, err := o.getCostAndUsageForSpace(ctx, &costAndUsageReq{
+		account:     req.account,
+		spaceID:     req.spaceID,
+		start:       aws.StringValue(current.Start),
+		end:         aws.StringValue(current.End),
+		groupBy:     []string{"SERVICE"},
+		granularity: costexplorer.GranularityMonthly,
+		metrics:     []string{req.metric},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	previousOut, previousCached, _, err := o.getCostAndUsageForSpace(ctx, &costAndUsageReq{
+		account:     req.account,
+		spaceID:     req.spaceID,
+		start:       aws.StringValue(previous.Start),
+		end:         aws.StringValue(previous.End),
+		groupBy:     []string{"SERVICE"},
+		granularity: costexplorer.GranularityMonthly,
+		metrics:     []string{req.metric},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	out, err := compareCosts(currentOut, previousOut, req.metric)
+	if err != nil {
+		return nil, false, err
+	}
+
+	out.Period = req.period
+	out.Metric = req.metric
+	out.CurrentPeriod = current
+	out.PreviousPeriod = previous
+
+	return out, currentCached && previousCached, nil
+}
+
+// comparisonPeriods returns the current period to date and the same span at the start of the
+// previous period.  Supported periods are month and quarter.
+func comparisonPeriods(period string, now time.Time) (*costexplorer.DateInterval, *costexplorer.DateInterval, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var months int
+	switch period {
+	case "month":
+		months = 1
+	case "quarter":
+		months = 3
+	default:
+		return nil, nil, fmt.Errorf("invalid period '%s', valid values month, quarter", period)
+	}
+
+	// start of the current period, quarters start in January, April, July and October
+	startMonth := time.Month((int(today.Month())-1)/months*months + 1)
+	currentStart := time.Date(today.Year(), startMonth, 1, 0, 0, 0, 0, time.UTC)
+	previousStart := currentStart.AddDate(0, -months, 0)
+
+	// on the first day of a period, compare the first day
+	currentEnd := today
+	if !currentEnd.After(currentStart) {
+		currentEnd = currentStart.AddDate(0, 0, 1)
+	}
+
+	// compare the same number of days, but never overlap the current period
+	previousEnd := previousStart.AddDate(0, 0, int(currentEnd.Sub(currentStart).Hours()/24))
+	if previousEnd.After(currentStart) {
+		previousEnd = currentStart
+	}
+
+	current := &costexplorer.DateInterval{
+		Start: aws.String(currentStart.Format("2006-01-02")),
+		End:   aws.String(currentEnd.Format("2006-01-02")),
+	}
+
+	previous := &costexplorer.DateInterval{
+		Start: aws.String(previousStart.Format("2006-01-02")),
+		End:   aws.String(previousEnd.Format("2006-01-02")),
+	}
+
+	return current, previous, nil
+}
+
+// compareCosts sums the metric per service for the current and previous results and computes the deltas
+func compareCosts(current, previous []*costexplorer.ResultByTime, metric string) (*CostComparisonResponse, error) {
+	metricKey := metricResponseKey(metric)
+
+	currentCosts, currentUnit, err := sumGroups(current, metricKey)
+	if err != nil {
+		return nil, err
+	}
+
+	previousCosts, previousUnit, err := sumGroups(previous, metricKey)
+	if err != nil {
+		return nil, err
+	}
+
+	unit := currentUnit
+	if unit == "" {
+		unit = previousUnit
+	}
+
+	services := map[string]struct{}{}
+	for s := range currentCosts {
+		services[s] = struct{}{}
+	}
+	for s := range previousCosts {
+		services[s] = struct{}{}
+	}
+
+	out := &CostComparisonResponse{
+		Unit:     unit,
+		Services: []*ServiceCostComparison{},
+	}
+
+	for s := range services {
+		c := currentCosts[s]
+		p := previousCosts[s]
+
+		out.Current += c
+		out.Previous += p
+		out.Services = append(out.Services, &ServiceCostComparison{
+			Service:      s,
+			Current:      c,
+			Previous:     p,
+			Delta:        c - p,
+			DeltaPercent: deltaPercent(c, p),
+		})
+	}
+
+	out.Delta = out.Current - out.Previous
+	out.DeltaPercent = deltaPercent(out.Current, out.Previous)
+
+	// biggest changes first
+	sort.SliceStable(out.Services, func(i, j int) bool {
+		di, dj := math.Abs(out.Services[i].Delta), math.Abs(out.Services[j].Delta)
+		if di == dj {
+			return out.Services[i].Service < out.Services[j].Service
+		}
+		return di > dj
+	})
+
+	return out, nil
+}
+
+// sumGroups sums the metric for each group key across all of the time periods
+func sumGroups(results []*costexplorer.ResultByTime, metricKey string) (map[string]float64, string, error) {
+	var unit string
+	sums := map[string]float64{}
+	for _, r := range results {
+		for _, g := range r.Groups {
+			m, ok := g.Metrics[metricKey]
+			if !ok {
+				continue
+			}
+
+			amount, err := strconv.ParseFloat(aws.StringValue(m.Amount), 64)
+			if err != nil {
+				msg := fmt.Sprintf("failed to parse amount '%s' for metric %s", aws.StringValue(m.Amount), metricKey)
+				return nil, "", apierror.New(apierror.ErrInternalError, msg, err)
+			}
+
+			key := strings.Join(aws.StringValueSlice(g.Keys), "|")
+			sums[key] += amount
+			unit = aws.StringValue(m.Unit)
+		}
+	}
+
+	return sums, unit, nil
+}
+
+// deltaPercent returns the percentage change from previous to current, or nil if there was no previous cost
+func deltaPercent(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+
+	p := (current - previous) / previous * 100
+	return &p
+}
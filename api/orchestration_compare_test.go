@@ -0,0 +1,148 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+func TestComparisonPeriods(t *testing.T) {
+	tests := []struct {
+		name     string
+		period   string
+		now      time.Time
+		current  *costexplorer.DateInterval
+		previous *costexplorer.DateInterval
+		wantErr  bool
+	}{
+		{
+			name:     "month",
+			period:   "month",
+			now:      time.Date(2021, time.May, 15, 12, 0, 0, 0, time.UTC),
+			current:  &costexplorer.DateInterval{Start: aws.String("2021-05-01"), End: aws.String("2021-05-15")},
+			previous: &costexplorer.DateInterval{Start: aws.String("2021-04-01"), End: aws.String("2021-04-15")},
+		},
+		{
+			name:     "first day of month",
+			period:   "month",
+			now:      time.Date(2021, time.May, 1, 12, 0, 0, 0, time.UTC),
+			current:  &costexplorer.DateInterval{Start: aws.String("2021-05-01"), End: aws.String("2021-05-02")},
+			previous: &costexplorer.DateInterval{Start: aws.String("2021-04-01"), End: aws.String("2021-04-02")},
+		},
+		{
+			name:     "end of long month",
+			period:   "month",
+			now:      time.Date(2021, time.March, 31, 12, 0, 0, 0, time.UTC),
+			current:  &costexplorer.DateInterval{Start: aws.String("2021-03-01"), End: aws.String("2021-03-31")},
+			previous: &costexplorer.DateInterval{Start: aws.String("2021-02-01"), End: aws.String("2021-03-01")},
+		},
+		{
+			name:     "january",
+			period:   "month",
+			now:      time.Date(2021, time.January, 10, 0, 0, 0, 0, time.UTC),
+			current:  &costexplorer.DateInterval{Start: aws.String("2021-01-01"), End: aws.String("2021-01-10")},
+			previous: &costexplorer.DateInterval{Start: aws.String("2020-12-01"), End: aws.String("2020-12-10")},
+		},
+		{
+			name:     "quarter",
+			period:   "quarter",
+			now:      time.Date(2021, time.May, 15, 0, 0, 0, 0, time.UTC),
+			current:  &costexplorer.DateInterval{Start: aws.String("2021-04-01"), End: aws.String("2021-05-15")},
+			previous: &costexplorer.DateInterval{Start: aws.String("2021-01-01"), End: aws.String("2021-02-14")},
+		},
+		{
+			name:    "invalid",
+			period:  "fortnight",
+			now:     time.Date(2021, time.May, 15, 0, 0, 0, 0, time.UTC),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, previous, err := comparisonPeriods(tt.period, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("comparisonPeriods() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !awsutil.DeepEqual(current, tt.current) {
+				t.Errorf("comparisonPeriods() current = %s, want %s", awsutil.Prettify(current), awsutil.Prettify(tt.current))
+			}
+			if !awsutil.DeepEqual(previous, tt.previous) {
+				t.Errorf("comparisonPeriods() previous = %s, want %s", awsutil.Prettify(previous), awsutil.Prettify(tt.previous))
+			}
+		})
+	}
+}
+
+func TestCompareCosts(t *testing.T) {
+	current := []*costexplorer.ResultByTime{
+		{
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    []*string{aws.String("Amazon Simple Storage Service")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("15")},
+				},
+				{
+					Keys:    []*string{aws.String("Amazon Elastic Compute Cloud - Compute")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("100")},
+				},
+			},
+		},
+	}
+
+	previous := []*costexplorer.ResultByTime{
+		{
+			Groups: []*costexplorer.Group{
+				{
+					Keys:    []*string{aws.String("Amazon Simple Storage Service")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("10")},
+				},
+				{
+					Keys:    []*string{aws.String("AWS Lambda")},
+					Metrics: map[string]*costexplorer.MetricValue{"UnblendedCost": testMetric("5")},
+				},
+			},
+		},
+	}
+
+	out, err := compareCosts(current, previous, "UNBLENDED_COST")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if out.Current != 115 || out.Previous != 15 || out.Delta != 100 {
+		t.Errorf("expected current 115, previous 15, delta 100, got %f, %f, %f", out.Current, out.Previous, out.Delta)
+	}
+
+	if out.Unit != "USD" {
+		t.Errorf("expected unit USD, got %s", out.Unit)
+	}
+
+	expected := []struct {
+		service      string
+		delta        float64
+		deltaPercent *float64
+	}{
+		{"Amazon Elastic Compute Cloud - Compute", 100, nil},
+		{"AWS Lambda", -5, aws.Float64(-100)},
+		{"Amazon Simple Storage Service", 5, aws.Float64(50)},
+	}
+
+	if len(out.Services) != len(expected) {
+		t.Fatalf("expected %d services, got %d", len(expected), len(out.Services))
+	}
+
+	for i, e := range expected {
+		s := out.Services[i]
+		if s.Service != e.service || s.Delta != e.delta {
+			t.Errorf("expected service %s with delta %f, got %s with delta %f", e.service, e.delta, s.Service, s.Delta)
+		}
+
+		if (e.deltaPercent == nil) != (s.DeltaPercent == nil) || (e.deltaPercent != nil && *e.deltaPercent != *s.DeltaPercent) {
+			t.Errorf("expected delta percent %v for %s, got %v", e.deltaPercent, e.service, s.DeltaPercent)
+		}
+	}
+}
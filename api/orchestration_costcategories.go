@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type costCategoryValuesReq struct {
+	account, name, start, end string
+}
+
+// listCostCategories lists the cost category definitions in the account
+func (o *costExplorerOrchestrator) listCostCategories(ctx context.Context, account string) ([]*costexplorer.CostCategoryReference, bool, time.Duration, error) {
+	cacheKey := fmt.Sprintf("costcategories_%s", account)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and costcategories-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.ListCostCategoryDefinitions(ctx, &costexplorer.ListCostCategoryDefinitionsInput{})
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return out, false, 0, nil
+	}
+
+	out, ok := c.([]*costexplorer.CostCategoryReference)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a []*costexplorer.CostCategoryReference!")
+	}
+
+	log.Debugf("found cached object: %s", out)
+
+	return out, true, time.Until(expire), nil
+}
+
+// getCostCategoryValues gets the values of a cost category in the account.  By default, it gets the
+// values from the start of the month until now.
+func (o *costExplorerOrchestrator) getCostCategoryValues(ctx context.Context, req *costCategoryValuesReq) ([]string, bool, time.Duration, error) {
+	if req.name == "" {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, "cost category name is required", nil)
+	}
+
+	start, end, err := parseTime(req.start, req.end)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	cacheKey := fmt.Sprintf("costcategoryvalues_%s_%s_%s_%s", req.account, req.name, start, end)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and costcategoryvalues-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetCostCategories(ctx, &costexplorer.GetCostCategoriesInput{
+			CostCategoryName: aws.String(req.name),
+			TimePeriod: &costexplorer.DateInterval{
+				Start: aws.String(start),
+				End:   aws.String(end),
+			},
+		})
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return out, false, 0, nil
+	}
+
+	out, ok := c.([]string)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a []string!")
+	}
+
+	log.Debugf("found cached object: %s", out)
+
+	return out, true, time.Until(expire), nil
+}
@@ -3,8 +3,12 @@ package api
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/YaleSpinup/apierror"
 	ce "github.com/YaleSpinup/cost-api/costexplorer"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
@@ -13,51 +17,92 @@ import (
 )
 
 type costAndUsageReq struct {
-	account, spaceID, start, end, groupBy string
+	account, spaceID, start, end, granularity string
+	groupBy, metrics, costCategories          []string
+
+	// filter is an additional expression ANDed with the space filter
+	filter *costexplorer.Expression
+}
+
+// maxGroupBy is the maximum number of group definitions supported by cost explorer
+const maxGroupBy = 2
+
+// defaultCostMetrics are the metrics returned for a cost and usage query when none are requested
+var defaultCostMetrics = []string{
+	costexplorer.MetricBlendedCost,
+	costexplorer.MetricUnblendedCost,
+	costexplorer.MetricUsageQuantity,
 }
 
+// maxDailyRange is the longest time period supported for DAILY granularity queries and
+// maxHourlyRange is how far back hourly data is available from cost explorer
+var (
+	maxDailyRange  = 366 * 24 * time.Hour
+	maxHourlyRange = 14 * 24 * time.Hour
+)
+
 func (o *costExplorerOrchestrator) getCostAndUsageForSpace(ctx context.Context, req *costAndUsageReq) ([]*costexplorer.ResultByTime, bool, time.Duration, error) {
 	start, end, err := parseTime(req.start, req.end)
 	if err != nil {
-		return nil, false, 0, nil
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	if req.granularity == "" {
+		req.granularity = costexplorer.GranularityMonthly
+	}
+
+	if err := validateGranularity(req.granularity, start, end); err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	if len(req.metrics) == 0 {
+		req.metrics = defaultCostMetrics
+	}
+
+	for _, m := range req.metrics {
+		if !validCostMetric(m) {
+			msg := fmt.Sprintf("invalid metric '%s', valid values %s", m, strings.Join(costexplorer.Metric_Values(), ", "))
+			return nil, false, 0, apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+	}
+
+	costCategories, err := parseCostCategories(req.costCategories)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	filters := append([]*costexplorer.Expression{inSpace(req.spaceID), inOrg(o.server.org), notTryIT()}, costCategories...)
+	if req.filter != nil {
+		filters = append(filters, req.filter)
 	}
 
 	input := costexplorer.GetCostAndUsageInput{
-		Filter:      ce.And(inSpace(req.spaceID), inOrg(o.server.org), notTryIT()),
-		Granularity: aws.String("MONTHLY"),
-		Metrics: []*string{
-			aws.String("BLENDED_COST"),
-			aws.String("UNBLENDED_COST"),
-			aws.String("USAGE_QUANTITY"),
-		},
+		Filter:      ce.And(filters...),
+		Granularity: aws.String(req.granularity),
+		Metrics:     aws.StringSlice(req.metrics),
 		TimePeriod: &costexplorer.DateInterval{
 			Start: aws.String(start),
 			End:   aws.String(end),
 		},
 	}
 
-	switch {
-	case req.groupBy == "RESOURCE_NAME":
-		input.GroupBy = []*costexplorer.GroupDefinition{
-			{
-				Key:  aws.String("Name"),
-				Type: aws.String("TAG"),
-			},
-		}
-	case req.groupBy != "":
-		input.GroupBy = []*costexplorer.GroupDefinition{
-			{
-				Key:  aws.String(req.groupBy),
-				Type: aws.String("DIMENSION"),
-			},
-		}
+	groupBy, err := parseGroupBy(req.groupBy)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	if len(groupBy) > 0 {
+		input.GroupBy = groupBy
 	}
 
 	// create a cacheKey more unique than spaceID for managing cache objects.
 	// Since we will accept date-range cost exploring and grouping, concatenate
-	// the spaceID, the start time, end time and group by so we can cache each
-	// time-based result
-	cacheKey := fmt.Sprintf("%s_%s_%s_%s_%s", req.account, req.spaceID, req.start, req.end, req.groupBy)
+	// the spaceID, the start time, end time, group by, granularity, metrics, cost
+	// categories and filter so we can cache each time-based result
+	cacheKey := fmt.Sprintf("%s_%s_%s_%s_%s_%s_%s_%s", req.account, req.spaceID, req.start, req.end, strings.Join(req.groupBy, ","), req.granularity, sortedKey(req.metrics), sortedKey(req.costCategories))
+	if req.filter != nil {
+		cacheKey = fmt.Sprintf("%s_%s", cacheKey, filterKey(req.filter))
+	}
 
 	log.Debugf("cacheKey: %s", cacheKey)
 
@@ -89,6 +134,415 @@ func (o *costExplorerOrchestrator) getCostAndUsageForSpace(ctx context.Context,
 	return out, true, time.Until(expire), nil
 }
 
+type spacesCostReq struct {
+	account, start, end, metric string
+}
+
+// spaceTagKey is the tag used to identify the space a resource belongs to
+const spaceTagKey = "spinup:spaceid"
+
+// getCostForSpaces gets the cost for all of the spaces in the org, grouped by the space tag.  The
+// costs are summed across the time period and untagged costs are dropped.
+func (o *costExplorerOrchestrator) getCostForSpaces(ctx context.Context, req *spacesCostReq) ([]*SpaceCost, bool, time.Duration, error) {
+	start, end, err := parseTime(req.start, req.end)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	if req.metric == "" {
+		req.metric = costexplorer.MetricUnblendedCost
+	}
+
+	if !validCostMetric(req.metric) {
+		msg := fmt.Sprintf("invalid metric '%s', valid values %s", req.metric, strings.Join(costexplorer.Metric_Values(), ", "))
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	input := costexplorer.GetCostAndUsageInput{
+		Filter:      ce.And(inOrg(o.server.org), notTryIT()),
+		Granularity: aws.String(costexplorer.GranularityMonthly),
+		GroupBy: []*costexplorer.GroupDefinition{
+			{
+				Key:  aws.String(spaceTagKey),
+				Type: aws.String(costexplorer.GroupDefinitionTypeTag),
+			},
+		},
+		Metrics: aws.StringSlice([]string{req.metric}),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String(start),
+			End:   aws.String(end),
+		},
+	}
+
+	cacheKey := fmt.Sprintf("spaces_%s_%s_%s_%s", req.account, start, end, req.metric)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and spaces-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetCostAndUsage(ctx, &input)
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		costs, err := toSpaceCosts(out, req.metric)
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, costs)
+
+		return costs, false, 0, nil
+	}
+
+	costs, ok := c.([]*SpaceCost)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a []*SpaceCost!")
+	}
+
+	log.Debugf("found cached object: %+v", costs)
+
+	return costs, true, time.Until(expire), nil
+}
+
+type resourceCostReq struct {
+	account, spaceID, service, granularity string
+	metrics                                []string
+}
+
+// defaultResourceService is the service used for resource level cost queries when none is passed,
+// cost explorer requires filtering on a service for GetCostAndUsageWithResources
+const defaultResourceService = "Amazon Elastic Compute Cloud - Compute"
+
+// resourceCostDays is the number of days of resource level data available from cost explorer
+const resourceCostDays = 14
+
+func (o *costExplorerOrchestrator) getResourceCostsForSpace(ctx context.Context, req *resourceCostReq) ([]*costexplorer.ResultByTime, bool, time.Duration, error) {
+	if req.service == "" {
+		req.service = defaultResourceService
+	}
+
+	if req.granularity == "" {
+		req.granularity = costexplorer.GranularityDaily
+	}
+
+	if len(req.metrics) == 0 {
+		req.metrics = defaultCostMetrics
+	}
+
+	for _, m := range req.metrics {
+		if !validCostMetric(m) {
+			msg := fmt.Sprintf("invalid metric '%s', valid values %s", m, strings.Join(costexplorer.Metric_Values(), ", "))
+			return nil, false, 0, apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+	}
+
+	// resource level data is only available for the last 14 days (including today)
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start := today.AddDate(0, 0, -(resourceCostDays - 1)).Format("2006-01-02")
+	end := today.AddDate(0, 0, 1).Format("2006-01-02")
+
+	if err := validateGranularity(req.granularity, start, end); err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	input := costexplorer.GetCostAndUsageWithResourcesInput{
+		Filter:      ce.And(inSpace(req.spaceID), inOrg(o.server.org), notTryIT(), ce.Dimension("SERVICE", []string{req.service})),
+		Granularity: aws.String(req.granularity),
+		GroupBy: []*costexplorer.GroupDefinition{
+			{
+				Key:  aws.String("RESOURCE_ID"),
+				Type: aws.String(costexplorer.GroupDefinitionTypeDimension),
+			},
+		},
+		Metrics: aws.StringSlice(req.metrics),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String(start),
+			End:   aws.String(end),
+		},
+	}
+
+	cacheKey := fmt.Sprintf("resources_%s_%s_%s_%s_%s_%s_%s", req.account, req.spaceID, start, end, req.service, req.granularity, sortedKey(req.metrics))
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and space-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetCostAndUsageWithResources(ctx, &input)
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return out, false, 0, nil
+	}
+
+	out, ok := c.([]*costexplorer.ResultByTime)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a []*costexplorer.ResultByTime!")
+	}
+
+	log.Debugf("found cached object: %s", out)
+
+	return out, true, time.Until(expire), nil
+}
+
+type costForecastReq struct {
+	account, spaceID, start, end, granularity, metric string
+	predictionInterval                                int64
+}
+
+func (o *costExplorerOrchestrator) getCostForecastForSpace(ctx context.Context, req *costForecastReq) (*costexplorer.GetCostForecastOutput, bool, time.Duration, error) {
+	start, end, err := parseForecastTime(req.start, req.end)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	if req.granularity == "" {
+		req.granularity = "MONTHLY"
+	}
+
+	if req.metric == "" {
+		req.metric = costexplorer.MetricUnblendedCost
+	}
+
+	if !validForecastMetric(req.metric) {
+		msg := fmt.Sprintf("invalid forecast metric '%s', valid values %s", req.metric, strings.Join(forecastMetrics, ", "))
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if req.predictionInterval == 0 {
+		req.predictionInterval = 80
+	}
+
+	if err := validateForecastOptions(req.granularity, req.predictionInterval); err != nil {
+		return nil, false, 0, err
+	}
+
+	input := costexplorer.GetCostForecastInput{
+		Filter:                  ce.And(inSpace(req.spaceID), inOrg(o.server.org), notTryIT()),
+		Granularity:             aws.String(req.granularity),
+		Metric:                  aws.String(req.metric),
+		PredictionIntervalLevel: aws.Int64(req.predictionInterval),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String(start),
+			End:   aws.String(end),
+		},
+	}
+
+	// the forecast is keyed on the computed time period so that the default (rest of the month)
+	// forecast isn't served from the cache after the day rolls over
+	cacheKey := fmt.Sprintf("forecast_%s_%s_%s_%s_%s_%s_%d", req.account, req.spaceID, start, end, req.granularity, req.metric, req.predictionInterval)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and space-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetCostForecast(ctx, &input)
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return out, false, 0, nil
+	}
+
+	out, ok := c.(*costexplorer.GetCostForecastOutput)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a *costexplorer.GetCostForecastOutput!")
+	}
+
+	log.Debugf("found cached object: %s", out)
+
+	return out, true, time.Until(expire), nil
+}
+
+type usageForecastReq struct {
+	account, spaceID, start, end, granularity, metric, usageTypeGroup string
+	predictionInterval                                                int64
+}
+
+// defaultUsageTypeGroup is the usage type group forecasted when none is passed
+const defaultUsageTypeGroup = "EC2: Running Hours"
+
+// usageForecastMetrics are the metrics supported by GetUsageForecast
+var usageForecastMetrics = []string{
+	costexplorer.MetricUsageQuantity,
+	costexplorer.MetricNormalizedUsageAmount,
+}
+
+// getUsageForecastForSpace gets the usage forecast for a usage type group (ie. "EC2: Running Hours" or
+// "S3: Storage - Standard") in a space.  A single usage type group is required so that the forecast
+// is in a single unit.
+func (o *costExplorerOrchestrator) getUsageForecastForSpace(ctx context.Context, req *usageForecastReq) (*costexplorer.GetUsageForecastOutput, bool, time.Duration, error) {
+	start, end, err := parseForecastTime(req.start, req.end)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	if req.granularity == "" {
+		req.granularity = "MONTHLY"
+	}
+
+	if req.metric == "" {
+		req.metric = costexplorer.MetricUsageQuantity
+	}
+
+	if err := validateOption("usage forecast metric", req.metric, usageForecastMetrics); err != nil {
+		return nil, false, 0, err
+	}
+
+	if req.usageTypeGroup == "" {
+		req.usageTypeGroup = defaultUsageTypeGroup
+	}
+
+	if req.predictionInterval == 0 {
+		req.predictionInterval = 80
+	}
+
+	if err := validateForecastOptions(req.granularity, req.predictionInterval); err != nil {
+		return nil, false, 0, err
+	}
+
+	input := costexplorer.GetUsageForecastInput{
+		Filter: ce.And(
+			inSpace(req.spaceID),
+			inOrg(o.server.org),
+			notTryIT(),
+			ce.Dimension(costexplorer.DimensionUsageTypeGroup, []string{req.usageTypeGroup}),
+		),
+		Granularity:             aws.String(req.granularity),
+		Metric:                  aws.String(req.metric),
+		PredictionIntervalLevel: aws.Int64(req.predictionInterval),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String(start),
+			End:   aws.String(end),
+		},
+	}
+
+	cacheKey := fmt.Sprintf("usageforecast_%s_%s_%s_%s_%s_%s_%s_%d", req.account, req.spaceID, start, end, req.granularity, req.metric, req.usageTypeGroup, req.predictionInterval)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and space-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetUsageForecast(ctx, &input)
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return out, false, 0, nil
+	}
+
+	out, ok := c.(*costexplorer.GetUsageForecastOutput)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a *costexplorer.GetUsageForecastOutput!")
+	}
+
+	log.Debugf("found cached object: %s", out)
+
+	return out, true, time.Until(expire), nil
+}
+
+// validateForecastOptions validates the granularity and prediction interval level of a forecast
+func validateForecastOptions(granularity string, predictionInterval int64) error {
+	if granularity != costexplorer.GranularityDaily && granularity != costexplorer.GranularityMonthly {
+		msg := fmt.Sprintf("invalid forecast granularity '%s', valid values DAILY, MONTHLY", granularity)
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	if predictionInterval < 51 || predictionInterval > 99 {
+		msg := fmt.Sprintf("invalid prediction interval level %d, must be between 51 and 99", predictionInterval)
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	return nil
+}
+
+func validCostMetric(metric string) bool {
+	for _, m := range costexplorer.Metric_Values() {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKey returns a stable, order independent representation of a list of values (ie. metrics or
+// cost categories) for use in a cache key or to compare lists
+func sortedKey(values []string) string {
+	v := make([]string, len(values))
+	copy(v, values)
+	sort.Strings(v)
+	return strings.Join(v, ",")
+}
+
+// forecastMetrics are the metrics supported by GetCostForecast
+var forecastMetrics = []string{
+	costexplorer.MetricAmortizedCost,
+	costexplorer.MetricBlendedCost,
+	costexplorer.MetricNetAmortizedCost,
+	costexplorer.MetricNetUnblendedCost,
+	costexplorer.MetricUnblendedCost,
+}
+
+func validForecastMetric(metric string) bool {
+	for _, m := range forecastMetrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// parseForecastTime returns the time range from today until the first day of next month if the
+// passed values are empty, otherwise it parses the strings and returns the values (or an error)
+func parseForecastTime(start, end string) (string, string, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if start == "" {
+		start = today.Format("2006-01-02")
+	}
+
+	startStamp, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return "", "", err
+	}
+
+	if startStamp.Before(today) {
+		return "", "", fmt.Errorf("forecast start time cannot be in the past")
+	}
+
+	if end == "" {
+		end = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	}
+
+	endStamp, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !endStamp.After(startStamp) {
+		return "", "", fmt.Errorf("end time should be after start time")
+	}
+
+	return startStamp.Format("2006-01-02"), endStamp.Format("2006-01-02"), nil
+}
+
 // parseTime returns time range from beginning of month to day-of-month now if the
 // passed values are empty otherwise, it parses the string and returns the value (or an error)
 func parseTime(start, end string) (string, string, error) {
@@ -125,6 +579,211 @@ func parseTime(start, end string) (string, string, error) {
 	return startStamp.Format("2006-01-02"), endStamp.Format("2006-01-02"), nil
 }
 
+// toSpaceCosts sums the metric for each space tag group across all of the time periods
+func toSpaceCosts(results []*costexplorer.ResultByTime, metric string) ([]*SpaceCost, error) {
+	// metrics are keyed by the camel case name in the response, ie. UNBLENDED_COST -> UnblendedCost
+	metricKey := metricResponseKey(metric)
+
+	costs := []*SpaceCost{}
+	bySpace := map[string]*SpaceCost{}
+	for _, r := range results {
+		for _, g := range r.Groups {
+			if len(g.Keys) == 0 {
+				continue
+			}
+
+			// tag group keys are returned as key$value
+			space := strings.TrimPrefix(aws.StringValue(g.Keys[0]), spaceTagKey+"$")
+			if space == "" {
+				continue
+			}
+
+			m, ok := g.Metrics[metricKey]
+			if !ok {
+				continue
+			}
+
+			amount, err := strconv.ParseFloat(aws.StringValue(m.Amount), 64)
+			if err != nil {
+				msg := fmt.Sprintf("failed to parse amount '%s' for space %s", aws.StringValue(m.Amount), space)
+				return nil, apierror.New(apierror.ErrInternalError, msg, err)
+			}
+
+			sc, ok := bySpace[space]
+			if !ok {
+				sc = &SpaceCost{
+					Space: space,
+					Unit:  aws.StringValue(m.Unit),
+				}
+				bySpace[space] = sc
+				costs = append(costs, sc)
+			}
+			sc.Amount += amount
+		}
+	}
+
+	return costs, nil
+}
+
+// metricResponseKey converts a metric name to the key used in the cost explorer response
+func metricResponseKey(metric string) string {
+	parts := strings.Split(strings.ToLower(metric), "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// sortSpaceCosts returns a sorted copy of the space costs, limited to the top N if top is greater than 0.
+// Costs can be sorted by amount (the default) or space, in asc or desc order.
+func sortSpaceCosts(costs []*SpaceCost, sortBy, order string, top int) ([]*SpaceCost, error) {
+	if sortBy == "" {
+		sortBy = "amount"
+	}
+
+	if order == "" {
+		order = "desc"
+		if sortBy == "space" {
+			order = "asc"
+		}
+	}
+
+	if order != "asc" && order != "desc" {
+		return nil, apierror.New(apierror.ErrBadRequest, fmt.Sprintf("invalid order '%s', valid values asc, desc", order), nil)
+	}
+
+	if top < 0 {
+		return nil, apierror.New(apierror.ErrBadRequest, "top must be a positive number", nil)
+	}
+
+	sorted := make([]*SpaceCost, len(costs))
+	copy(sorted, costs)
+
+	var less func(i, j int) bool
+	switch sortBy {
+	case "amount":
+		less = func(i, j int) bool { return sorted[i].Amount < sorted[j].Amount }
+	case "space":
+		less = func(i, j int) bool { return sorted[i].Space < sorted[j].Space }
+	default:
+		return nil, apierror.New(apierror.ErrBadRequest, fmt.Sprintf("invalid sort '%s', valid values amount, space", sortBy), nil)
+	}
+
+	if order == "desc" {
+		sort.SliceStable(sorted, func(i, j int) bool { return less(j, i) })
+	} else {
+		sort.SliceStable(sorted, less)
+	}
+
+	if top > 0 && top < len(sorted) {
+		sorted = sorted[:top]
+	}
+
+	return sorted, nil
+}
+
+// parseGroupBy converts the list of group by values into cost explorer group definitions. Values
+// prefixed with TAG: or COST_CATEGORY: group by the given tag key or cost category name, the custom
+// RESOURCE_NAME value groups by the Name tag and anything else is treated as a dimension.
+func parseGroupBy(groupBy []string) ([]*costexplorer.GroupDefinition, error) {
+	if len(groupBy) > maxGroupBy {
+		return nil, fmt.Errorf("up to %d groupby values are supported", maxGroupBy)
+	}
+
+	groups := []*costexplorer.GroupDefinition{}
+	for _, g := range groupBy {
+		var groupType, key string
+		switch {
+		case g == "RESOURCE_NAME":
+			groupType, key = costexplorer.GroupDefinitionTypeTag, "Name"
+		case strings.HasPrefix(g, "TAG:"):
+			groupType, key = costexplorer.GroupDefinitionTypeTag, strings.TrimPrefix(g, "TAG:")
+		case strings.HasPrefix(g, "COST_CATEGORY:"):
+			groupType, key = costexplorer.GroupDefinitionTypeCostCategory, strings.TrimPrefix(g, "COST_CATEGORY:")
+		default:
+			groupType, key = costexplorer.GroupDefinitionTypeDimension, g
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("invalid groupby '%s', key cannot be empty", g)
+		}
+
+		for _, e := range groups {
+			if aws.StringValue(e.Type) == groupType && aws.StringValue(e.Key) == key {
+				return nil, fmt.Errorf("duplicate groupby '%s'", g)
+			}
+		}
+
+		groups = append(groups, &costexplorer.GroupDefinition{
+			Key:  aws.String(key),
+			Type: aws.String(groupType),
+		})
+	}
+
+	return groups, nil
+}
+
+// parseCostCategories converts the list of Name:Value cost category filters into cost explorer
+// expressions.  Values for the same cost category are combined into a single expression (matching
+// any of the values) and the expressions are returned sorted by the cost category name.
+func parseCostCategories(costCategories []string) ([]*costexplorer.Expression, error) {
+	values := map[string][]string{}
+	names := []string{}
+	for _, c := range costCategories {
+		parts := strings.SplitN(c, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid costCategory '%s', expected Name:Value", c)
+		}
+
+		name, value := parts[0], parts[1]
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = append(values[name], value)
+	}
+
+	sort.Strings(names)
+
+	expressions := []*costexplorer.Expression{}
+	for _, n := range names {
+		expressions = append(expressions, ce.CostCategory(n, values[n]))
+	}
+
+	return expressions, nil
+}
+
+// validateGranularity validates the granularity against the (parsed) date range.  Hourly
+// data is only available for the last 14 days and daily queries are limited to maxDailyRange.
+func validateGranularity(granularity, start, end string) error {
+	startStamp, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return err
+	}
+
+	endStamp, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return err
+	}
+
+	switch granularity {
+	case costexplorer.GranularityMonthly:
+	case costexplorer.GranularityDaily:
+		if endStamp.Sub(startStamp) > maxDailyRange {
+			return fmt.Errorf("date range for DAILY granularity cannot be longer than %d days", int(maxDailyRange.Hours()/24))
+		}
+	case costexplorer.GranularityHourly:
+		if time.Since(startStamp) > maxHourlyRange {
+			return fmt.Errorf("HOURLY granularity is only available for the last %d days", int(maxHourlyRange.Hours()/24))
+		}
+	default:
+		return fmt.Errorf("invalid granularity '%s', valid values %s", granularity, strings.Join(costexplorer.Granularity_Values(), ", "))
+	}
+
+	return nil
+}
+
 // inSpace returns the cost explorer expression to filter on spaceid
 func inSpace(spaceID string) *costexplorer.Expression {
 	return ce.Tag("spinup:spaceid", []string{spaceID})
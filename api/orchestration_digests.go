@@ -0,0 +1,376 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/YaleSpinup/cost-api/sns"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	awssns "github.com/aws/aws-sdk-go/service/sns"
+	log "github.com/sirupsen/logrus"
+)
+
+// digestTopServices is the number of services included in a cost digest
+const digestTopServices = 5
+
+// validateDigestRequest validates and normalizes a digest subscription request.  SNS topics must be
+// in the account of the space and webhooks must use HTTPS and can't be a local or private address.
+// Webhook hosts are resolved again when the digest is delivered, see newWebhookClient.
+func validateDigestRequest(account string, req *DigestSubscriptionRequest) error {
+	req.Type = strings.ToLower(req.Type)
+	if err := validateOption("type", req.Type, []string{digestTypeSNS, digestTypeWebhook}); err != nil {
+		return err
+	}
+
+	if req.Frequency == "" {
+		req.Frequency = digestFrequencyWeekly
+	}
+	req.Frequency = strings.ToUpper(req.Frequency)
+
+	if err := validateOption("frequency", req.Frequency, []string{digestFrequencyDaily, digestFrequencyWeekly}); err != nil {
+		return err
+	}
+
+	if req.Endpoint == "" {
+		return apierror.New(apierror.ErrBadRequest, "Endpoint is required", nil)
+	}
+
+	switch req.Type {
+	case digestTypeSNS:
+		a, err := arn.Parse(req.Endpoint)
+		if err != nil || a.Service != "sns" {
+			msg := fmt.Sprintf("invalid sns topic arn '%s'", req.Endpoint)
+			return apierror.New(apierror.ErrBadRequest, msg, err)
+		}
+
+		if a.AccountID != account {
+			msg := fmt.Sprintf("sns topic %s must be in account %s", req.Endpoint, account)
+			return apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+	case digestTypeWebhook:
+		u, err := url.Parse(req.Endpoint)
+		if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+			msg := fmt.Sprintf("invalid webhook url '%s', an https url is required", req.Endpoint)
+			return apierror.New(apierror.ErrBadRequest, msg, err)
+		}
+
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		if ip := net.ParseIP(host); (ip != nil && !webhookAddressAllowed(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			msg := fmt.Sprintf("invalid webhook url '%s', local and private addresses are not allowed", req.Endpoint)
+			return apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+	}
+
+	return nil
+}
+
+// getCostDigest gets the month to date cost, the forecast for the rest of the month and the top
+// services for a space.  The forecast is best effort since cost explorer can't forecast spaces
+// without enough cost history.
+func (o *costExplorerOrchestrator) getCostDigest(ctx context.Context, account, spaceID string) (*CostDigest, error) {
+	start, end, err := parseTime("", "")
+	if err != nil {
+		return nil, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	metric := costexplorer.MetricUnblendedCost
+
+	results, _, _, err := o.getCostAndUsageForSpace(ctx, &costAndUsageReq{
+		account: account,
+		spaceID: spaceID,
+		start:   start,
+		end:     end,
+		groupBy: []string{costexplorer.DimensionService},
+		metrics: []string{metric},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := toServiceCosts(results, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &CostDigest{
+		Account: account,
+		Space:   spaceID,
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String(start),
+			End:   aws.String(end),
+		},
+		MonthToDate: &DigestAmount{Unit: "USD"},
+		TopServices: services,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for _, s := range services {
+		digest.MonthToDate.Amount += s.Amount
+		digest.MonthToDate.Unit = s.Unit
+	}
+	digest.MonthToDate.Amount = roundCents(digest.MonthToDate.Amount)
+
+	if len(digest.TopServices) > digestTopServices {
+		digest.TopServices = digest.TopServices[:digestTopServices]
+	}
+
+	forecast, _, _, err := o.getCostForecastForSpace(ctx, &costForecastReq{
+		account: account,
+		spaceID: spaceID,
+		metric:  metric,
+	})
+	if err != nil {
+		log.Warnf("failed to get cost forecast for cost digest for space %s: %s", spaceID, err)
+	} else if forecast.Total != nil {
+		amount, err := strconv.ParseFloat(aws.StringValue(forecast.Total.Amount), 64)
+		if err != nil {
+			log.Warnf("failed to parse cost forecast amount '%s' for space %s", aws.StringValue(forecast.Total.Amount), spaceID)
+		} else {
+			digest.Forecast = &DigestAmount{
+				Amount: roundCents(amount),
+				Unit:   aws.StringValue(forecast.Total.Unit),
+			}
+		}
+	}
+
+	return digest, nil
+}
+
+// sendDigest generates the cost digest for the subscription and delivers it
+func (s *server) sendDigest(ctx context.Context, sub *DigestSubscription) error {
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", sub.Account, s.session.RoleName)
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		return apierror.New(apierror.ErrInternalError, "failed to generate policy", err)
+	}
+
+	orch, err := s.newCostExplorerOrchestrator(ctx, &sessionParams{
+		inlinePolicy: policy,
+		role:         role,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", sub.Account)
+		return apierror.New(apierror.ErrForbidden, msg, nil)
+	}
+
+	digest, err := orch.getCostDigest(ctx, sub.Account, sub.Space)
+	if err != nil {
+		return err
+	}
+
+	switch sub.Type {
+	case digestTypeSNS:
+		return s.publishDigest(ctx, sub, digest)
+	case digestTypeWebhook:
+		return deliverWebhook(ctx, s.digestClient, sub.Endpoint, digest)
+	}
+
+	return apierror.New(apierror.ErrBadRequest, fmt.Sprintf("unknown digest type %s", sub.Type), nil)
+}
+
+// publishDigest publishes the digest as a text message to the subscription's SNS topic
+func (s *server) publishDigest(ctx context.Context, sub *DigestSubscription, digest *CostDigest) error {
+	role := fmt.Sprintf("arn:aws:iam::%s:role/%s", sub.Account, s.session.RoleName)
+	policy, err := snsPublishPolicy()
+	if err != nil {
+		return apierror.New(apierror.ErrInternalError, "failed to generate policy", err)
+	}
+
+	session, err := s.assumeRole(
+		ctx,
+		s.session.ExternalID,
+		role,
+		policy,
+	)
+	if err != nil {
+		msg := fmt.Sprintf("failed to assume role in account: %s", sub.Account)
+		return apierror.New(apierror.ErrForbidden, msg, nil)
+	}
+
+	client := sns.New(sns.WithSession(session.Session))
+	if _, err := client.Publish(ctx, &awssns.PublishInput{
+		TopicArn: aws.String(sub.Endpoint),
+		Subject:  aws.String(fmt.Sprintf("Cost digest for space %s", sub.Space)),
+		Message:  aws.String(digestMessage(digest)),
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// newWebhookClient returns the http client used to deliver digests to webhooks.  The client resolves
+// the webhook host when it connects and refuses to connect to local and private addresses, so a host
+// can't be re-pointed (ie. DNS rebinding) after it's validated.  Redirects and proxies aren't followed.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         webhookDialContext(dialer),
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return fmt.Errorf("webhook redirect to %s refused", req.URL.Redacted())
+		},
+	}
+}
+
+// webhookDialContext resolves the host and dials the resolved addresses, it fails if any of the
+// addresses aren't allowed
+func webhookDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("webhook host %s has no addresses", host)
+		}
+
+		for _, ip := range ips {
+			if !webhookAddressAllowed(ip.IP) {
+				return nil, fmt.Errorf("webhook host %s resolves to disallowed address %s", host, ip.IP)
+			}
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+
+		return nil, lastErr
+	}
+}
+
+// webhookAddressAllowed returns false for loopback, private, link-local, multicast and unspecified addresses
+func webhookAddressAllowed(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// deliverWebhook posts the digest as JSON to the webhook url, any non 2xx response is an error
+func deliverWebhook(ctx context.Context, client *http.Client, endpoint string, digest *CostDigest) error {
+	j, err := json.Marshal(digest)
+	if err != nil {
+		return apierror.New(apierror.ErrInternalError, "failed to marshal cost digest", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(j))
+	if err != nil {
+		return apierror.New(apierror.ErrBadRequest, "failed to create webhook request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return apierror.New(apierror.ErrServiceUnavailable, "failed to deliver cost digest to webhook", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg := fmt.Sprintf("webhook returned unexpected status %s", res.Status)
+		return apierror.New(apierror.ErrServiceUnavailable, msg, nil)
+	}
+
+	return nil
+}
+
+// digestMessage formats the digest as a plain text message
+func digestMessage(d *CostDigest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cost digest for space %s in account %s\n\n", d.Space, d.Account)
+	fmt.Fprintf(&b, "Month to date (%s - %s): %.2f %s\n", aws.StringValue(d.TimePeriod.Start), aws.StringValue(d.TimePeriod.End), d.MonthToDate.Amount, d.MonthToDate.Unit)
+
+	if d.Forecast != nil {
+		fmt.Fprintf(&b, "Forecast for the rest of the month: %.2f %s\n", d.Forecast.Amount, d.Forecast.Unit)
+	}
+
+	if len(d.TopServices) > 0 {
+		b.WriteString("\nTop services:\n")
+		for _, s := range d.TopServices {
+			fmt.Fprintf(&b, "  %s: %.2f %s\n", s.Service, s.Amount, s.Unit)
+		}
+	}
+
+	return b.String()
+}
+
+// toServiceCosts sums the metric for each service group across all of the time periods, sorted by amount
+func toServiceCosts(results []*costexplorer.ResultByTime, metric string) ([]*ServiceCost, error) {
+	metricKey := metricResponseKey(metric)
+
+	costs := []*ServiceCost{}
+	byService := map[string]*ServiceCost{}
+	for _, r := range results {
+		for _, g := range r.Groups {
+			if len(g.Keys) == 0 {
+				continue
+			}
+
+			m, ok := g.Metrics[metricKey]
+			if !ok {
+				continue
+			}
+
+			service := aws.StringValue(g.Keys[0])
+			amount, err := strconv.ParseFloat(aws.StringValue(m.Amount), 64)
+			if err != nil {
+				msg := fmt.Sprintf("failed to parse amount '%s' for service %s", aws.StringValue(m.Amount), service)
+				return nil, apierror.New(apierror.ErrInternalError, msg, err)
+			}
+
+			sc, ok := byService[service]
+			if !ok {
+				sc = &ServiceCost{
+					Service: service,
+					Unit:    aws.StringValue(m.Unit),
+				}
+				byService[service] = sc
+				costs = append(costs, sc)
+			}
+			sc.Amount += amount
+		}
+	}
+
+	for _, c := range costs {
+		c.Amount = roundCents(c.Amount)
+	}
+
+	sort.SliceStable(costs, func(i, j int) bool {
+		if costs[i].Amount == costs[j].Amount {
+			return costs[i].Service < costs[j].Service
+		}
+		return costs[i].Amount > costs[j].Amount
+	})
+
+	return costs, nil
+}
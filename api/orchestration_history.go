@@ -0,0 +1,326 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	ce "github.com/YaleSpinup/cost-api/costexplorer"
+	"github.com/YaleSpinup/cost-api/snapshotstore"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	historyGranularityDaily   = "DAILY"
+	historyGranularityMonthly = "MONTHLY"
+	historyGranularityYearly  = "YEARLY"
+)
+
+var (
+	// SnapshotInterval is how often cost snapshots are collected
+	SnapshotInterval = 24 * time.Hour
+
+	// SnapshotLookback is the minimum number of days collected again on each run, cost explorer
+	// data for recent days isn't final.  The open and recently closed months are always collected.
+	SnapshotLookback = 3
+
+	// SnapshotMaxBackfill is the maximum number of days collected the first time an account is
+	// snapshotted, cost explorer only keeps about 12 months of daily data
+	SnapshotMaxBackfill = 365
+
+	// defaultHistoryYears is how far back the history goes when no start date is passed
+	defaultHistoryYears = 3
+)
+
+// getDailyCostForSpaces gets the daily cost for all of the spaces in the org from start (inclusive)
+// to end (exclusive) as snapshots.  The results aren't cached since they are only used by the
+// snapshot collector.
+func (o *costExplorerOrchestrator) getDailyCostForSpaces(ctx context.Context, account, start, end, metric string) ([]*snapshotstore.Snapshot, error) {
+	input := costexplorer.GetCostAndUsageInput{
+		Filter:      ce.And(inOrg(o.server.org), notTryIT()),
+		Granularity: aws.String(costexplorer.GranularityDaily),
+		GroupBy: []*costexplorer.GroupDefinition{
+			{
+				Key:  aws.String(spaceTagKey),
+				Type: aws.String(costexplorer.GroupDefinitionTypeTag),
+			},
+		},
+		Metrics: aws.StringSlice([]string{metric}),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String(start),
+			End:   aws.String(end),
+		},
+	}
+
+	out, err := o.client.GetCostAndUsage(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []*snapshotstore.Snapshot{}
+	for _, r := range out {
+		if r.TimePeriod == nil {
+			continue
+		}
+
+		costs, err := toSpaceCosts([]*costexplorer.ResultByTime{r}, metric)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range costs {
+			snapshots = append(snapshots, &snapshotstore.Snapshot{
+				Date:   aws.StringValue(r.TimePeriod.Start),
+				Space:  c.Space,
+				Amount: c.Amount,
+				Unit:   c.Unit,
+			})
+		}
+	}
+
+	return snapshots, nil
+}
+
+// snapshotStart returns the first day to collect for an account.  Recent days, the open month and the
+// recently closed month are collected again on every run until their costs are final (ie. credits and
+// refunds are applied after a month closes), accounts without snapshots are backfilled and gaps (ie. if
+// the collector wasn't running) are filled from the latest snapshot.
+func snapshotStart(latest string, today time.Time, lookback, backfill int) time.Time {
+	start := today.AddDate(0, 0, -lookback)
+
+	if refresh := time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, time.UTC); refresh.Before(start) {
+		start = refresh
+	}
+
+	if latest == "" {
+		if backfill > SnapshotMaxBackfill {
+			backfill = SnapshotMaxBackfill
+		}
+
+		if b := today.AddDate(0, 0, -backfill); b.Before(start) {
+			start = b
+		}
+
+		return start
+	}
+
+	if l, err := time.Parse("2006-01-02", latest); err == nil && l.Before(start) {
+		start = l
+	}
+
+	if earliest := today.AddDate(0, 0, -SnapshotMaxBackfill); start.Before(earliest) {
+		start = earliest
+	}
+
+	return start
+}
+
+// collectSnapshots collects the daily space cost snapshots for all of the configured accounts
+// up to (but not including) today
+func (s *server) collectSnapshots(ctx context.Context, now time.Time) {
+	policy, err := costExplorerReadPolicy()
+	if err != nil {
+		log.Errorf("failed to generate policy for cost snapshots: %s", err)
+		return
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	accounts := map[string]struct{}{}
+	for _, a := range s.accountsMap {
+		accounts[a] = struct{}{}
+	}
+
+	for account := range accounts {
+		latest, err := s.snapshotStore.Latest(account)
+		if err != nil {
+			log.Errorf("failed to get latest cost snapshot for account %s: %s", account, err)
+			continue
+		}
+
+		start := snapshotStart(latest, today, s.snapshots.Lookback, s.snapshots.Backfill)
+
+		orch, err := s.newCostExplorerOrchestrator(ctx, &sessionParams{
+			inlinePolicy: policy,
+			role:         fmt.Sprintf("arn:aws:iam::%s:role/%s", account, s.session.RoleName),
+		})
+		if err != nil {
+			log.Errorf("failed to assume role in account %s for cost snapshots: %s", account, err)
+			continue
+		}
+
+		log.Infof("collecting cost snapshots for account %s from %s to %s", account, start.Format("2006-01-02"), today.Format("2006-01-02"))
+
+		snapshots, err := orch.getDailyCostForSpaces(ctx, account, start.Format("2006-01-02"), today.Format("2006-01-02"), s.snapshots.Metric)
+		if err != nil {
+			log.Errorf("failed to get daily cost for cost snapshots in account %s: %s", account, err)
+			continue
+		}
+
+		// the collection replaces the snapshots in the range, including spaces that no longer have cost
+		if err := s.snapshotStore.Put(account, start.Format("2006-01-02"), today.Format("2006-01-02"), snapshots); err != nil {
+			log.Errorf("failed to save cost snapshots for account %s: %s", account, err)
+		}
+	}
+}
+
+// startSnapshotCollector collects cost snapshots in the background, once at startup and then every
+// interval until the context is cancelled
+func (s *server) startSnapshotCollector(ctx context.Context, interval time.Duration) {
+	log.Infof("starting cost snapshot collector with interval %s", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		s.collectSnapshots(ctx, time.Now())
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("stopping cost snapshot collector")
+				return
+			case <-ticker.C:
+				s.collectSnapshots(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+type historyReq struct {
+	account, spaceID, start, end, granularity string
+}
+
+// getSpaceHistory summarizes the cost snapshots for a space by day, month or year.  By default the
+// monthly history for the last 3 years is returned.
+func (s *server) getSpaceHistory(req *historyReq) (*SpaceHistory, error) {
+	if s.snapshotStore == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "cost snapshots are not configured", nil)
+	}
+
+	if req.granularity == "" {
+		req.granularity = historyGranularityMonthly
+	}
+	req.granularity = strings.ToUpper(req.granularity)
+
+	if err := validateOption("granularity", req.granularity, []string{historyGranularityDaily, historyGranularityMonthly, historyGranularityYearly}); err != nil {
+		return nil, err
+	}
+
+	start, end, err := parseHistoryTime(req.start, req.end, time.Now())
+	if err != nil {
+		return nil, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	snapshots, err := s.snapshotStore.Query(req.account, req.spaceID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSpaceHistory(req.spaceID, req.granularity, start, end, snapshots), nil
+}
+
+// toSpaceHistory sums the snapshots into periods of the granularity.  Periods are clamped to the
+// start and end of the history and only periods with snapshots are returned.
+func toSpaceHistory(space, granularity, start, end string, snapshots []*snapshotstore.Snapshot) *SpaceHistory {
+	history := &SpaceHistory{
+		Space:       space,
+		Granularity: granularity,
+		Start:       start,
+		End:         end,
+		Unit:        "USD",
+		Periods:     []*HistoryPeriod{},
+	}
+
+	byPeriod := map[string]*HistoryPeriod{}
+	days := map[string]map[string]struct{}{}
+	for _, snap := range snapshots {
+		d, err := time.Parse("2006-01-02", snap.Date)
+		if err != nil {
+			log.Warnf("skipping cost snapshot with invalid date %s", snap.Date)
+			continue
+		}
+
+		var periodStart, periodEnd time.Time
+		switch granularity {
+		case historyGranularityDaily:
+			periodStart = d
+			periodEnd = d.AddDate(0, 0, 1)
+		case historyGranularityYearly:
+			periodStart = time.Date(d.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+			periodEnd = periodStart.AddDate(1, 0, 0)
+		default:
+			periodStart = time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+			periodEnd = periodStart.AddDate(0, 1, 0)
+		}
+
+		ps, pe := periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02")
+		if ps < start {
+			ps = start
+		}
+		if pe > end {
+			pe = end
+		}
+
+		p, ok := byPeriod[ps]
+		if !ok {
+			p = &HistoryPeriod{Start: ps, End: pe}
+			byPeriod[ps] = p
+			days[ps] = map[string]struct{}{}
+			history.Periods = append(history.Periods, p)
+		}
+
+		p.Amount += snap.Amount
+		days[ps][snap.Date] = struct{}{}
+		history.Total += snap.Amount
+
+		if snap.Unit != "" {
+			history.Unit = snap.Unit
+		}
+	}
+
+	sort.Slice(history.Periods, func(i, j int) bool {
+		return history.Periods[i].Start < history.Periods[j].Start
+	})
+
+	for _, p := range history.Periods {
+		p.Amount = roundCents(p.Amount)
+		p.Days = len(days[p.Start])
+	}
+	history.Total = roundCents(history.Total)
+
+	return history
+}
+
+// parseHistoryTime parses the start and end dates of the history.  The end defaults to today and
+// the start defaults to the beginning of the month 3 years before the end.
+func parseHistoryTime(start, end string, now time.Time) (string, string, error) {
+	endTime := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if end != "" {
+		e, err := time.Parse("2006-01-02", end)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid end date '%s', expected YYYY-MM-DD", end)
+		}
+		endTime = e
+	}
+
+	startTime := time.Date(endTime.Year()-defaultHistoryYears, endTime.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if start != "" {
+		s, err := time.Parse("2006-01-02", start)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid start date '%s', expected YYYY-MM-DD", start)
+		}
+		startTime = s
+	}
+
+	if !endTime.After(startTime) {
+		return "", "", fmt.Errorf("end time should be after start time")
+	}
+
+	return startTime.Format("2006-01-02"), endTime.Format("2006-01-02"), nil
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/YaleSpinup/cost-api/snapshotstore"
+)
+
+func TestSnapshotStart(t *testing.T) {
+	today := time.Date(2021, time.May, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		latest   string
+		lookback int
+		backfill int
+		want     string
+	}{
+		// the open and recently closed months are collected again
+		{"2021-05-14", 3, 0, "2021-04-01"},
+		// lookbacks longer than the recently closed month are collected again
+		{"2021-05-14", 60, 0, "2021-03-16"},
+		// accounts without snapshots are backfilled
+		{"", 3, 0, "2021-04-01"},
+		{"", 3, 90, "2021-02-14"},
+		{"", 3, 1000, "2020-05-15"},
+		// gaps are filled from the latest snapshot
+		{"2021-04-01", 3, 0, "2021-04-01"},
+		{"2021-03-20", 3, 0, "2021-03-20"},
+		{"2019-04-01", 3, 0, "2020-05-15"},
+	}
+
+	for _, tt := range tests {
+		got := snapshotStart(tt.latest, today, tt.lookback, tt.backfill).Format("2006-01-02")
+		if got != tt.want {
+			t.Errorf("snapshotStart(%s, %d, %d) = %s, want %s", tt.latest, tt.lookback, tt.backfill, got, tt.want)
+		}
+	}
+
+	// the recently closed month can be in the previous year
+	january := time.Date(2021, time.January, 5, 0, 0, 0, 0, time.UTC)
+	if got := snapshotStart("2021-01-04", january, 3, 0).Format("2006-01-02"); got != "2020-12-01" {
+		t.Errorf("snapshotStart(2021-01-04, 3, 0) = %s, want 2020-12-01", got)
+	}
+}
+
+func TestParseHistoryTime(t *testing.T) {
+	now := time.Date(2021, time.May, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		start, end         string
+		wantStart, wantEnd string
+		wantErr            bool
+	}{
+		{"", "", "2018-05-01", "2021-05-15", false},
+		{"2019-01-01", "", "2019-01-01", "2021-05-15", false},
+		{"", "2020-01-01", "2017-01-01", "2020-01-01", false},
+		{"2020-01-01", "2020-01-01", "", "", true},
+		{"2020-01", "", "", "", true},
+		{"", "tomorrow", "", "", true},
+	}
+
+	for _, tt := range tests {
+		start, end, err := parseHistoryTime(tt.start, tt.end, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseHistoryTime(%s, %s) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+			continue
+		}
+
+		if start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("parseHistoryTime(%s, %s) = %s, %s, want %s, %s", tt.start, tt.end, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
+
+var testSnapshots = []*snapshotstore.Snapshot{
+	{Date: "2020-12-31", Space: "spc-1", Amount: 1.111, Unit: "USD"},
+	{Date: "2021-01-01", Space: "spc-1", Amount: 2.222, Unit: "USD"},
+	{Date: "2021-01-02", Space: "spc-1", Amount: 3.333, Unit: "USD"},
+	{Date: "2021-02-10", Space: "spc-1", Amount: 4, Unit: "USD"},
+}
+
+func TestToSpaceHistory(t *testing.T) {
+	tests := []struct {
+		granularity string
+		want        []*HistoryPeriod
+	}{
+		{
+			historyGranularityMonthly,
+			[]*HistoryPeriod{
+				{Start: "2020-12-15", End: "2021-01-01", Amount: 1.11, Days: 1},
+				{Start: "2021-01-01", End: "2021-02-01", Amount: 5.56, Days: 2},
+				{Start: "2021-02-01", End: "2021-02-15", Amount: 4, Days: 1},
+			},
+		},
+		{
+			historyGranularityYearly,
+			[]*HistoryPeriod{
+				{Start: "2020-12-15", End: "2021-01-01", Amount: 1.11, Days: 1},
+				{Start: "2021-01-01", End: "2021-02-15", Amount: 9.56, Days: 3},
+			},
+		},
+		{
+			historyGranularityDaily,
+			[]*HistoryPeriod{
+				{Start: "2020-12-31", End: "2021-01-01", Amount: 1.11, Days: 1},
+				{Start: "2021-01-01", End: "2021-01-02", Amount: 2.22, Days: 1},
+				{Start: "2021-01-02", End: "2021-01-03", Amount: 3.33, Days: 1},
+				{Start: "2021-02-10", End: "2021-02-11", Amount: 4, Days: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		out := toSpaceHistory("spc-1", tt.granularity, "2020-12-15", "2021-02-15", testSnapshots)
+
+		if !reflect.DeepEqual(out.Periods, tt.want) {
+			t.Errorf("%s: expected periods %+v, got %+v", tt.granularity, tt.want, out.Periods)
+		}
+
+		if out.Total != 10.67 {
+			t.Errorf("%s: expected total 10.67, got %f", tt.granularity, out.Total)
+		}
+	}
+
+	out := toSpaceHistory("spc-1", historyGranularityMonthly, "2021-01-01", "2021-02-01", nil)
+	if out.Periods == nil || len(out.Periods) != 0 || out.Total != 0 || out.Unit != "USD" {
+		t.Errorf("expected empty history, got %+v", out)
+	}
+}
+
+func TestGetSpaceHistory(t *testing.T) {
+	s := &server{}
+	if _, err := s.getSpaceHistory(&historyReq{account: "012345678901", spaceID: "spc-1"}); err == nil {
+		t.Error("expected error when snapshots aren't configured, got nil")
+	}
+
+	store, err := snapshotstore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if err := store.Put("012345678901", "2020-12-01", "2021-03-01", append(testSnapshots, &snapshotstore.Snapshot{Date: "2021-01-01", Space: "spc-2", Amount: 100, Unit: "USD"})); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+	s.snapshotStore = store
+
+	out, err := s.getSpaceHistory(&historyReq{
+		account:     "012345678901",
+		spaceID:     "spc-1",
+		start:       "2021-01-01",
+		end:         "2021-03-01",
+		granularity: "monthly",
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := &SpaceHistory{
+		Space:       "spc-1",
+		Granularity: historyGranularityMonthly,
+		Start:       "2021-01-01",
+		End:         "2021-03-01",
+		Total:       9.56,
+		Unit:        "USD",
+		Periods: []*HistoryPeriod{
+			{Start: "2021-01-01", End: "2021-02-01", Amount: 5.56, Days: 2},
+			{Start: "2021-02-01", End: "2021-03-01", Amount: 4, Days: 1},
+		},
+	}
+
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	_, err = s.getSpaceHistory(&historyReq{account: "012345678901", spaceID: "spc-1", granularity: "HOURLY"})
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	ce "github.com/YaleSpinup/cost-api/costexplorer"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+// maxFilterDepth is the deepest a cost query filter expression can be nested
+const maxFilterDepth = 10
+
+// compileFilter compiles a cost query filter expression into a cost explorer expression
+func compileFilter(f *FilterExpression) (*costexplorer.Expression, error) {
+	return compileFilterNode(f, 1)
+}
+
+func compileFilterNode(f *FilterExpression, depth int) (*costexplorer.Expression, error) {
+	if f == nil {
+		return nil, fmt.Errorf("filter expression cannot be empty")
+	}
+
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("filter expression cannot be nested more than %d levels", maxFilterDepth)
+	}
+
+	set := 0
+	for _, ok := range []bool{f.And != nil, f.Or != nil, f.Not != nil, f.Tag != nil, f.Dimension != nil, f.CostCategory != nil} {
+		if ok {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return nil, fmt.Errorf("filter expression must have exactly one of And, Or, Not, Tag, Dimension or CostCategory")
+	}
+
+	switch {
+	case f.And != nil:
+		exps, err := compileFilterList("And", f.And, depth)
+		if err != nil {
+			return nil, err
+		}
+		return ce.And(exps...), nil
+	case f.Or != nil:
+		exps, err := compileFilterList("Or", f.Or, depth)
+		if err != nil {
+			return nil, err
+		}
+		return ce.Or(exps...), nil
+	case f.Not != nil:
+		exp, err := compileFilterNode(f.Not, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return ce.Not(exp), nil
+	case f.Tag != nil:
+		if err := validateFilterValues("Tag", f.Tag); err != nil {
+			return nil, err
+		}
+		return ce.Tag(f.Tag.Key, f.Tag.Values), nil
+	case f.Dimension != nil:
+		if err := validateFilterValues("Dimension", f.Dimension); err != nil {
+			return nil, err
+		}
+
+		if !validDimension(f.Dimension.Key) {
+			return nil, fmt.Errorf("invalid Dimension filter key '%s'", f.Dimension.Key)
+		}
+		return ce.Dimension(f.Dimension.Key, f.Dimension.Values), nil
+	default:
+		if err := validateFilterValues("CostCategory", f.CostCategory); err != nil {
+			return nil, err
+		}
+		return ce.CostCategory(f.CostCategory.Key, f.CostCategory.Values), nil
+	}
+}
+
+// compileFilterList compiles the list of expressions for an And or Or node, cost explorer requires
+// at least two expressions in the list
+func compileFilterList(op string, list []*FilterExpression, depth int) ([]*costexplorer.Expression, error) {
+	if len(list) < 2 {
+		return nil, fmt.Errorf("%s filter expression requires at least 2 expressions", op)
+	}
+
+	exps := make([]*costexplorer.Expression, 0, len(list))
+	for _, e := range list {
+		exp, err := compileFilterNode(e, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		exps = append(exps, exp)
+	}
+
+	return exps, nil
+}
+
+func validateFilterValues(op string, v *FilterValues) error {
+	if v.Key == "" {
+		return fmt.Errorf("%s filter expression requires a Key", op)
+	}
+
+	if len(v.Values) == 0 {
+		return fmt.Errorf("%s filter expression requires at least one value", op)
+	}
+
+	return nil
+}
+
+func validDimension(dimension string) bool {
+	for _, d := range costexplorer.Dimension_Values() {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// filterKey returns a stable representation of a compiled filter for use in a cache key
+func filterKey(filter *costexplorer.Expression) string {
+	j, err := json.Marshal(filter)
+	if err != nil {
+		return fmt.Sprintf("%p", filter)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(j))
+}
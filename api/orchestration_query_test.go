@@ -0,0 +1,137 @@
+package api
+
+import (
+	"testing"
+
+	ce "github.com/YaleSpinup/cost-api/costexplorer"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+func TestCompileFilter(t *testing.T) {
+	owner := &FilterExpression{Tag: &FilterValues{Key: "spinup:owner", Values: []string{"jdoe"}}}
+	ec2 := &FilterExpression{Dimension: &FilterValues{Key: "SERVICE", Values: []string{"Amazon Elastic Compute Cloud - Compute"}}}
+	research := &FilterExpression{CostCategory: &FilterValues{Key: "Department", Values: []string{"Research"}}}
+
+	tests := []struct {
+		name    string
+		filter  *FilterExpression
+		want    *costexplorer.Expression
+		wantErr bool
+	}{
+		{
+			name:   "tag",
+			filter: owner,
+			want:   ce.Tag("spinup:owner", []string{"jdoe"}),
+		},
+		{
+			name:   "dimension",
+			filter: ec2,
+			want:   ce.Dimension("SERVICE", []string{"Amazon Elastic Compute Cloud - Compute"}),
+		},
+		{
+			name:   "cost category",
+			filter: research,
+			want:   ce.CostCategory("Department", []string{"Research"}),
+		},
+		{
+			name: "nested",
+			filter: &FilterExpression{
+				And: []*FilterExpression{
+					owner,
+					{Or: []*FilterExpression{{Not: ec2}, research}},
+				},
+			},
+			want: ce.And(
+				ce.Tag("spinup:owner", []string{"jdoe"}),
+				ce.Or(
+					ce.Not(ce.Dimension("SERVICE", []string{"Amazon Elastic Compute Cloud - Compute"})),
+					ce.CostCategory("Department", []string{"Research"}),
+				),
+			),
+		},
+		{
+			name:    "nil",
+			filter:  nil,
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			filter:  &FilterExpression{},
+			wantErr: true,
+		},
+		{
+			name:    "more than one node",
+			filter:  &FilterExpression{Tag: owner.Tag, Dimension: ec2.Dimension},
+			wantErr: true,
+		},
+		{
+			name:    "single and",
+			filter:  &FilterExpression{And: []*FilterExpression{owner}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid dimension",
+			filter:  &FilterExpression{Dimension: &FilterValues{Key: "FOO", Values: []string{"bar"}}},
+			wantErr: true,
+		},
+		{
+			name:    "missing key",
+			filter:  &FilterExpression{Tag: &FilterValues{Values: []string{"jdoe"}}},
+			wantErr: true,
+		},
+		{
+			name:    "missing values",
+			filter:  &FilterExpression{CostCategory: &FilterValues{Key: "Department"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid nested",
+			filter:  &FilterExpression{Or: []*FilterExpression{owner, {Not: &FilterExpression{}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compileFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compileFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !awsutil.DeepEqual(got, tt.want) {
+				t.Errorf("compileFilter() = %s, want %s", awsutil.Prettify(got), awsutil.Prettify(tt.want))
+			}
+		})
+	}
+}
+
+func TestCompileFilterDepth(t *testing.T) {
+	filter := &FilterExpression{Tag: &FilterValues{Key: "spinup:owner", Values: []string{"jdoe"}}}
+	for i := 1; i < maxFilterDepth; i++ {
+		filter = &FilterExpression{Not: filter}
+	}
+
+	if _, err := compileFilter(filter); err != nil {
+		t.Errorf("expected nil error for filter with depth %d, got %s", maxFilterDepth, err)
+	}
+
+	filter = &FilterExpression{Not: filter}
+	if _, err := compileFilter(filter); err == nil {
+		t.Errorf("expected error for filter with depth %d, got nil", maxFilterDepth+1)
+	}
+}
+
+func TestFilterKey(t *testing.T) {
+	a := ce.And(ce.Tag("spinup:owner", []string{"jdoe"}), ce.Dimension("SERVICE", []string{"AWS Lambda"}))
+	b := ce.And(ce.Tag("spinup:owner", []string{"jdoe"}), ce.Dimension("SERVICE", []string{"AWS Lambda"}))
+	c := ce.And(ce.Tag("spinup:owner", []string{"jdoe"}), ce.Dimension("SERVICE", []string{"Amazon Simple Storage Service"}))
+
+	if filterKey(a) != filterKey(b) {
+		t.Errorf("expected equal filters to have the same key")
+	}
+
+	if filterKey(a) == filterKey(c) {
+		t.Errorf("expected different filters to have different keys")
+	}
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultReservationService is the service reservation purchase recommendations are requested
+// for when none is passed
+const defaultReservationService = "Amazon Elastic Compute Cloud - Compute"
+
+// purchasePaymentOptions are the payment options supported for purchase recommendations, the legacy
+// *_UTILIZATION options aren't supported for savings plans or current reservations
+var purchasePaymentOptions = []string{
+	costexplorer.PaymentOptionNoUpfront,
+	costexplorer.PaymentOptionPartialUpfront,
+	costexplorer.PaymentOptionAllUpfront,
+}
+
+type purchaseRecommendationsReq struct {
+	account, kind, savingsPlansType, service, term, payment, lookback string
+}
+
+// getPurchaseRecommendations gets the savings plans or reserved instance purchase recommendations
+// for an account.  Since AWS only refreshes recommendations once a day, results are cached for
+// RecommendationCacheExpireTime.
+func (o *costExplorerOrchestrator) getPurchaseRecommendations(ctx context.Context, req *purchaseRecommendationsReq) (interface{}, bool, time.Duration, error) {
+	if req.kind == "" {
+		req.kind = "savingsplans"
+	}
+
+	if req.term == "" {
+		req.term = costexplorer.TermInYearsOneYear
+	}
+
+	if req.payment == "" {
+		req.payment = costexplorer.PaymentOptionNoUpfront
+	}
+
+	if req.lookback == "" {
+		req.lookback = costexplorer.LookbackPeriodInDaysThirtyDays
+	}
+
+	if err := validateOption("term", req.term, costexplorer.TermInYears_Values()); err != nil {
+		return nil, false, 0, err
+	}
+
+	if err := validateOption("lookback", req.lookback, costexplorer.LookbackPeriodInDays_Values()); err != nil {
+		return nil, false, 0, err
+	}
+
+	if err := validateOption("payment", req.payment, purchasePaymentOptions); err != nil {
+		return nil, false, 0, err
+	}
+
+	var fetch func() (interface{}, error)
+	switch req.kind {
+	case "savingsplans":
+		if req.savingsPlansType == "" {
+			req.savingsPlansType = costexplorer.SupportedSavingsPlansTypeComputeSp
+		}
+
+		if err := validateOption("savingsPlansType", req.savingsPlansType, costexplorer.SupportedSavingsPlansType_Values()); err != nil {
+			return nil, false, 0, err
+		}
+
+		// the reservation service is not used for savings plans, don't let it split the cache
+		req.service = ""
+
+		fetch = func() (interface{}, error) {
+			return o.client.GetSavingsPlansPurchaseRecommendation(ctx, &costexplorer.GetSavingsPlansPurchaseRecommendationInput{
+				LookbackPeriodInDays: aws.String(req.lookback),
+				PaymentOption:        aws.String(req.payment),
+				SavingsPlansType:     aws.String(req.savingsPlansType),
+				TermInYears:          aws.String(req.term),
+			})
+		}
+	case "reservations":
+		if req.service == "" {
+			req.service = defaultReservationService
+		}
+
+		req.savingsPlansType = ""
+
+		fetch = func() (interface{}, error) {
+			return o.client.GetReservationPurchaseRecommendation(ctx, &costexplorer.GetReservationPurchaseRecommendationInput{
+				LookbackPeriodInDays: aws.String(req.lookback),
+				PaymentOption:        aws.String(req.payment),
+				Service:              aws.String(req.service),
+				TermInYears:          aws.String(req.term),
+			})
+		}
+	default:
+		msg := fmt.Sprintf("invalid recommendation type '%s', valid values savingsplans, reservations", req.kind)
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	cacheKey := fmt.Sprintf("purchases_%s_%s_%s_%s_%s_%s_%s", req.account, req.kind, req.savingsPlansType, req.service, req.term, req.payment, req.lookback)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and purchases-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := fetch()
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.Set(cacheKey, out, RecommendationCacheExpireTime)
+
+		return out, false, 0, nil
+	}
+
+	log.Debugf("found cached object: %s", c)
+
+	return c, true, time.Until(expire), nil
+}
+
+// validateOption returns a bad request error if the value isn't one of the valid values
+func validateOption(name, value string, valid []string) error {
+	for _, v := range valid {
+		if v == value {
+			return nil
+		}
+	}
+
+	msg := fmt.Sprintf("invalid %s '%s', valid values %s", name, value, strings.Join(valid, ", "))
+	return apierror.New(apierror.ErrBadRequest, msg, nil)
+}
+
+type rightsizingReq struct {
+	account, spaceID, target string
+	benefitsConsidered       bool
+}
+
+// getRightsizingForSpace gets the cost explorer EC2 rightsizing recommendations for the account and
+// filters them to the instances in the space inventory.  The recommendations for the account are cached.
+func (o *costExplorerOrchestrator) getRightsizingForSpace(ctx context.Context, req *rightsizingReq, inventory []*InventoryResponse) ([]*InstanceRightsizing, bool, time.Duration, error) {
+	if req.target == "" {
+		req.target = costexplorer.RecommendationTargetSameInstanceFamily
+	}
+
+	if err := validateOption("target", req.target, costexplorer.RecommendationTarget_Values()); err != nil {
+		return nil, false, 0, err
+	}
+
+	cacheKey := fmt.Sprintf("rightsizing_%s_%s_%t", req.account, req.target, req.benefitsConsidered)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	var recommendations []*costexplorer.RightsizingRecommendation
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and rightsizing-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetRightsizingRecommendation(ctx, &costexplorer.GetRightsizingRecommendationInput{
+			Configuration: &costexplorer.RightsizingRecommendationConfiguration{
+				BenefitsConsidered:   aws.Bool(req.benefitsConsidered),
+				RecommendationTarget: aws.String(req.target),
+			},
+			Service: aws.String("AmazonEC2"),
+		})
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.Set(cacheKey, out, RecommendationCacheExpireTime)
+
+		return toInstanceRightsizing(out, spaceInstances(inventory)), false, 0, nil
+	}
+
+	log.Debugf("found cached object: %s", c)
+
+	recommendations, ok = c.([]*costexplorer.RightsizingRecommendation)
+	if !ok {
+		return nil, false, 0, apierror.New(apierror.ErrInternalError, "unexpected cached object type", nil)
+	}
+
+	return toInstanceRightsizing(recommendations, spaceInstances(inventory)), true, time.Until(expire), nil
+}
+
+// spaceInstances returns a map of the EC2 instance ids in the inventory to their name
+func spaceInstances(inventory []*InventoryResponse) map[string]string {
+	instances := map[string]string{}
+	for _, i := range inventory {
+		if i.Service != "ec2" || !strings.HasPrefix(i.Resource, "instance/") {
+			continue
+		}
+
+		instances[strings.TrimPrefix(i.Resource, "instance/")] = i.Name
+	}
+
+	return instances
+}
+
+// toInstanceRightsizing converts the rightsizing recommendations for the given instances to a list of
+// InstanceRightsizing, ordered by the largest estimated monthly savings
+func toInstanceRightsizing(recommendations []*costexplorer.RightsizingRecommendation, instances map[string]string) []*InstanceRightsizing {
+	out := []*InstanceRightsizing{}
+	for _, r := range recommendations {
+		if r.CurrentInstance == nil {
+			continue
+		}
+
+		id := aws.StringValue(r.CurrentInstance.ResourceId)
+		name, ok := instances[id]
+		if !ok {
+			continue
+		}
+
+		if name == "" {
+			name = aws.StringValue(r.CurrentInstance.InstanceName)
+		}
+
+		rec := &InstanceRightsizing{
+			InstanceID:         id,
+			Name:               name,
+			Action:             aws.StringValue(r.RightsizingType),
+			MonthlyCost:        parseAmount(aws.StringValue(r.CurrentInstance.MonthlyCost)),
+			Unit:               aws.StringValue(r.CurrentInstance.CurrencyCode),
+			FindingReasonCodes: aws.StringValueSlice(r.FindingReasonCodes),
+		}
+
+		if d := r.CurrentInstance.ResourceDetails; d != nil && d.EC2ResourceDetails != nil {
+			rec.InstanceType = aws.StringValue(d.EC2ResourceDetails.InstanceType)
+		}
+
+		switch {
+		case r.TerminateRecommendationDetail != nil:
+			rec.EstimatedMonthlySavings = parseAmount(aws.StringValue(r.TerminateRecommendationDetail.EstimatedMonthlySavings))
+		case r.ModifyRecommendationDetail != nil:
+			if t := defaultTargetInstance(r.ModifyRecommendationDetail.TargetInstances); t != nil {
+				rec.EstimatedMonthlySavings = parseAmount(aws.StringValue(t.EstimatedMonthlySavings))
+				if t.ResourceDetails != nil && t.ResourceDetails.EC2ResourceDetails != nil {
+					rec.TargetInstanceType = aws.StringValue(t.ResourceDetails.EC2ResourceDetails.InstanceType)
+				}
+			}
+		}
+
+		out = append(out, rec)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].EstimatedMonthlySavings > out[j].EstimatedMonthlySavings
+	})
+
+	return out
+}
+
+// defaultTargetInstance returns the default target instance from the list, or the first one if
+// none is marked as the default
+func defaultTargetInstance(targets []*costexplorer.TargetInstance) *costexplorer.TargetInstance {
+	for _, t := range targets {
+		if aws.BoolValue(t.DefaultTargetInstance) {
+			return t
+		}
+	}
+
+	if len(targets) > 0 {
+		return targets[0]
+	}
+
+	return nil
+}
+
+// parseAmount parses a cost explorer amount, logging and returning 0 if it's not a number
+func parseAmount(amount string) float64 {
+	if amount == "" {
+		return 0
+	}
+
+	f, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		log.Warnf("failed to parse amount '%s': %s", amount, err)
+		return 0
+	}
+
+	return f
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/pkg/errors"
+)
+
+func TestValidateOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		valid   []string
+		wantErr bool
+	}{
+		{"term", costexplorer.TermInYearsOneYear, costexplorer.TermInYears_Values(), false},
+		{"term", costexplorer.TermInYearsThreeYears, costexplorer.TermInYears_Values(), false},
+		{"term", "TWO_YEARS", costexplorer.TermInYears_Values(), true},
+		{"payment", costexplorer.PaymentOptionAllUpfront, costexplorer.PaymentOption_Values(), false},
+		{"payment", "SOME_UPFRONT", costexplorer.PaymentOption_Values(), true},
+		{"lookback", costexplorer.LookbackPeriodInDaysSevenDays, costexplorer.LookbackPeriodInDays_Values(), false},
+		{"lookback", "seven_days", costexplorer.LookbackPeriodInDays_Values(), true},
+		{"savingsPlansType", "", costexplorer.SupportedSavingsPlansType_Values(), true},
+	}
+
+	for _, tt := range tests {
+		err := validateOption(tt.name, tt.value, tt.valid)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateOption(%s, %s) error = %v, wantErr %v", tt.name, tt.value, err, tt.wantErr)
+			continue
+		}
+
+		if err != nil {
+			aerr, ok := errors.Cause(err).(apierror.Error)
+			if !ok || aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("expected bad request apierror for %s %s, got %v", tt.name, tt.value, err)
+			}
+		}
+	}
+}
+
+func TestPurchaseRecommendationsPaymentOption(t *testing.T) {
+	o := &costExplorerOrchestrator{server: &server{}}
+
+	for _, payment := range purchasePaymentOptions {
+		if err := validateOption("payment", payment, purchasePaymentOptions); err != nil {
+			t.Errorf("expected payment %s to be valid, got %s", payment, err)
+		}
+	}
+
+	for _, kind := range []string{"savingsplans", "reservations"} {
+		for _, payment := range []string{costexplorer.PaymentOptionHeavyUtilization, costexplorer.PaymentOptionLightUtilization, costexplorer.PaymentOptionMediumUtilization, "SOME_UPFRONT"} {
+			_, _, _, err := o.getPurchaseRecommendations(context.TODO(), &purchaseRecommendationsReq{kind: kind, payment: payment})
+			if aerr, ok := errors.Cause(err).(apierror.Error); !ok || aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("expected bad request apierror for %s payment %s, got %v", kind, payment, err)
+			}
+		}
+	}
+}
+
+func TestSpaceInstances(t *testing.T) {
+	inventory := []*InventoryResponse{
+		{Name: "web", Service: "ec2", Resource: "instance/i-0000001"},
+		{Name: "", Service: "ec2", Resource: "instance/i-0000002"},
+		{Name: "vol", Service: "ec2", Resource: "volume/vol-0000001"},
+		{Name: "db", Service: "rds", Resource: "db:mydb"},
+	}
+
+	expected := map[string]string{
+		"i-0000001": "web",
+		"i-0000002": "",
+	}
+
+	if out := spaceInstances(inventory); !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+}
+
+func TestToInstanceRightsizing(t *testing.T) {
+	ec2Details := func(instanceType string) *costexplorer.ResourceDetails {
+		return &costexplorer.ResourceDetails{
+			EC2ResourceDetails: &costexplorer.EC2ResourceDetails{InstanceType: aws.String(instanceType)},
+		}
+	}
+
+	recommendations := []*costexplorer.RightsizingRecommendation{
+		{
+			CurrentInstance: &costexplorer.CurrentInstance{
+				ResourceId:      aws.String("i-0000001"),
+				InstanceName:    aws.String("aws-name"),
+				MonthlyCost:     aws.String("70.08"),
+				CurrencyCode:    aws.String("USD"),
+				ResourceDetails: ec2Details("m5.large"),
+			},
+			FindingReasonCodes: aws.StringSlice([]string{"CPU_OVER_PROVISIONED"}),
+			RightsizingType:    aws.String(costexplorer.RightsizingTypeModify),
+			ModifyRecommendationDetail: &costexplorer.ModifyRecommendationDetail{
+				TargetInstances: []*costexplorer.TargetInstance{
+					{EstimatedMonthlySavings: aws.String("10"), ResourceDetails: ec2Details("t3.large")},
+					{EstimatedMonthlySavings: aws.String("35.04"), DefaultTargetInstance: aws.Bool(true), ResourceDetails: ec2Details("m5.medium")},
+				},
+			},
+		},
+		{
+			CurrentInstance: &costexplorer.CurrentInstance{
+				ResourceId:      aws.String("i-0000002"),
+				InstanceName:    aws.String("aws-name-2"),
+				MonthlyCost:     aws.String("140.16"),
+				CurrencyCode:    aws.String("USD"),
+				ResourceDetails: ec2Details("m5.xlarge"),
+			},
+			RightsizingType: aws.String(costexplorer.RightsizingTypeTerminate),
+			TerminateRecommendationDetail: &costexplorer.TerminateRecommendationDetail{
+				EstimatedMonthlySavings: aws.String("140.16"),
+			},
+		},
+		{
+			CurrentInstance: &costexplorer.CurrentInstance{ResourceId: aws.String("i-notinspace")},
+			RightsizingType: aws.String(costexplorer.RightsizingTypeTerminate),
+		},
+		{},
+	}
+
+	instances := map[string]string{
+		"i-0000001": "web",
+		"i-0000002": "",
+	}
+
+	expected := []*InstanceRightsizing{
+		{
+			InstanceID:              "i-0000002",
+			Name:                    "aws-name-2",
+			InstanceType:            "m5.xlarge",
+			Action:                  costexplorer.RightsizingTypeTerminate,
+			FindingReasonCodes:      []string{},
+			MonthlyCost:             140.16,
+			EstimatedMonthlySavings: 140.16,
+			Unit:                    "USD",
+		},
+		{
+			InstanceID:              "i-0000001",
+			Name:                    "web",
+			InstanceType:            "m5.large",
+			Action:                  costexplorer.RightsizingTypeModify,
+			TargetInstanceType:      "m5.medium",
+			FindingReasonCodes:      []string{"CPU_OVER_PROVISIONED"},
+			MonthlyCost:             70.08,
+			EstimatedMonthlySavings: 35.04,
+			Unit:                    "USD",
+		},
+	}
+
+	out := toInstanceRightsizing(recommendations, instances)
+	if !reflect.DeepEqual(out, expected) {
+		for _, o := range out {
+			t.Logf("got %+v", o)
+		}
+		t.Errorf("unexpected rightsizing output")
+	}
+}
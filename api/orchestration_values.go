@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	ce "github.com/YaleSpinup/cost-api/costexplorer"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type valuesReq struct {
+	account, spaceID, key, start, end string
+}
+
+// getDimensionValues gets the values of a cost explorer dimension in the account, scoped to
+// the space when one is passed.  By default, it gets the values from the start of the month until now.
+func (o *costExplorerOrchestrator) getDimensionValues(ctx context.Context, req *valuesReq) ([]*costexplorer.DimensionValuesWithAttributes, bool, time.Duration, error) {
+	if err := validateOption("dimension", req.key, costexplorer.Dimension_Values()); err != nil {
+		return nil, false, 0, err
+	}
+
+	start, end, err := parseTime(req.start, req.end)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	cacheKey := fmt.Sprintf("dimensionvalues_%s_%s_%s_%s_%s", req.account, req.spaceID, req.key, start, end)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and dimensionvalues-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetDimensionValues(ctx, &costexplorer.GetDimensionValuesInput{
+			Dimension: aws.String(req.key),
+			Filter:    o.spaceFilter(req.spaceID),
+			TimePeriod: &costexplorer.DateInterval{
+				Start: aws.String(start),
+				End:   aws.String(end),
+			},
+		})
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return out, false, 0, nil
+	}
+
+	out, ok := c.([]*costexplorer.DimensionValuesWithAttributes)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a []*costexplorer.DimensionValuesWithAttributes!")
+	}
+
+	log.Debugf("found cached object: %s", out)
+
+	return out, true, time.Until(expire), nil
+}
+
+// getTagValues gets the values of a tag key in the account, scoped to the space when one is
+// passed.  By default, it gets the values from the start of the month until now.
+func (o *costExplorerOrchestrator) getTagValues(ctx context.Context, req *valuesReq) ([]string, bool, time.Duration, error) {
+	if req.key == "" {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, "tag key is required", nil)
+	}
+
+	start, end, err := parseTime(req.start, req.end)
+	if err != nil {
+		return nil, false, 0, apierror.New(apierror.ErrBadRequest, err.Error(), err)
+	}
+
+	cacheKey := fmt.Sprintf("tagvalues_%s_%s_%s_%s_%s", req.account, req.spaceID, req.key, start, end)
+
+	log.Debugf("cacheKey: %s", cacheKey)
+
+	c, expire, ok := o.server.resultCache.GetWithExpiration(cacheKey)
+	if !ok || c == nil {
+		log.Debugf("cache empty for org, and tagvalues-cacheKey: %s, %s, calling cost-explorer", o.server.org, cacheKey)
+
+		out, err := o.client.GetTags(ctx, &costexplorer.GetTagsInput{
+			Filter: o.spaceFilter(req.spaceID),
+			TagKey: aws.String(req.key),
+			TimePeriod: &costexplorer.DateInterval{
+				Start: aws.String(start),
+				End:   aws.String(end),
+			},
+		})
+		if err != nil {
+			return nil, false, 0, err
+		}
+
+		o.server.resultCache.SetDefault(cacheKey, out)
+
+		return out, false, 0, nil
+	}
+
+	out, ok := c.([]string)
+	if !ok {
+		return nil, false, 0, errors.New("value in cache is not a []string!")
+	}
+
+	log.Debugf("found cached object: %s", out)
+
+	return out, true, time.Until(expire), nil
+}
+
+// spaceFilter returns the cost explorer expression for the resources in a space, or nil if
+// no space is passed
+func (o *costExplorerOrchestrator) spaceFilter(spaceID string) *costexplorer.Expression {
+	if spaceID == "" {
+		return nil
+	}
+
+	return ce.And(inSpace(spaceID), inOrg(o.server.org), notTryIT())
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"testing"
+
+	ce "github.com/YaleSpinup/cost-api/costexplorer"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+)
+
+func TestSpaceFilter(t *testing.T) {
+	o := &costExplorerOrchestrator{server: &server{org: "testorg"}}
+
+	if out := o.spaceFilter(""); out != nil {
+		t.Errorf("expected nil filter for empty space, got %s", awsutil.Prettify(out))
+	}
+
+	expected := ce.And(inSpace("spc-123"), inOrg("testorg"), notTryIT())
+	if out := o.spaceFilter("spc-123"); !awsutil.DeepEqual(out, expected) {
+		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+}
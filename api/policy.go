@@ -36,7 +36,9 @@ func orgTagAccessPolicy(org string) (string, error) {
 	return string(j), nil
 }
 
-func budgetReadWritePolicy() (string, error) {
+// budgetReadWritePolicy generates the budget policy to be passed inline when assuming a role.  If an action role
+// arn is passed, the role can be passed to AWS Budgets to run budget actions.
+func budgetReadWritePolicy(actionRoleArn string) (string, error) {
 	log.Debugf("generating budget read/write policy document")
 
 	policy := iam.PolicyDocument{
@@ -47,6 +49,10 @@ func budgetReadWritePolicy() (string, error) {
 				Action: []string{
 					"budgets:ViewBudget",
 					"budgets:ModifyBudget",
+					"budgets:CreateBudgetAction",
+					"budgets:DescribeBudgetActionsForBudget",
+					"budgets:ExecuteBudgetAction",
+					"budgets:UpdateBudgetAction",
 					"SNS:CreateTopic",
 					"SNS:DeleteTopic",
 					"SNS:Subscribe",
@@ -56,6 +62,19 @@ func budgetReadWritePolicy() (string, error) {
 		},
 	}
 
+	if actionRoleArn != "" {
+		policy.Statement = append(policy.Statement, iam.StatementEntry{
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: []string{actionRoleArn},
+			Condition: iam.Condition{
+				"StringEquals": iam.ConditionStatement{
+					"iam:PassedToService": []string{"budgets.amazonaws.com"},
+				},
+			},
+		})
+	}
+
 	j, err := json.Marshal(policy)
 	if err != nil {
 		return "", err
@@ -158,3 +177,28 @@ func defaultBudgetTopicPolicy(arn string) (string, error) {
 
 	return string(j), nil
 }
+
+// snsPublishPolicy generates the policy to publish cost digests to an SNS topic
+func snsPublishPolicy() (string, error) {
+	log.Debugf("generating sns publish policy document")
+
+	policy := iam.PolicyDocument{
+		Version: "2012-10-17",
+		Statement: []iam.StatementEntry{
+			{
+				Effect: "Allow",
+				Action: []string{
+					"SNS:Publish",
+				},
+				Resource: []string{"*"},
+			},
+		},
+	}
+
+	j, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+
+	return string(j), nil
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/aws-go/services/iam"
+)
+
+func TestBudgetReadWritePolicy(t *testing.T) {
+	out, err := budgetReadWritePolicy("")
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	policy := iam.PolicyDocument{}
+	if err := json.Unmarshal([]byte(out), &policy); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if len(policy.Statement) != 1 {
+		t.Fatalf("expected 1 statement without an action role, got %+v", policy.Statement)
+	}
+
+	for _, a := range policy.Statement[0].Action {
+		if a == "iam:PassRole" {
+			t.Error("expected iam:PassRole not to be granted without an action role")
+		}
+	}
+
+	out, err = budgetReadWritePolicy("arn:aws:iam::012345678901:role/budget-actions")
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	policy = iam.PolicyDocument{}
+	if err := json.Unmarshal([]byte(out), &policy); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if len(policy.Statement) != 2 {
+		t.Fatalf("expected 2 statements with an action role, got %+v", policy.Statement)
+	}
+
+	expected := iam.StatementEntry{
+		Effect:   "Allow",
+		Action:   []string{"iam:PassRole"},
+		Resource: []string{"arn:aws:iam::012345678901:role/budget-actions"},
+		Condition: iam.Condition{
+			"StringEquals": iam.ConditionStatement{
+				"iam:PassedToService": []string{"budgets.amazonaws.com"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(policy.Statement[1], expected) {
+		t.Errorf("expected pass role statement %+v, got %+v", expected, policy.Statement[1])
+	}
+}
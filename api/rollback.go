@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RollbackTimeout is how long a rollback is allowed to run
+var RollbackTimeout = 120 * time.Second
+
+// rollbackFunc compensates for a resource created before a failure
+type rollbackFunc func(ctx context.Context) error
+
+// rollBack executes the stack of rollback functions in reverse order.  Errors are logged and
+// the rollback continues with the remaining functions.  The rollback runs with its own context
+// so it isn't cancelled with the request that failed.
+func rollBack(t *[]rollbackFunc) {
+	if t == nil || len(*t) == 0 {
+		log.Info("nothing to roll back")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RollbackTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		tasks := *t
+		log.Errorf("executing rollback of %d tasks", len(tasks))
+		for i := len(tasks) - 1; i >= 0; i-- {
+			if err := tasks[i](ctx); err != nil {
+				log.Errorf("rollback task error: %s, continuing rollback", err)
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		log.Info("rollback completed")
+	case <-ctx.Done():
+		log.Errorf("timeout waiting for rollback to complete")
+	}
+}
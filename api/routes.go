@@ -15,16 +15,56 @@ func (s *server) routes() {
 	api.HandleFunc("/version", s.VersionHandler).Methods(http.MethodGet)
 	api.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
 
+	// cost endpoints for a space across all of the configured accounts
+	api.HandleFunc("/spaces/{space}", s.SpaceAccountsGetHandler).Methods(http.MethodGet).MatcherFunc(matchSpaceQueries)
+
+	// cost endpoints for all spaces in the org
+	api.HandleFunc("/{account}/spaces", s.SpacesGetHandler).Methods(http.MethodGet)
+
 	// cost endpoints for a space
 	api.HandleFunc("/{account}/spaces/{space}", s.SpaceGetHandler).Methods(http.MethodGet).MatcherFunc(matchSpaceQueries)
+	api.HandleFunc("/{account}/spaces/{space}/query", s.SpaceQueryHandler).Methods(http.MethodPost)
+	api.HandleFunc("/{account}/spaces/{space}/forecast", s.SpaceForecastGetHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/forecast/usage", s.SpaceUsageForecastGetHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/resources", s.SpaceResourcesGetHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/compare", s.SpaceCompareGetHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/anomalies", s.SpaceAnomaliesGetHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/history", s.SpaceHistoryGetHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/rightsizing", s.SpaceRightsizingGetHandler).Methods(http.MethodGet)
 
 	api.HandleFunc("/{account}/spaces/{space}/budgets", s.SpaceBudgetsCreatehandler).Methods(http.MethodPost)
 	api.HandleFunc("/{account}/spaces/{space}/budgets", s.SpaceBudgetsListHandler).Methods(http.MethodGet)
 	api.HandleFunc("/{account}/spaces/{space}/budgets/{budget}", s.SpaceBudgetsShowHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/budgets/{budget}/history", s.SpaceBudgetsHistoryHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/budgets/{budget}/actions", s.SpaceBudgetActionsListHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/budgets/{budget}/actions/{action}/{execution}", s.SpaceBudgetActionsExecuteHandler).Methods(http.MethodPost)
+	api.HandleFunc("/{account}/spaces/{space}/budgets/{budget}", s.SpaceBudgetsUpdateHandler).Methods(http.MethodPut)
 	api.HandleFunc("/{account}/spaces/{space}/budgets/{budget}", s.SpaceBudgetsDeleteHandler).Methods(http.MethodDelete)
 
+	api.HandleFunc("/{account}/spaces/{space}/digests", s.SpaceDigestsCreateHandler).Methods(http.MethodPost)
+	api.HandleFunc("/{account}/spaces/{space}/digests", s.SpaceDigestsListHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/digests/{digest}", s.SpaceDigestsShowHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/spaces/{space}/digests/{digest}", s.SpaceDigestsUpdateHandler).Methods(http.MethodPut)
+	api.HandleFunc("/{account}/spaces/{space}/digests/{digest}", s.SpaceDigestsDeleteHandler).Methods(http.MethodDelete)
+
 	api.HandleFunc("/{account}/spaces/{space}/instances/{id}/optimizer", s.SpaceInstanceOptimizer).Methods(http.MethodGet)
 
+	// dimension and tag values for an account
+	api.HandleFunc("/{account}/dimensions/{dimension}/values", s.DimensionValuesGetHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/tags/{key}/values", s.TagValuesGetHandler).Methods(http.MethodGet)
+
+	// chargeback reports for an account
+	api.HandleFunc("/{account}/chargeback/{month}", s.ChargebackCreateHandler).Methods(http.MethodPost)
+	api.HandleFunc("/{account}/chargeback/{month}", s.ChargebackGetHandler).Methods(http.MethodGet)
+
+	// cost categories for an account
+	api.HandleFunc("/{account}/costcategories", s.CostCategoriesListHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/costcategories/{name}/values", s.CostCategoryValuesGetHandler).Methods(http.MethodGet)
+
+	// savings plans and reserved instance reports and purchase recommendations for an account
+	api.HandleFunc("/{account}/commitments/{commitment:savingsplans|reservations}/{report:utilization|coverage}", s.CommitmentsGetHandler).Methods(http.MethodGet)
+	api.HandleFunc("/{account}/recommendations/purchases", s.PurchaseRecommendationsGetHandler).Methods(http.MethodGet)
+
 	// metrics subrouter - /v1/metrics
 	metricsApi := s.router.PathPrefix("/v1/metrics").Subrouter()
 	metricsApi.HandleFunc("/ping", s.PingHandler).Methods(http.MethodGet)
@@ -79,9 +119,23 @@ func matchSpaceQueries(req *http.Request, r *mux.RouteMatch) bool {
 		return false
 	}
 
-	if g, ok := queries["groupby"]; ok {
-		r.Vars["groupby"] = g[0]
+	if g, ok := queries["granularity"]; ok {
+		r.Vars["granularity"] = g[0]
 	}
 
+	// groupby, metric and costCategory can be passed multiple times, they are read from the
+	// query by the handler with queryValues so values containing commas are preserved
+
 	return true
 }
+
+// queryValues returns the non-empty values of a query parameter that can be passed multiple times
+func queryValues(r *http.Request, key string) []string {
+	var values []string
+	for _, v := range r.URL.Query()[key] {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
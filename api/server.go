@@ -10,7 +10,10 @@ import (
 	"github.com/YaleSpinup/aws-go/services/session"
 	"github.com/YaleSpinup/cost-api/common"
 	"github.com/YaleSpinup/cost-api/imagecache"
+	"github.com/YaleSpinup/cost-api/reportstore"
 	"github.com/YaleSpinup/cost-api/s3cache"
+	"github.com/YaleSpinup/cost-api/snapshotstore"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	cache "github.com/patrickmn/go-cache"
@@ -21,20 +24,31 @@ import (
 var (
 	CacheExpireTime = 4 * time.Hour
 	CachePurgeTime  = 15 * time.Minute
+
+	// RecommendationCacheExpireTime is how long cost explorer recommendations are cached, AWS only
+	// refreshes them once a day
+	RecommendationCacheExpireTime = 24 * time.Hour
 )
 
 type server struct {
-	accountsMap    map[string]string
-	router         *mux.Router
-	version        common.Version
-	context        context.Context
-	session        session.Session
-	orgPolicy      string
-	optimizerCache *cache.Cache
-	resultCache    *cache.Cache
-	imageCache     imagecache.ImageCache
-	sessionCache   *cache.Cache
-	org            string
+	accountsMap      map[string]string
+	budgetActionRole string
+	chargeback       *common.Chargeback
+	router           *mux.Router
+	version          common.Version
+	context          context.Context
+	digestClient     *http.Client
+	digestStore      *digestStore
+	session          session.Session
+	orgPolicy        string
+	optimizerCache   *cache.Cache
+	resultCache      *cache.Cache
+	imageCache       imagecache.ImageCache
+	reportStore      reportstore.ReportStore
+	sessionCache     *cache.Cache
+	snapshots        *common.Snapshots
+	snapshotStore    *snapshotstore.Store
+	org              string
 }
 
 // NewServer creates a new server and starts it
@@ -44,11 +58,12 @@ func NewServer(config common.Config) error {
 	defer cancel()
 
 	s := server{
-		accountsMap:  config.AccountsMap,
-		router:       mux.NewRouter(),
-		version:      config.Version,
-		context:      ctx,
-		sessionCache: cache.New(600*time.Second, 900*time.Second),
+		accountsMap:      config.AccountsMap,
+		budgetActionRole: config.BudgetActionRole,
+		router:           mux.NewRouter(),
+		version:          config.Version,
+		context:          ctx,
+		sessionCache:     cache.New(600*time.Second, 900*time.Second),
 	}
 
 	if config.Org == "" {
@@ -108,6 +123,89 @@ func NewServer(config common.Config) error {
 		s.imageCache = s3cache.New(config.ImageCache)
 	}
 
+	// if specified, configure chargeback reports and their storage
+	if config.Chargeback != nil {
+		if config.Chargeback.SigningKey == "" {
+			return errors.New("'chargeback.signingKey' cannot be empty in the configuration")
+		}
+
+		store, err := reportstore.New(config.Chargeback.Storage)
+		if err != nil {
+			return err
+		}
+
+		s.chargeback = config.Chargeback
+		s.reportStore = store
+	}
+
+	// if specified, configure the cost digest subscriptions and start the scheduler
+	if config.Digests != nil {
+		if config.Digests.Interval != "" {
+			interval, err := time.ParseDuration(config.Digests.Interval)
+			if err != nil {
+				log.Error("Unexpected error with configured digest interval")
+				return err
+			}
+			DigestInterval = interval
+		}
+
+		if config.Digests.WebhookTimeout != "" {
+			timeout, err := time.ParseDuration(config.Digests.WebhookTimeout)
+			if err != nil {
+				log.Error("Unexpected error with configured digest webhook timeout")
+				return err
+			}
+			DigestWebhookTimeout = timeout
+		}
+
+		digests, err := newDigestStore(config.Digests.File)
+		if err != nil {
+			return err
+		}
+		s.digestStore = digests
+		s.digestClient = newWebhookClient(DigestWebhookTimeout)
+
+		s.startDigestScheduler(ctx, DigestInterval)
+	}
+
+	// if specified, configure the historical cost snapshot store and start the collector
+	if config.Snapshots != nil {
+		if config.Snapshots.Interval != "" {
+			interval, err := time.ParseDuration(config.Snapshots.Interval)
+			if err != nil {
+				log.Error("Unexpected error with configured snapshot interval")
+				return err
+			}
+			SnapshotInterval = interval
+		}
+
+		if config.Snapshots.Metric == "" {
+			config.Snapshots.Metric = costexplorer.MetricUnblendedCost
+		}
+
+		if err := validateOption("snapshots metric", config.Snapshots.Metric, forecastMetrics); err != nil {
+			return err
+		}
+
+		if config.Snapshots.Lookback <= 0 {
+			config.Snapshots.Lookback = SnapshotLookback
+		}
+
+		store, err := snapshotstore.New(config.Snapshots.Directory)
+		if err != nil {
+			return err
+		}
+
+		s.snapshots = config.Snapshots
+		s.snapshotStore = store
+
+		if len(s.accountsMap) == 0 {
+			log.Warn("no accounts are configured, cost snapshots will not be collected")
+		} else {
+			s.startSnapshotCollector(ctx, SnapshotInterval)
+		}
+	}
+
 	publicURLs := map[string]string{
 		"/v1/cost/ping":       "public",
 		"/v1/cost/version":    "public",
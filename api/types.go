@@ -1,9 +1,13 @@
 package api
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/service/budgets"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
 	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
 	"github.com/aws/aws-sdk-go/service/sns"
 	log "github.com/sirupsen/logrus"
@@ -11,19 +15,68 @@ import (
 
 // BudgetCreateRequest is the request object to create a Budget
 type BudgetCreateRequest struct {
-	// Amount in USD for the budget
+	// COST (the default), USAGE, RI_UTILIZATION, RI_COVERAGE, SAVINGS_PLANS_UTILIZATION
+	// or SAVINGS_PLANS_COVERAGE
+	Type string
+
+	// Amount for the budget in the unit, it's always 100 for RI and savings plans budgets
 	Amount string
 
+	// Unit of the amount, USD for COST budgets, the unit of the usage (ie. Hrs) for USAGE
+	// budgets and PERCENTAGE for RI and savings plans budgets
+	Unit string
+
 	// DAILY, MONTHLY, QUARTERLY, or ANNUALLY
 	TimeUnit string
 
+	// CostFilters are additional filters for the budget, ie. UsageTypeGroup or InstanceType.
+	// The budget is always filtered by the space tag.
+	CostFilters map[string][]string
+
+	// CostTypes overrides the default cost types of COST budgets
+	CostTypes *BudgetCostTypes
+
 	// Alerts is a list of threshold/notification configurations for
 	// a budget.  Maximum number is 5.
 	Alerts []*BudgetAlert
 
+	// Actions are run when a threshold of the budget is crossed, ie. to stop the
+	// instances in the space.  Actions are optional.
+	Actions []*BudgetAction
+
 	Tags []*Tag
 }
 
+// BudgetCostTypes are the types of costs included in a COST budget, unset types keep their default
+type BudgetCostTypes struct {
+	IncludeCredit            *bool
+	IncludeDiscount          *bool
+	IncludeOtherSubscription *bool
+	IncludeRecurring         *bool
+	IncludeRefund            *bool
+	IncludeSubscription      *bool
+	IncludeSupport           *bool
+	IncludeTax               *bool
+	IncludeUpfront           *bool
+	UseAmortized             *bool
+	UseBlended               *bool
+}
+
+// BudgetUpdateRequest is the request object to update a Budget.  The time unit is part of the
+// budget name and can't be changed.
+type BudgetUpdateRequest struct {
+	// Amount in the unit of the budget, the amount is unchanged if it's empty
+	Amount string
+
+	// Alerts replaces the list of threshold/notification configurations for the
+	// budget, the alerts are unchanged if it's not passed.  Maximum number is 5.
+	Alerts []*BudgetAlert
+
+	// Actions are added to the budget, the existing actions are kept and their
+	// instances are refreshed from the instances tagged with the space
+	Actions []*BudgetAction
+}
+
 type BudgetAlert struct {
 	// Addresses are the email addresses for notifications (up to 10)
 	Addresses []string
@@ -54,12 +107,143 @@ type BudgetAlert struct {
 	ThresholdType string
 }
 
+// BudgetAction is an action that is run when a threshold of a budget is crossed.  Only actions that
+// stop the instances in the space (RUN_SSM_DOCUMENTS) can be created.
+type BudgetAction struct {
+	// ID is the id of the action, it's set by AWS when the action is created
+	ID string
+
+	// RUN_SSM_DOCUMENTS, APPLY_IAM_POLICY and APPLY_SCP_POLICY actions are only listed
+	ActionType string
+
+	// AUTOMATIC runs the action when the threshold is crossed, MANUAL (the default)
+	// waits for the action to be approved
+	ApprovalModel string
+
+	// The role that the budget assumes to run the action, it's always the configured
+	// budget action role in the account and can't be passed
+	ExecutionRoleArn string
+
+	// Whether the action is run for how much you have spent (ACTUAL, the default) or
+	// for how much you're forecasted to spend (FORECASTED).
+	NotificationType string
+
+	// The threshold that runs the action
+	Threshold float64
+
+	// PERCENTAGE (the default) or ABSOLUTE_VALUE
+	ThresholdType string
+
+	// Addresses are the email addresses notified when the action runs (up to 10)
+	Addresses []string
+
+	// IamPolicy attaches an IAM policy to roles, groups or users, it can't be created
+	IamPolicy *BudgetIamPolicyAction `json:",omitempty"`
+
+	// ScpPolicy attaches a service control policy to organization targets, it can't be created
+	ScpPolicy *BudgetScpPolicyAction `json:",omitempty"`
+
+	// Ssm stops EC2 or RDS instances
+	Ssm *BudgetSsmAction `json:",omitempty"`
+
+	// Status is the status of the action, ie. PENDING when it's waiting for approval
+	Status string
+}
+
+// BudgetIamPolicyAction is the definition of an APPLY_IAM_POLICY action
+type BudgetIamPolicyAction struct {
+	PolicyArn string
+	Groups    []string
+	Roles     []string
+	Users     []string
+}
+
+// BudgetScpPolicyAction is the definition of an APPLY_SCP_POLICY action
+type BudgetScpPolicyAction struct {
+	PolicyId  string
+	TargetIds []string
+}
+
+// BudgetSsmAction is the definition of a RUN_SSM_DOCUMENTS action
+type BudgetSsmAction struct {
+	// STOP_EC2_INSTANCES or STOP_RDS_INSTANCES
+	ActionSubType string
+
+	// InstanceIds are the EC2 instance ids or RDS instance identifiers to stop.  They can't be
+	// set, the action stops all of the instances tagged with the space in the region and they're
+	// refreshed when the budget is updated and before the action is approved or retried.
+	InstanceIds []string
+
+	// Region of the instances, defaults to us-east-1
+	Region string
+}
+
+// CostQueryRequest is the request object to query the cost for a space with a filter expression
+type CostQueryRequest struct {
+	// Start and End dates of the query (YYYY-MM-DD), defaults to month to date
+	Start string
+	End   string
+
+	// MONTHLY, DAILY or HOURLY, defaults to MONTHLY
+	Granularity string
+
+	// Metrics to return, defaults to BLENDED_COST, UNBLENDED_COST and USAGE_QUANTITY
+	Metrics []string
+
+	// GroupBy values, the same as the groupby query parameter (up to 2)
+	GroupBy []string
+
+	// Filter is ANDed with the space filter
+	Filter *FilterExpression
+}
+
+// FilterExpression is a node in a cost query filter.  Exactly one of the fields must be set.
+type FilterExpression struct {
+	And          []*FilterExpression
+	Or           []*FilterExpression
+	Not          *FilterExpression
+	Tag          *FilterValues
+	Dimension    *FilterValues
+	CostCategory *FilterValues
+}
+
+// FilterValues matches any of the values for a tag key, dimension or cost category
+type FilterValues struct {
+	Key    string
+	Values []string
+}
+
 // BudgetResponse is the standard respoonse for a Budget
 type BudgetResponse struct {
 	Amount   string
 	Name     string
+	Type     string
+	Unit     string
 	TimeUnit string
 	Alerts   []*BudgetAlert
+	Actions  []*BudgetAction `json:",omitempty"`
+}
+
+// BudgetHistoryResponse is the budgeted versus actual amounts of a budget for each budget period
+type BudgetHistoryResponse struct {
+	Name  string
+	Start string
+	End   string
+
+	// Exceeded is the number of periods where the actual amount was more than the budgeted amount
+	Exceeded int
+
+	Periods []*BudgetPeriod
+}
+
+// BudgetPeriod is the budgeted and actual amounts of a budget for a period
+type BudgetPeriod struct {
+	Start    string
+	End      string
+	Budgeted string
+	Actual   string
+	Unit     string
+	Exceeded bool
 }
 
 type Tag struct {
@@ -95,15 +279,119 @@ func toBudgetAlert(notification *budgets.Notification, subscribers []*budgets.Su
 	}
 }
 
+func toBudgetAction(action *budgets.Action) *BudgetAction {
+	addresses := []string{}
+	for _, s := range action.Subscribers {
+		a := aws.StringValue(s.Address)
+
+		if _, err := arn.Parse(a); err == nil {
+			continue
+		}
+
+		addresses = append(addresses, a)
+	}
+
+	out := &BudgetAction{
+		ID:               aws.StringValue(action.ActionId),
+		ActionType:       aws.StringValue(action.ActionType),
+		ApprovalModel:    aws.StringValue(action.ApprovalModel),
+		ExecutionRoleArn: aws.StringValue(action.ExecutionRoleArn),
+		NotificationType: aws.StringValue(action.NotificationType),
+		Addresses:        addresses,
+		Status:           aws.StringValue(action.Status),
+	}
+
+	if t := action.ActionThreshold; t != nil {
+		out.Threshold = aws.Float64Value(t.ActionThresholdValue)
+		out.ThresholdType = aws.StringValue(t.ActionThresholdType)
+	}
+
+	if d := action.Definition; d != nil {
+		if iam := d.IamActionDefinition; iam != nil {
+			out.IamPolicy = &BudgetIamPolicyAction{
+				PolicyArn: aws.StringValue(iam.PolicyArn),
+				Groups:    aws.StringValueSlice(iam.Groups),
+				Roles:     aws.StringValueSlice(iam.Roles),
+				Users:     aws.StringValueSlice(iam.Users),
+			}
+		}
+
+		if scp := d.ScpActionDefinition; scp != nil {
+			out.ScpPolicy = &BudgetScpPolicyAction{
+				PolicyId:  aws.StringValue(scp.PolicyId),
+				TargetIds: aws.StringValueSlice(scp.TargetIds),
+			}
+		}
+
+		if ssm := d.SsmActionDefinition; ssm != nil {
+			out.Ssm = &BudgetSsmAction{
+				ActionSubType: aws.StringValue(ssm.ActionSubType),
+				InstanceIds:   aws.StringValueSlice(ssm.InstanceIds),
+				Region:        aws.StringValue(ssm.Region),
+			}
+		}
+	}
+
+	return out
+}
+
 func toBudgetResponse(budget *budgets.Budget, alerts []*BudgetAlert) *BudgetResponse {
 	return &BudgetResponse{
 		Amount:   aws.StringValue(budget.BudgetLimit.Amount),
 		Name:     aws.StringValue(budget.BudgetName),
+		Type:     aws.StringValue(budget.BudgetType),
+		Unit:     aws.StringValue(budget.BudgetLimit.Unit),
 		TimeUnit: aws.StringValue(budget.TimeUnit),
 		Alerts:   alerts,
 	}
 }
 
+func toBudgetHistoryResponse(budget, start, end string, amounts []*budgets.BudgetedAndActualAmounts) *BudgetHistoryResponse {
+	history := &BudgetHistoryResponse{
+		Name:    budget,
+		Start:   start,
+		End:     end,
+		Periods: []*BudgetPeriod{},
+	}
+
+	for _, a := range amounts {
+		period := &BudgetPeriod{}
+
+		if a.TimePeriod != nil {
+			if a.TimePeriod.Start != nil {
+				period.Start = a.TimePeriod.Start.UTC().Format("2006-01-02")
+			}
+
+			if a.TimePeriod.End != nil {
+				period.End = a.TimePeriod.End.UTC().Format("2006-01-02")
+			}
+		}
+
+		if a.BudgetedAmount != nil {
+			period.Budgeted = aws.StringValue(a.BudgetedAmount.Amount)
+			period.Unit = aws.StringValue(a.BudgetedAmount.Unit)
+		}
+
+		if a.ActualAmount != nil {
+			period.Actual = aws.StringValue(a.ActualAmount.Amount)
+			if period.Unit == "" {
+				period.Unit = aws.StringValue(a.ActualAmount.Unit)
+			}
+		}
+
+		budgeted, berr := strconv.ParseFloat(period.Budgeted, 64)
+		actual, aerr := strconv.ParseFloat(period.Actual, 64)
+		if berr == nil && aerr == nil && actual > budgeted {
+			period.Exceeded = true
+			history.Exceeded++
+		}
+
+		history.Periods = append(history.Periods, period)
+	}
+
+	return history
+}
+
 func toSnsTag(tags []*Tag) []*sns.Tag {
 	snsTags := make([]*sns.Tag, len(tags))
 	for i, t := range tags {
@@ -116,6 +404,179 @@ func toSnsTag(tags []*Tag) []*sns.Tag {
 	return snsTags
 }
 
+// SpaceCost is the total cost for a space over a time period
+type SpaceCost struct {
+	Space  string  `json:"space"`
+	Amount float64 `json:"amount"`
+	Unit   string  `json:"unit"`
+}
+
+// MultiAccountCostResponse is the response for a space cost query across all of the configured accounts
+type MultiAccountCostResponse struct {
+	// Accounts are the subtotals for each account
+	Accounts []*AccountCost
+
+	// ResultsByTime are the merged results from all of the accounts
+	ResultsByTime []*costexplorer.ResultByTime
+}
+
+// AccountCost is the total cost in an account
+type AccountCost struct {
+	Name      string
+	AccountID string
+	Total     map[string]*costexplorer.MetricValue
+}
+
+// CostComparisonResponse is the comparison of the cost for the current period with the previous period
+type CostComparisonResponse struct {
+	Period         string
+	Metric         string
+	Unit           string
+	CurrentPeriod  *costexplorer.DateInterval
+	PreviousPeriod *costexplorer.DateInterval
+	Current        float64
+	Previous       float64
+	Delta          float64
+
+	// DeltaPercent is the percentage change from the previous period, it's
+	// null when there was no cost in the previous period
+	DeltaPercent *float64
+
+	// Services are the per-service comparisons, ordered by the largest change
+	Services []*ServiceCostComparison
+}
+
+// ServiceCostComparison is the comparison of the cost for a service
+type ServiceCostComparison struct {
+	Service      string
+	Current      float64
+	Previous     float64
+	Delta        float64
+	DeltaPercent *float64
+}
+
+// InstanceRightsizing is the cost explorer rightsizing recommendation for an instance
+type InstanceRightsizing struct {
+	InstanceID   string
+	Name         string
+	InstanceType string
+
+	// Action is the recommended action, MODIFY or TERMINATE
+	Action             string
+	TargetInstanceType string `json:",omitempty"`
+	FindingReasonCodes []string
+
+	MonthlyCost             float64
+	EstimatedMonthlySavings float64
+	Unit                    string
+}
+
+// ChargebackReport is the monthly cost for each cost center in an account
+type ChargebackReport struct {
+	Account     string
+	Month       string
+	TimePeriod  *costexplorer.DateInterval
+	Metric      string
+	Unit        string
+	GeneratedAt string
+	Total       float64
+	CostCenters []*CostCenterCost
+}
+
+// CostCenterCost is the cost for a cost center and the spaces billed to it
+type CostCenterCost struct {
+	CostCenter string
+	Amount     float64
+	Spaces     []*SpaceCost
+}
+
+// ChargebackManifest describes the signed artifacts of a chargeback report
+type ChargebackManifest struct {
+	Account    string
+	Month      string
+	Generation string
+	Algorithm  string
+	Artifacts  []*ChargebackArtifact
+}
+
+// ChargebackArtifact is a stored chargeback report artifact and its signature
+type ChargebackArtifact struct {
+	Key         string
+	Format      string
+	ContentType string
+	SHA256      string
+	Signature   string
+}
+
+// DigestSubscriptionRequest is the request to create or update a cost digest subscription for a space
+type DigestSubscriptionRequest struct {
+	// Type is the delivery type, sns or webhook
+	Type string
+
+	// Endpoint is the SNS topic ARN or the HTTPS webhook URL
+	Endpoint string
+
+	// Frequency is how often the digest is delivered, DAILY or WEEKLY
+	Frequency string
+}
+
+// DigestSubscription is a cost digest subscription for a space
+type DigestSubscription struct {
+	ID        string
+	Account   string
+	Space     string
+	Type      string
+	Endpoint  string
+	Frequency string
+	CreatedAt time.Time
+	LastSent  *time.Time `json:",omitempty"`
+	LastError string     `json:",omitempty"`
+}
+
+// CostDigest is the summary of a space's cost delivered to digest subscribers
+type CostDigest struct {
+	Account     string
+	Space       string
+	TimePeriod  *costexplorer.DateInterval
+	MonthToDate *DigestAmount
+	Forecast    *DigestAmount `json:",omitempty"`
+	TopServices []*ServiceCost
+	GeneratedAt string
+}
+
+// DigestAmount is an amount of a cost digest
+type DigestAmount struct {
+	Amount float64
+	Unit   string
+}
+
+// ServiceCost is the cost of a service
+type ServiceCost struct {
+	Service string
+	Amount  float64
+	Unit    string
+}
+
+// SpaceHistory is the historical cost of a space served from the cost snapshots
+type SpaceHistory struct {
+	Space       string
+	Granularity string
+	Start       string
+	End         string
+	Total       float64
+	Unit        string
+	Periods     []*HistoryPeriod
+}
+
+// HistoryPeriod is the cost of a space for a period.  Days is the number of days in the period
+// with snapshots, so gaps in the collected history can be identified.
+type HistoryPeriod struct {
+	Start  string
+	End    string
+	Amount float64
+	Days   int
+}
+
 type InventoryResponse struct {
 	Name      string `json:"name"`
 	ARN       string `json:"arn"`
@@ -181,3 +181,234 @@ func (b *Budgets) DescribeSubscribers(ctx context.Context, account, budget strin
 
 	return out.Subscribers, nil
 }
+
+// DescribeBudgetPerformanceHistory gets the budgeted and actual amounts of a budget for each budget period
+// in the time period.  If the time period is nil, the history for all of the available periods is returned.
+func (b *Budgets) DescribeBudgetPerformanceHistory(ctx context.Context, account, budget string, period *budgets.TimePeriod) ([]*budgets.BudgetedAndActualAmounts, error) {
+	if account == "" || budget == "" {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("describing performance history for budget %s in account %s", budget, account)
+
+	amounts := []*budgets.BudgetedAndActualAmounts{}
+	input := budgets.DescribeBudgetPerformanceHistoryInput{
+		AccountId:  aws.String(account),
+		BudgetName: aws.String(budget),
+		TimePeriod: period,
+	}
+
+	for {
+		out, err := b.Service.DescribeBudgetPerformanceHistoryWithContext(ctx, &input)
+		if err != nil {
+			return nil, ErrCode("failed to describe budget performance history", err)
+		}
+
+		if out.BudgetPerformanceHistory != nil {
+			amounts = append(amounts, out.BudgetPerformanceHistory.BudgetedAndActualAmountsList...)
+		}
+
+		if out.NextToken != nil {
+			input.NextToken = out.NextToken
+			continue
+		}
+
+		log.Debugf("returning budget performance history: %+v", amounts)
+
+		return amounts, nil
+	}
+}
+
+func (b *Budgets) UpdateBudget(ctx context.Context, input *budgets.UpdateBudgetInput) error {
+	if input == nil || input.NewBudget == nil {
+		return apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("updating budget %s", aws.StringValue(input.NewBudget.BudgetName))
+
+	if _, err := b.Service.UpdateBudgetWithContext(ctx, input); err != nil {
+		return ErrCode("failed to update budget", err)
+	}
+
+	return nil
+}
+
+func (b *Budgets) CreateNotification(ctx context.Context, account, budget string, notification *budgets.Notification, subscribers []*budgets.Subscriber) error {
+	if account == "" || budget == "" || notification == nil || len(subscribers) == 0 {
+		return apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("creating notification for budget %s in account %s", budget, account)
+
+	if _, err := b.Service.CreateNotificationWithContext(ctx, &budgets.CreateNotificationInput{
+		AccountId:    aws.String(account),
+		BudgetName:   aws.String(budget),
+		Notification: notification,
+		Subscribers:  subscribers,
+	}); err != nil {
+		return ErrCode("failed to create budget notification", err)
+	}
+
+	return nil
+}
+
+func (b *Budgets) UpdateNotification(ctx context.Context, account, budget string, oldNotification, newNotification *budgets.Notification) error {
+	if account == "" || budget == "" || oldNotification == nil || newNotification == nil {
+		return apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("updating notification for budget %s in account %s", budget, account)
+
+	if _, err := b.Service.UpdateNotificationWithContext(ctx, &budgets.UpdateNotificationInput{
+		AccountId:       aws.String(account),
+		BudgetName:      aws.String(budget),
+		OldNotification: oldNotification,
+		NewNotification: newNotification,
+	}); err != nil {
+		return ErrCode("failed to update budget notification", err)
+	}
+
+	return nil
+}
+
+func (b *Budgets) DeleteNotification(ctx context.Context, account, budget string, notification *budgets.Notification) error {
+	if account == "" || budget == "" || notification == nil {
+		return apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("deleting notification for budget %s in account %s", budget, account)
+
+	if _, err := b.Service.DeleteNotificationWithContext(ctx, &budgets.DeleteNotificationInput{
+		AccountId:    aws.String(account),
+		BudgetName:   aws.String(budget),
+		Notification: notification,
+	}); err != nil {
+		return ErrCode("failed to delete budget notification", err)
+	}
+
+	return nil
+}
+
+func (b *Budgets) CreateSubscriber(ctx context.Context, account, budget string, notification *budgets.Notification, subscriber *budgets.Subscriber) error {
+	if account == "" || budget == "" || notification == nil || subscriber == nil {
+		return apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("creating subscriber %s for budget %s in account %s", aws.StringValue(subscriber.Address), budget, account)
+
+	if _, err := b.Service.CreateSubscriberWithContext(ctx, &budgets.CreateSubscriberInput{
+		AccountId:    aws.String(account),
+		BudgetName:   aws.String(budget),
+		Notification: notification,
+		Subscriber:   subscriber,
+	}); err != nil {
+		return ErrCode("failed to create budget notification subscriber", err)
+	}
+
+	return nil
+}
+
+func (b *Budgets) DeleteSubscriber(ctx context.Context, account, budget string, notification *budgets.Notification, subscriber *budgets.Subscriber) error {
+	if account == "" || budget == "" || notification == nil || subscriber == nil {
+		return apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("deleting subscriber %s for budget %s in account %s", aws.StringValue(subscriber.Address), budget, account)
+
+	if _, err := b.Service.DeleteSubscriberWithContext(ctx, &budgets.DeleteSubscriberInput{
+		AccountId:    aws.String(account),
+		BudgetName:   aws.String(budget),
+		Notification: notification,
+		Subscriber:   subscriber,
+	}); err != nil {
+		return ErrCode("failed to delete budget notification subscriber", err)
+	}
+
+	return nil
+}
+
+// CreateBudgetAction creates an action for a budget and returns the id of the action
+func (b *Budgets) CreateBudgetAction(ctx context.Context, input *budgets.CreateBudgetActionInput) (string, error) {
+	if input == nil || input.Definition == nil || input.ActionThreshold == nil {
+		return "", apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("creating %s action for budget %s", aws.StringValue(input.ActionType), aws.StringValue(input.BudgetName))
+
+	out, err := b.Service.CreateBudgetActionWithContext(ctx, input)
+	if err != nil {
+		return "", ErrCode("failed to create budget action", err)
+	}
+
+	log.Debugf("output creating budget action: %+v", out)
+
+	return aws.StringValue(out.ActionId), nil
+}
+
+// DescribeBudgetActions lists the actions for a budget
+func (b *Budgets) DescribeBudgetActions(ctx context.Context, account, budget string) ([]*budgets.Action, error) {
+	if account == "" || budget == "" {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("describing actions for budget %s in account %s", budget, account)
+
+	actions := []*budgets.Action{}
+	input := budgets.DescribeBudgetActionsForBudgetInput{
+		AccountId:  aws.String(account),
+		BudgetName: aws.String(budget),
+	}
+
+	for {
+		out, err := b.Service.DescribeBudgetActionsForBudgetWithContext(ctx, &input)
+		if err != nil {
+			return nil, ErrCode("failed to describe budget actions", err)
+		}
+
+		actions = append(actions, out.Actions...)
+
+		if out.NextToken != nil {
+			input.NextToken = out.NextToken
+			continue
+		}
+
+		log.Debugf("returning budget actions: %+v", actions)
+
+		return actions, nil
+	}
+}
+
+// ExecuteBudgetAction executes a budget action, ie. approves a pending action or reverses an executed action
+func (b *Budgets) ExecuteBudgetAction(ctx context.Context, account, budget, action, executionType string) error {
+	if account == "" || budget == "" || action == "" || executionType == "" {
+		return apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("executing %s for action %s of budget %s in account %s", executionType, action, budget, account)
+
+	if _, err := b.Service.ExecuteBudgetActionWithContext(ctx, &budgets.ExecuteBudgetActionInput{
+		AccountId:     aws.String(account),
+		ActionId:      aws.String(action),
+		BudgetName:    aws.String(budget),
+		ExecutionType: aws.String(executionType),
+	}); err != nil {
+		return ErrCode("failed to execute budget action", err)
+	}
+
+	return nil
+}
+
+// UpdateBudgetAction updates an existing action on a budget
+func (b *Budgets) UpdateBudgetAction(ctx context.Context, input *budgets.UpdateBudgetActionInput) error {
+	if input == nil || aws.StringValue(input.AccountId) == "" || aws.StringValue(input.BudgetName) == "" || aws.StringValue(input.ActionId) == "" {
+		return apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("updating action %s of budget %s in account %s", aws.StringValue(input.ActionId), aws.StringValue(input.BudgetName), aws.StringValue(input.AccountId))
+
+	if _, err := b.Service.UpdateBudgetActionWithContext(ctx, input); err != nil {
+		return ErrCode("failed to update budget action", err)
+	}
+
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/YaleSpinup/apierror"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -326,3 +328,411 @@ func TestBudgets_DescribeSubscribers(t *testing.T) {
 		})
 	}
 }
+
+func (m *mockBudgetsClient) UpdateBudgetWithContext(ctx context.Context, input *budgets.UpdateBudgetInput, opts ...request.Option) (*budgets.UpdateBudgetOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.UpdateBudgetOutput{}, nil
+}
+
+func (m *mockBudgetsClient) CreateNotificationWithContext(ctx context.Context, input *budgets.CreateNotificationInput, opts ...request.Option) (*budgets.CreateNotificationOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.CreateNotificationOutput{}, nil
+}
+
+func (m *mockBudgetsClient) UpdateNotificationWithContext(ctx context.Context, input *budgets.UpdateNotificationInput, opts ...request.Option) (*budgets.UpdateNotificationOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.UpdateNotificationOutput{}, nil
+}
+
+func (m *mockBudgetsClient) DeleteNotificationWithContext(ctx context.Context, input *budgets.DeleteNotificationInput, opts ...request.Option) (*budgets.DeleteNotificationOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.DeleteNotificationOutput{}, nil
+}
+
+func (m *mockBudgetsClient) CreateSubscriberWithContext(ctx context.Context, input *budgets.CreateSubscriberInput, opts ...request.Option) (*budgets.CreateSubscriberOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.CreateSubscriberOutput{}, nil
+}
+
+func (m *mockBudgetsClient) DeleteSubscriberWithContext(ctx context.Context, input *budgets.DeleteSubscriberInput, opts ...request.Option) (*budgets.DeleteSubscriberOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.DeleteSubscriberOutput{}, nil
+}
+
+func (m *mockBudgetsClient) DescribeBudgetPerformanceHistoryWithContext(ctx context.Context, input *budgets.DescribeBudgetPerformanceHistoryInput, opts ...request.Option) (*budgets.DescribeBudgetPerformanceHistoryOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	// return a page for each of the first two months
+	out := &budgets.DescribeBudgetPerformanceHistoryOutput{
+		BudgetPerformanceHistory: &budgets.BudgetPerformanceHistory{
+			BudgetName: input.BudgetName,
+			BudgetedAndActualAmountsList: []*budgets.BudgetedAndActualAmounts{
+				testBudgetedAndActualAmounts("2021-01-01"),
+			},
+		},
+		NextToken: aws.String("next"),
+	}
+
+	if input.NextToken != nil {
+		out.BudgetPerformanceHistory.BudgetedAndActualAmountsList = []*budgets.BudgetedAndActualAmounts{
+			testBudgetedAndActualAmounts("2021-02-01"),
+		}
+		out.NextToken = nil
+	}
+
+	return out, nil
+}
+
+func testBudgetedAndActualAmounts(start string) *budgets.BudgetedAndActualAmounts {
+	s, _ := time.Parse("2006-01-02", start)
+	return &budgets.BudgetedAndActualAmounts{
+		ActualAmount:   &budgets.Spend{Amount: aws.String("12.5"), Unit: aws.String("USD")},
+		BudgetedAmount: &budgets.Spend{Amount: aws.String("10"), Unit: aws.String("USD")},
+		TimePeriod:     &budgets.TimePeriod{Start: aws.Time(s), End: aws.Time(s.AddDate(0, 1, 0))},
+	}
+}
+
+func TestBudgets_DescribeBudgetPerformanceHistory(t *testing.T) {
+	b := &Budgets{Service: newMockBudgetsClient(t, nil)}
+
+	got, err := b.DescribeBudgetPerformanceHistory(context.TODO(), "0123456789", "budget", nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := []*budgets.BudgetedAndActualAmounts{
+		testBudgetedAndActualAmounts("2021-01-01"),
+		testBudgetedAndActualAmounts("2021-02-01"),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	if _, err := b.DescribeBudgetPerformanceHistory(context.TODO(), "", "budget", nil); err == nil {
+		t.Error("expected error for empty account, got nil")
+	}
+
+	b = &Budgets{Service: newMockBudgetsClient(t, awserr.New(budgets.ErrCodeNotFoundException, "not found", nil))}
+	_, err = b.DescribeBudgetPerformanceHistory(context.TODO(), "0123456789", "budget", nil)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrNotFound {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrNotFound, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %v", err)
+	}
+}
+
+func (m *mockBudgetsClient) CreateBudgetActionWithContext(ctx context.Context, input *budgets.CreateBudgetActionInput, opts ...request.Option) (*budgets.CreateBudgetActionOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.CreateBudgetActionOutput{
+		AccountId:  input.AccountId,
+		ActionId:   aws.String(testActionID),
+		BudgetName: input.BudgetName,
+	}, nil
+}
+
+func (m *mockBudgetsClient) DescribeBudgetActionsForBudgetWithContext(ctx context.Context, input *budgets.DescribeBudgetActionsForBudgetInput, opts ...request.Option) (*budgets.DescribeBudgetActionsForBudgetOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	// return an action on each of two pages
+	if input.NextToken == nil {
+		return &budgets.DescribeBudgetActionsForBudgetOutput{
+			Actions:   []*budgets.Action{{ActionId: aws.String("action-1"), BudgetName: input.BudgetName}},
+			NextToken: aws.String("next"),
+		}, nil
+	}
+
+	return &budgets.DescribeBudgetActionsForBudgetOutput{
+		Actions: []*budgets.Action{{ActionId: aws.String("action-2"), BudgetName: input.BudgetName}},
+	}, nil
+}
+
+func (m *mockBudgetsClient) ExecuteBudgetActionWithContext(ctx context.Context, input *budgets.ExecuteBudgetActionInput, opts ...request.Option) (*budgets.ExecuteBudgetActionOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.ExecuteBudgetActionOutput{
+		AccountId:     input.AccountId,
+		ActionId:      input.ActionId,
+		BudgetName:    input.BudgetName,
+		ExecutionType: input.ExecutionType,
+	}, nil
+}
+
+func (m *mockBudgetsClient) UpdateBudgetActionWithContext(ctx context.Context, input *budgets.UpdateBudgetActionInput, opts ...request.Option) (*budgets.UpdateBudgetActionOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &budgets.UpdateBudgetActionOutput{
+		AccountId:  input.AccountId,
+		BudgetName: input.BudgetName,
+	}, nil
+}
+
+func TestBudgets_UpdateBudget(t *testing.T) {
+	type fields struct {
+		session *session.Session
+		Service budgetsiface.BudgetsAPI
+	}
+	type args struct {
+		ctx   context.Context
+		input *budgets.UpdateBudgetInput
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name:   "nil input",
+			fields: fields{Service: newMockBudgetsClient(t, nil)},
+			args: args{
+				ctx: context.TODO(),
+			},
+			wantErr: true,
+		},
+		{
+			name:   "nil budget",
+			fields: fields{Service: newMockBudgetsClient(t, nil)},
+			args: args{
+				ctx:   context.TODO(),
+				input: &budgets.UpdateBudgetInput{},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "aws err",
+			fields: fields{Service: newMockBudgetsClient(t, awserr.New(budgets.ErrCodeNotFoundException, "boom", nil))},
+			args: args{
+				ctx: context.TODO(),
+				input: &budgets.UpdateBudgetInput{
+					AccountId: aws.String("0123456789"),
+					NewBudget: &budgets.Budget{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "valid input",
+			fields: fields{Service: newMockBudgetsClient(t, nil)},
+			args: args{
+				ctx: context.TODO(),
+				input: &budgets.UpdateBudgetInput{
+					AccountId: aws.String("0123456789"),
+					NewBudget: &budgets.Budget{},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Budgets{
+				session: tt.fields.session,
+				Service: tt.fields.Service,
+			}
+			if err := b.UpdateBudget(tt.args.ctx, tt.args.input); (err != nil) != tt.wantErr {
+				t.Errorf("Budgets.UpdateBudget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+var testNotification = &budgets.Notification{
+	ComparisonOperator: aws.String("GREATER_THAN"),
+	NotificationType:   aws.String("ACTUAL"),
+	Threshold:          aws.Float64(80),
+	ThresholdType:      aws.String("PERCENTAGE"),
+}
+
+var testSubscriber = &budgets.Subscriber{
+	Address:          aws.String("jdoe@example.com"),
+	SubscriptionType: aws.String("EMAIL"),
+}
+
+func TestBudgets_Notifications(t *testing.T) {
+	b := &Budgets{Service: newMockBudgetsClient(t, nil)}
+	ctx := context.TODO()
+
+	if err := b.CreateNotification(ctx, "0123456789", "budget", testNotification, []*budgets.Subscriber{testSubscriber}); err != nil {
+		t.Errorf("Budgets.CreateNotification() expected nil error, got %s", err)
+	}
+
+	if err := b.UpdateNotification(ctx, "0123456789", "budget", testNotification, testNotification); err != nil {
+		t.Errorf("Budgets.UpdateNotification() expected nil error, got %s", err)
+	}
+
+	if err := b.DeleteNotification(ctx, "0123456789", "budget", testNotification); err != nil {
+		t.Errorf("Budgets.DeleteNotification() expected nil error, got %s", err)
+	}
+
+	if err := b.CreateSubscriber(ctx, "0123456789", "budget", testNotification, testSubscriber); err != nil {
+		t.Errorf("Budgets.CreateSubscriber() expected nil error, got %s", err)
+	}
+
+	if err := b.DeleteSubscriber(ctx, "0123456789", "budget", testNotification, testSubscriber); err != nil {
+		t.Errorf("Budgets.DeleteSubscriber() expected nil error, got %s", err)
+	}
+
+	// invalid input
+	for name, err := range map[string]error{
+		"CreateNotification no subscribers": b.CreateNotification(ctx, "0123456789", "budget", testNotification, nil),
+		"CreateNotification empty account":  b.CreateNotification(ctx, "", "budget", testNotification, []*budgets.Subscriber{testSubscriber}),
+		"UpdateNotification nil new":        b.UpdateNotification(ctx, "0123456789", "budget", testNotification, nil),
+		"DeleteNotification nil":            b.DeleteNotification(ctx, "0123456789", "budget", nil),
+		"CreateSubscriber nil":              b.CreateSubscriber(ctx, "0123456789", "budget", testNotification, nil),
+		"DeleteSubscriber empty budget":     b.DeleteSubscriber(ctx, "0123456789", "", testNotification, testSubscriber),
+	} {
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected error code %s, got: %s", name, apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", name, err)
+		}
+	}
+
+	// aws errors
+	b = &Budgets{Service: newMockBudgetsClient(t, awserr.New(budgets.ErrCodeNotFoundException, "boom", nil))}
+	for name, err := range map[string]error{
+		"CreateNotification": b.CreateNotification(ctx, "0123456789", "budget", testNotification, []*budgets.Subscriber{testSubscriber}),
+		"UpdateNotification": b.UpdateNotification(ctx, "0123456789", "budget", testNotification, testNotification),
+		"DeleteNotification": b.DeleteNotification(ctx, "0123456789", "budget", testNotification),
+		"CreateSubscriber":   b.CreateSubscriber(ctx, "0123456789", "budget", testNotification, testSubscriber),
+		"DeleteSubscriber":   b.DeleteSubscriber(ctx, "0123456789", "budget", testNotification, testSubscriber),
+	} {
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrNotFound {
+				t.Errorf("%s: expected error code %s, got: %s", name, apierror.ErrNotFound, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", name, err)
+		}
+	}
+}
+
+const testActionID = "6e9c2f1a-0b8d-4c3e-9f7a-1d2b3c4d5e6f"
+
+var testActionInput = &budgets.CreateBudgetActionInput{
+	AccountId:        aws.String("012345678901"),
+	BudgetName:       aws.String("budget"),
+	ActionType:       aws.String(budgets.ActionTypeRunSsmDocuments),
+	ApprovalModel:    aws.String(budgets.ApprovalModelManual),
+	NotificationType: aws.String(budgets.NotificationTypeActual),
+	ActionThreshold: &budgets.ActionThreshold{
+		ActionThresholdType:  aws.String(budgets.ThresholdTypePercentage),
+		ActionThresholdValue: aws.Float64(100),
+	},
+	Definition: &budgets.Definition{
+		SsmActionDefinition: &budgets.SsmActionDefinition{
+			ActionSubType: aws.String(budgets.ActionSubTypeStopEc2Instances),
+			InstanceIds:   aws.StringSlice([]string{"i-0123456789abcdef0"}),
+			Region:        aws.String("us-east-1"),
+		},
+	},
+	ExecutionRoleArn: aws.String("arn:aws:iam::012345678901:role/budget-actions"),
+	Subscribers:      []*budgets.Subscriber{testSubscriber},
+}
+
+func TestBudgets_Actions(t *testing.T) {
+	b := &Budgets{Service: newMockBudgetsClient(t, nil)}
+	ctx := context.TODO()
+
+	id, err := b.CreateBudgetAction(ctx, testActionInput)
+	if err != nil {
+		t.Errorf("Budgets.CreateBudgetAction() expected nil error, got %s", err)
+	}
+
+	if id != testActionID {
+		t.Errorf("Budgets.CreateBudgetAction() expected action id %s, got %s", testActionID, id)
+	}
+
+	actions, err := b.DescribeBudgetActions(ctx, "012345678901", "budget")
+	if err != nil {
+		t.Errorf("Budgets.DescribeBudgetActions() expected nil error, got %s", err)
+	}
+
+	if len(actions) != 2 || aws.StringValue(actions[1].ActionId) != "action-2" {
+		t.Errorf("Budgets.DescribeBudgetActions() expected 2 actions, got %+v", actions)
+	}
+
+	if err := b.ExecuteBudgetAction(ctx, "012345678901", "budget", testActionID, budgets.ExecutionTypeApproveBudgetAction); err != nil {
+		t.Errorf("Budgets.ExecuteBudgetAction() expected nil error, got %s", err)
+	}
+
+	testUpdateInput := &budgets.UpdateBudgetActionInput{
+		AccountId:  aws.String("012345678901"),
+		ActionId:   aws.String(testActionID),
+		BudgetName: aws.String("budget"),
+	}
+	if err := b.UpdateBudgetAction(ctx, testUpdateInput); err != nil {
+		t.Errorf("Budgets.UpdateBudgetAction() expected nil error, got %s", err)
+	}
+
+	// invalid input
+	for name, err := range map[string]error{
+		"CreateBudgetAction nil": func() error { _, err := b.CreateBudgetAction(ctx, nil); return err }(),
+		"CreateBudgetAction no definition": func() error {
+			_, err := b.CreateBudgetAction(ctx, &budgets.CreateBudgetActionInput{ActionThreshold: testActionInput.ActionThreshold})
+			return err
+		}(),
+		"DescribeBudgetActions empty budget":  func() error { _, err := b.DescribeBudgetActions(ctx, "012345678901", ""); return err }(),
+		"ExecuteBudgetAction empty action":    b.ExecuteBudgetAction(ctx, "012345678901", "budget", "", budgets.ExecutionTypeApproveBudgetAction),
+		"ExecuteBudgetAction empty execution": b.ExecuteBudgetAction(ctx, "012345678901", "budget", testActionID, ""),
+		"UpdateBudgetAction nil":              b.UpdateBudgetAction(ctx, nil),
+		"UpdateBudgetAction empty action":     b.UpdateBudgetAction(ctx, &budgets.UpdateBudgetActionInput{AccountId: aws.String("012345678901"), BudgetName: aws.String("budget")}),
+	} {
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected error code %s, got: %s", name, apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", name, err)
+		}
+	}
+
+	// aws errors
+	b = &Budgets{Service: newMockBudgetsClient(t, awserr.New(budgets.ErrCodeNotFoundException, "boom", nil))}
+	for name, err := range map[string]error{
+		"CreateBudgetAction":    func() error { _, err := b.CreateBudgetAction(ctx, testActionInput); return err }(),
+		"DescribeBudgetActions": func() error { _, err := b.DescribeBudgetActions(ctx, "012345678901", "budget"); return err }(),
+		"ExecuteBudgetAction":   b.ExecuteBudgetAction(ctx, "012345678901", "budget", testActionID, budgets.ExecutionTypeApproveBudgetAction),
+		"UpdateBudgetAction":    b.UpdateBudgetAction(ctx, testUpdateInput),
+	} {
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrNotFound {
+				t.Errorf("%s: expected error code %s, got: %s", name, apierror.ErrNotFound, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", name, err)
+		}
+	}
+}
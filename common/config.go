@@ -10,17 +10,21 @@ import (
 
 // Config is representation of the configuration data
 type Config struct {
-	Account         Account
-	Accounts        map[string]Account
-	AccountsMap     map[string]string
-	CacheExpireTime string
-	CachePurgeTime  string
-	ImageCache      *S3Cache
-	ListenAddress   string
-	LogLevel        string
-	Org             string
-	Token           string
-	Version         Version
+	Account          Account
+	Accounts         map[string]Account
+	AccountsMap      map[string]string
+	BudgetActionRole string
+	CacheExpireTime  string
+	CachePurgeTime   string
+	Chargeback       *Chargeback
+	Digests          *Digests
+	ImageCache       *S3Cache
+	ListenAddress    string
+	LogLevel         string
+	Org              string
+	Snapshots        *Snapshots
+	Token            string
+	Version          Version
 }
 
 // Account is the configuration for an individual account
@@ -58,6 +62,68 @@ type AccessLog struct {
 	Prefix string
 }
 
+// Chargeback is the configuration for chargeback reports
+type Chargeback struct {
+	// CostCenters maps space ids to the cost center (or department) they are billed to
+	CostCenters map[string]string
+
+	// Metric is the cost metric used for the reports, defaults to UNBLENDED_COST
+	Metric string
+
+	// SigningKey is the key used to sign the report artifacts (HMAC-SHA256)
+	SigningKey string
+
+	// Storage is where the report artifacts are stored
+	Storage *ReportStorage
+}
+
+// ReportStorage is the configuration for the report storage backend
+type ReportStorage struct {
+	// Type is the storage backend, s3 or file
+	Type string
+
+	// Directory is the base directory for file storage
+	Directory string
+
+	// S3 storage configuration
+	Bucket   string
+	Endpoint string
+	Region   string
+	Akid     string
+	Secret   string
+	Prefix   string
+}
+
+// Digests is the configuration for scheduled cost digests
+type Digests struct {
+	// Interval is how often the scheduler checks for digests that are due, defaults to 1h
+	Interval string
+
+	// File is where digest subscriptions are persisted, if empty they are only kept in memory
+	File string
+
+	// WebhookTimeout is the timeout for delivering a digest to a webhook, defaults to 10s
+	WebhookTimeout string
+}
+
+// Snapshots is the configuration for the historical cost snapshot collector
+type Snapshots struct {
+	// Directory is where the cost snapshots are stored
+	Directory string
+
+	// Interval is how often snapshots are collected, defaults to 24h
+	Interval string
+
+	// Metric is the cost metric collected, defaults to UNBLENDED_COST
+	Metric string
+
+	// Lookback is the number of days collected again on each run since recent costs aren't final, defaults to 3
+	Lookback int
+
+	// Backfill is the number of days collected the first time an account is snapshotted, up to 365
+	Backfill int
+}
+
 // ReadConfig decodes the configuration from an io Reader
 func ReadConfig(r io.Reader) (Config, error) {
 	var c Config
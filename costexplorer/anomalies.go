@@ -0,0 +1,41 @@
+package costexplorer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetAnomalies gets the list of cost anomalies detected by the cost explorer service
+func (c *CostExplorer) GetAnomalies(ctx context.Context, input *costexplorer.GetAnomaliesInput) ([]*costexplorer.Anomaly, error) {
+	if input == nil || input.DateInterval == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting anomalies with %+v", input)
+
+	anomalies := []*costexplorer.Anomaly{}
+	for {
+		out, err := c.Service.GetAnomaliesWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get anomalies %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		anomalies = append(anomalies, out.Anomalies...)
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got anomalies: %+v", anomalies)
+
+	return anomalies, nil
+}
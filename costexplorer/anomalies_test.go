@@ -0,0 +1,91 @@
+package costexplorer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+var testAnomaly1 = &costexplorer.Anomaly{
+	AnomalyId:        aws.String("anomaly-1"),
+	AnomalyStartDate: aws.String("2021-05-01"),
+	DimensionValue:   aws.String("Amazon Elastic Compute Cloud - Compute"),
+	RootCauses: []*costexplorer.RootCause{
+		{
+			Service: aws.String("Amazon Elastic Compute Cloud - Compute"),
+			Region:  aws.String("us-east-1"),
+		},
+	},
+}
+
+var testAnomaly2 = &costexplorer.Anomaly{
+	AnomalyId:        aws.String("anomaly-2"),
+	AnomalyStartDate: aws.String("2021-05-03"),
+	DimensionValue:   aws.String("Amazon Simple Storage Service"),
+}
+
+func (m *mockCostExplorerClient) GetAnomaliesWithContext(ctx context.Context, input *costexplorer.GetAnomaliesInput, opts ...request.Option) (*costexplorer.GetAnomaliesOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetAnomaliesOutput{
+			Anomalies:     []*costexplorer.Anomaly{testAnomaly1},
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetAnomaliesOutput{
+		Anomalies: []*costexplorer.Anomaly{testAnomaly2},
+	}, nil
+}
+
+func TestGetAnomalies(t *testing.T) {
+	c := CostExplorer{
+		Service: newmockCostExplorerClient(t, nil),
+	}
+
+	// test success across pages
+	expected := []*costexplorer.Anomaly{testAnomaly1, testAnomaly2}
+	out, err := c.GetAnomalies(context.TODO(), &costexplorer.GetAnomaliesInput{
+		DateInterval: &costexplorer.AnomalyDateInterval{StartDate: aws.String("2021-05-01")},
+	})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !awsutil.DeepEqual(out, expected) {
+		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+
+	// test nil input
+	_, err = c.GetAnomalies(context.TODO(), nil)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+
+	// test unknown monitor
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeUnknownMonitorException, "boom", nil))
+	_, err = c.GetAnomalies(context.TODO(), &costexplorer.GetAnomaliesInput{
+		DateInterval: &costexplorer.AnomalyDateInterval{StartDate: aws.String("2021-05-01")},
+	})
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrNotFound {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrNotFound, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+}
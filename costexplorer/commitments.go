@@ -0,0 +1,130 @@
+package costexplorer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetSavingsPlansUtilization gets the savings plans utilization from the cost explorer service
+func (c *CostExplorer) GetSavingsPlansUtilization(ctx context.Context, input *costexplorer.GetSavingsPlansUtilizationInput) (*costexplorer.GetSavingsPlansUtilizationOutput, error) {
+	if input == nil || input.TimePeriod == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting savings plans utilization with %+v", input)
+
+	out, err := c.Service.GetSavingsPlansUtilizationWithContext(ctx, input)
+	if err != nil {
+		msg := fmt.Sprintf("failed to get savings plans utilization %+v", *input)
+		return nil, ErrCode(msg, err)
+	}
+
+	log.Debugf("got savings plans utilization: %+v", out)
+
+	return out, nil
+}
+
+// GetSavingsPlansCoverage gets the savings plans coverage from the cost explorer service
+func (c *CostExplorer) GetSavingsPlansCoverage(ctx context.Context, input *costexplorer.GetSavingsPlansCoverageInput) ([]*costexplorer.SavingsPlansCoverage, error) {
+	if input == nil || input.TimePeriod == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting savings plans coverage with %+v", input)
+
+	coverages := []*costexplorer.SavingsPlansCoverage{}
+	for {
+		out, err := c.Service.GetSavingsPlansCoverageWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get savings plans coverage %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		coverages = append(coverages, out.SavingsPlansCoverages...)
+
+		if aws.StringValue(out.NextToken) == "" {
+			break
+		}
+
+		input.NextToken = out.NextToken
+	}
+
+	log.Debugf("got savings plans coverage: %+v", coverages)
+
+	return coverages, nil
+}
+
+// GetReservationUtilization gets the reserved instance utilization from the cost explorer service
+func (c *CostExplorer) GetReservationUtilization(ctx context.Context, input *costexplorer.GetReservationUtilizationInput) (*costexplorer.GetReservationUtilizationOutput, error) {
+	if input == nil || input.TimePeriod == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting reservation utilization with %+v", input)
+
+	utilization := &costexplorer.GetReservationUtilizationOutput{
+		UtilizationsByTime: []*costexplorer.UtilizationByTime{},
+	}
+	for {
+		out, err := c.Service.GetReservationUtilizationWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get reservation utilization %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		utilization.UtilizationsByTime = append(utilization.UtilizationsByTime, out.UtilizationsByTime...)
+		if out.Total != nil {
+			utilization.Total = out.Total
+		}
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got reservation utilization: %+v", utilization)
+
+	return utilization, nil
+}
+
+// GetReservationCoverage gets the reserved instance coverage from the cost explorer service
+func (c *CostExplorer) GetReservationCoverage(ctx context.Context, input *costexplorer.GetReservationCoverageInput) (*costexplorer.GetReservationCoverageOutput, error) {
+	if input == nil || input.TimePeriod == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting reservation coverage with %+v", input)
+
+	coverage := &costexplorer.GetReservationCoverageOutput{
+		CoveragesByTime: []*costexplorer.CoverageByTime{},
+	}
+	for {
+		out, err := c.Service.GetReservationCoverageWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get reservation coverage %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		coverage.CoveragesByTime = append(coverage.CoveragesByTime, out.CoveragesByTime...)
+		if out.Total != nil {
+			coverage.Total = out.Total
+		}
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got reservation coverage: %+v", coverage)
+
+	return coverage, nil
+}
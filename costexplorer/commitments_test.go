@@ -0,0 +1,205 @@
+package costexplorer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+var testTimePeriod = &costexplorer.DateInterval{
+	Start: aws.String("2021-05-01"),
+	End:   aws.String("2021-06-01"),
+}
+
+func (m *mockCostExplorerClient) GetSavingsPlansUtilizationWithContext(ctx context.Context, input *costexplorer.GetSavingsPlansUtilizationInput, opts ...request.Option) (*costexplorer.GetSavingsPlansUtilizationOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &costexplorer.GetSavingsPlansUtilizationOutput{
+		Total: &costexplorer.SavingsPlansUtilizationAggregates{
+			Utilization: &costexplorer.SavingsPlansUtilization{
+				UtilizationPercentage: aws.String("95"),
+			},
+		},
+	}, nil
+}
+
+func (m *mockCostExplorerClient) GetSavingsPlansCoverageWithContext(ctx context.Context, input *costexplorer.GetSavingsPlansCoverageInput, opts ...request.Option) (*costexplorer.GetSavingsPlansCoverageOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextToken == nil {
+		return &costexplorer.GetSavingsPlansCoverageOutput{
+			SavingsPlansCoverages: []*costexplorer.SavingsPlansCoverage{
+				{Coverage: &costexplorer.SavingsPlansCoverageData{CoveragePercentage: aws.String("50")}},
+			},
+			NextToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetSavingsPlansCoverageOutput{
+		SavingsPlansCoverages: []*costexplorer.SavingsPlansCoverage{
+			{Coverage: &costexplorer.SavingsPlansCoverageData{CoveragePercentage: aws.String("75")}},
+		},
+	}, nil
+}
+
+func (m *mockCostExplorerClient) GetReservationUtilizationWithContext(ctx context.Context, input *costexplorer.GetReservationUtilizationInput, opts ...request.Option) (*costexplorer.GetReservationUtilizationOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetReservationUtilizationOutput{
+			UtilizationsByTime: []*costexplorer.UtilizationByTime{
+				{TimePeriod: testTimePeriod},
+			},
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetReservationUtilizationOutput{
+		UtilizationsByTime: []*costexplorer.UtilizationByTime{
+			{TimePeriod: testTimePeriod},
+		},
+		Total: &costexplorer.ReservationAggregates{
+			UtilizationPercentage: aws.String("80"),
+		},
+	}, nil
+}
+
+func (m *mockCostExplorerClient) GetReservationCoverageWithContext(ctx context.Context, input *costexplorer.GetReservationCoverageInput, opts ...request.Option) (*costexplorer.GetReservationCoverageOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetReservationCoverageOutput{
+			CoveragesByTime: []*costexplorer.CoverageByTime{
+				{TimePeriod: testTimePeriod},
+			},
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetReservationCoverageOutput{
+		CoveragesByTime: []*costexplorer.CoverageByTime{
+			{TimePeriod: testTimePeriod},
+		},
+		Total: &costexplorer.Coverage{
+			CoverageHours: &costexplorer.CoverageHours{CoverageHoursPercentage: aws.String("60")},
+		},
+	}, nil
+}
+
+func TestGetSavingsPlansUtilization(t *testing.T) {
+	c := CostExplorer{Service: newmockCostExplorerClient(t, nil)}
+
+	out, err := c.GetSavingsPlansUtilization(context.TODO(), &costexplorer.GetSavingsPlansUtilizationInput{TimePeriod: testTimePeriod})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if p := aws.StringValue(out.Total.Utilization.UtilizationPercentage); p != "95" {
+		t.Errorf("expected utilization 95, got %s", p)
+	}
+
+	if _, err := c.GetSavingsPlansUtilization(context.TODO(), nil); err == nil {
+		t.Error("expected error for nil input, got nil")
+	}
+
+	if _, err := c.GetSavingsPlansUtilization(context.TODO(), &costexplorer.GetSavingsPlansUtilizationInput{}); err == nil {
+		t.Error("expected error for missing time period, got nil")
+	}
+
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeDataUnavailableException, "boom", nil))
+	if _, err := c.GetSavingsPlansUtilization(context.TODO(), &costexplorer.GetSavingsPlansUtilizationInput{TimePeriod: testTimePeriod}); err == nil {
+		t.Error("expected error from aws, got nil")
+	}
+}
+
+func TestGetSavingsPlansCoverage(t *testing.T) {
+	c := CostExplorer{Service: newmockCostExplorerClient(t, nil)}
+
+	expected := []*costexplorer.SavingsPlansCoverage{
+		{Coverage: &costexplorer.SavingsPlansCoverageData{CoveragePercentage: aws.String("50")}},
+		{Coverage: &costexplorer.SavingsPlansCoverageData{CoveragePercentage: aws.String("75")}},
+	}
+
+	out, err := c.GetSavingsPlansCoverage(context.TODO(), &costexplorer.GetSavingsPlansCoverageInput{TimePeriod: testTimePeriod})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !awsutil.DeepEqual(expected, out) {
+		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+
+	if _, err := c.GetSavingsPlansCoverage(context.TODO(), nil); err == nil {
+		t.Error("expected error for nil input, got nil")
+	}
+
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeLimitExceededException, "boom", nil))
+	if _, err := c.GetSavingsPlansCoverage(context.TODO(), &costexplorer.GetSavingsPlansCoverageInput{TimePeriod: testTimePeriod}); err == nil {
+		t.Error("expected error from aws, got nil")
+	}
+}
+
+func TestGetReservationUtilization(t *testing.T) {
+	c := CostExplorer{Service: newmockCostExplorerClient(t, nil)}
+
+	out, err := c.GetReservationUtilization(context.TODO(), &costexplorer.GetReservationUtilizationInput{TimePeriod: testTimePeriod})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if len(out.UtilizationsByTime) != 2 {
+		t.Errorf("expected 2 utilizations, got %d", len(out.UtilizationsByTime))
+	}
+
+	if p := aws.StringValue(out.Total.UtilizationPercentage); p != "80" {
+		t.Errorf("expected utilization 80, got %s", p)
+	}
+
+	if _, err := c.GetReservationUtilization(context.TODO(), nil); err == nil {
+		t.Error("expected error for nil input, got nil")
+	}
+
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeDataUnavailableException, "boom", nil))
+	if _, err := c.GetReservationUtilization(context.TODO(), &costexplorer.GetReservationUtilizationInput{TimePeriod: testTimePeriod}); err == nil {
+		t.Error("expected error from aws, got nil")
+	}
+}
+
+func TestGetReservationCoverage(t *testing.T) {
+	c := CostExplorer{Service: newmockCostExplorerClient(t, nil)}
+
+	out, err := c.GetReservationCoverage(context.TODO(), &costexplorer.GetReservationCoverageInput{TimePeriod: testTimePeriod})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if len(out.CoveragesByTime) != 2 {
+		t.Errorf("expected 2 coverages, got %d", len(out.CoveragesByTime))
+	}
+
+	if p := aws.StringValue(out.Total.CoverageHours.CoverageHoursPercentage); p != "60" {
+		t.Errorf("expected coverage 60, got %s", p)
+	}
+
+	if _, err := c.GetReservationCoverage(context.TODO(), nil); err == nil {
+		t.Error("expected error for nil input, got nil")
+	}
+
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeDataUnavailableException, "boom", nil))
+	if _, err := c.GetReservationCoverage(context.TODO(), &costexplorer.GetReservationCoverageInput{TimePeriod: testTimePeriod}); err == nil {
+		t.Error("expected error from aws, got nil")
+	}
+}
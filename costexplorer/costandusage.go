@@ -6,11 +6,13 @@ import (
 
 	"github.com/YaleSpinup/apierror"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
 	log "github.com/sirupsen/logrus"
 )
 
-// GetCostAndUsage gets cost and usage information from the cost explorer service
+// GetCostAndUsage gets cost and usage information from the cost explorer service.  Results are
+// paged through and the groups for each time period are merged.
 func (c *CostExplorer) GetCostAndUsage(ctx context.Context, input *costexplorer.GetCostAndUsageInput) ([]*costexplorer.ResultByTime, error) {
 	if input == nil {
 		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
@@ -18,15 +20,78 @@ func (c *CostExplorer) GetCostAndUsage(ctx context.Context, input *costexplorer.
 
 	log.Infof("getting cost and usage with %+v", input)
 
-	out, err := c.Service.GetCostAndUsageWithContext(ctx, input)
-	if err != nil {
-		msg := fmt.Sprintf("failed to get cost and usage report %+v", *input)
-		return nil, ErrCode(msg, err)
+	results := []*costexplorer.ResultByTime{}
+	for {
+		out, err := c.Service.GetCostAndUsageWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get cost and usage report %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		log.Debugf("got cost and usage: %+v", out)
+
+		results = mergeResultsByTime(results, out.ResultsByTime)
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	return results, nil
+}
+
+// GetCostAndUsageWithResources gets cost and usage information, including resource ids, from the cost
+// explorer service.  Results are paged through and the groups for each time period are merged.
+func (c *CostExplorer) GetCostAndUsageWithResources(ctx context.Context, input *costexplorer.GetCostAndUsageWithResourcesInput) ([]*costexplorer.ResultByTime, error) {
+	if input == nil || input.Filter == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
 	}
 
-	log.Debugf("got cost and usage: %+v", out)
+	log.Infof("getting cost and usage with resources with %+v", input)
+
+	results := []*costexplorer.ResultByTime{}
+	for {
+		out, err := c.Service.GetCostAndUsageWithResourcesWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get cost and usage with resources report %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		results = mergeResultsByTime(results, out.ResultsByTime)
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got cost and usage with resources: %+v", results)
+
+	return results, nil
+}
+
+// mergeResultsByTime appends the page of results to the list of results, merging the groups
+// for time periods that are already in the list
+func mergeResultsByTime(results, page []*costexplorer.ResultByTime) []*costexplorer.ResultByTime {
+	for _, r := range page {
+		merged := false
+		for _, existing := range results {
+			if awsutil.DeepEqual(existing.TimePeriod, r.TimePeriod) {
+				existing.Groups = append(existing.Groups, r.Groups...)
+				merged = true
+				break
+			}
+		}
+
+		if !merged {
+			results = append(results, r)
+		}
+	}
 
-	return out.ResultsByTime, nil
+	return results
 }
 
 // And returns the expressions wrapped in And
@@ -61,3 +126,23 @@ func Tag(key string, values []string) *costexplorer.Expression {
 		},
 	}
 }
+
+// Dimension returns the cost explorer expression to filter on a dimension
+func Dimension(key string, values []string) *costexplorer.Expression {
+	return &costexplorer.Expression{
+		Dimensions: &costexplorer.DimensionValues{
+			Key:    aws.String(key),
+			Values: aws.StringSlice(values),
+		},
+	}
+}
+
+// CostCategory returns the cost explorer expression to filter on a cost category
+func CostCategory(key string, values []string) *costexplorer.Expression {
+	return &costexplorer.Expression{
+		CostCategories: &costexplorer.CostCategoryValues{
+			Key:    aws.String(key),
+			Values: aws.StringSlice(values),
+		},
+	}
+}
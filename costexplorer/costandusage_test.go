@@ -77,6 +77,122 @@ func TestGetCostAndUsage(t *testing.T) {
 	}
 }
 
+var testResourceResultPage1 = &costexplorer.ResultByTime{
+	Estimated: aws.Bool(true),
+	Groups: []*costexplorer.Group{
+		{
+			Keys: []*string{aws.String("i-0123456789abcdef0")},
+			Metrics: map[string]*costexplorer.MetricValue{
+				"UnblendedCost": {
+					Amount: aws.String("1.23"),
+					Unit:   aws.String("USD"),
+				},
+			},
+		},
+	},
+	TimePeriod: &costexplorer.DateInterval{
+		End:   aws.String("2019-07-02"),
+		Start: aws.String("2019-07-01"),
+	},
+	Total: map[string]*costexplorer.MetricValue{},
+}
+
+var testResourceResultPage2 = &costexplorer.ResultByTime{
+	Estimated: aws.Bool(true),
+	Groups: []*costexplorer.Group{
+		{
+			Keys: []*string{aws.String("i-0fedcba9876543210")},
+			Metrics: map[string]*costexplorer.MetricValue{
+				"UnblendedCost": {
+					Amount: aws.String("4.56"),
+					Unit:   aws.String("USD"),
+				},
+			},
+		},
+	},
+	TimePeriod: &costexplorer.DateInterval{
+		End:   aws.String("2019-07-02"),
+		Start: aws.String("2019-07-01"),
+	},
+	Total: map[string]*costexplorer.MetricValue{},
+}
+
+func (m *mockCostExplorerClient) GetCostAndUsageWithResourcesWithContext(ctx context.Context, input *costexplorer.GetCostAndUsageWithResourcesInput, opts ...request.Option) (*costexplorer.GetCostAndUsageWithResourcesOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	// return the first page when no token is passed
+	if input.NextPageToken == nil {
+		return &costexplorer.GetCostAndUsageWithResourcesOutput{
+			ResultsByTime: []*costexplorer.ResultByTime{
+				{
+					Estimated:  testResourceResultPage1.Estimated,
+					Groups:     testResourceResultPage1.Groups,
+					TimePeriod: testResourceResultPage1.TimePeriod,
+					Total:      testResourceResultPage1.Total,
+				},
+			},
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetCostAndUsageWithResourcesOutput{
+		ResultsByTime: []*costexplorer.ResultByTime{
+			testResourceResultPage2,
+		},
+	}, nil
+}
+
+func TestGetCostAndUsageWithResources(t *testing.T) {
+	c := CostExplorer{
+		Service: newmockCostExplorerClient(t, nil),
+	}
+
+	// test success, pages for the same time period should be merged
+	expected := []*costexplorer.ResultByTime{
+		{
+			Estimated: aws.Bool(true),
+			Groups: []*costexplorer.Group{
+				testResourceResultPage1.Groups[0],
+				testResourceResultPage2.Groups[0],
+			},
+			TimePeriod: testResourceResultPage1.TimePeriod,
+			Total:      map[string]*costexplorer.MetricValue{},
+		},
+	}
+	out, err := c.GetCostAndUsageWithResources(context.TODO(), &costexplorer.GetCostAndUsageWithResourcesInput{
+		Filter: Tag("spinup:spaceid", []string{"space-123"}),
+	})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !awsutil.DeepEqual(out, expected) {
+		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+
+	// test nil input
+	_, err = c.GetCostAndUsageWithResources(context.TODO(), nil)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+
+	// test missing filter
+	_, err = c.GetCostAndUsageWithResources(context.TODO(), &costexplorer.GetCostAndUsageWithResourcesInput{})
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+}
+
 func TestAnd(t *testing.T) {
 	expected := &costexplorer.Expression{
 		And: []*costexplorer.Expression{
@@ -213,3 +329,34 @@ func TestFilterChain(t *testing.T) {
 		t.Errorf("expected expression %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
 	}
 }
+
+func TestDimension(t *testing.T) {
+	expected := &costexplorer.Expression{
+		Dimensions: &costexplorer.DimensionValues{
+			Key: aws.String("SERVICE"),
+			Values: []*string{
+				aws.String("Amazon Elastic Compute Cloud - Compute"),
+			},
+		},
+	}
+	out := Dimension("SERVICE", []string{"Amazon Elastic Compute Cloud - Compute"})
+	if !awsutil.DeepEqual(expected, out) {
+		t.Errorf("expected expression %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+}
+
+func TestCostCategory(t *testing.T) {
+	expected := &costexplorer.Expression{
+		CostCategories: &costexplorer.CostCategoryValues{
+			Key: aws.String("Department"),
+			Values: []*string{
+				aws.String("Research"),
+				aws.String("Teaching"),
+			},
+		},
+	}
+	out := CostCategory("Department", []string{"Research", "Teaching"})
+	if !awsutil.DeepEqual(expected, out) {
+		t.Errorf("expected expression %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+}
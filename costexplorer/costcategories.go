@@ -0,0 +1,73 @@
+package costexplorer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListCostCategoryDefinitions lists the cost category definitions in the account.  Results are
+// paged through and the list of cost category references is returned.
+func (c *CostExplorer) ListCostCategoryDefinitions(ctx context.Context, input *costexplorer.ListCostCategoryDefinitionsInput) ([]*costexplorer.CostCategoryReference, error) {
+	if input == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("listing cost category definitions with %+v", input)
+
+	references := []*costexplorer.CostCategoryReference{}
+	for {
+		out, err := c.Service.ListCostCategoryDefinitionsWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to list cost category definitions %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		references = append(references, out.CostCategoryReferences...)
+
+		if aws.StringValue(out.NextToken) == "" {
+			break
+		}
+
+		input.NextToken = out.NextToken
+	}
+
+	log.Debugf("got cost category definitions: %+v", references)
+
+	return references, nil
+}
+
+// GetCostCategories gets the values for a cost category over a time period.  Results are paged
+// through and the list of values is returned.
+func (c *CostExplorer) GetCostCategories(ctx context.Context, input *costexplorer.GetCostCategoriesInput) ([]string, error) {
+	if input == nil || input.CostCategoryName == nil || input.TimePeriod == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting cost categories with %+v", input)
+
+	values := []string{}
+	for {
+		out, err := c.Service.GetCostCategoriesWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get cost categories %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		values = append(values, aws.StringValueSlice(out.CostCategoryValues)...)
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got cost category values: %+v", values)
+
+	return values, nil
+}
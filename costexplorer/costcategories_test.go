@@ -0,0 +1,146 @@
+package costexplorer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+var testCostCategoryReference1 = &costexplorer.CostCategoryReference{
+	CostCategoryArn: aws.String("arn:aws:ce::012345678901:costcategory/department"),
+	Name:            aws.String("Department"),
+	Values:          aws.StringSlice([]string{"Research", "Teaching"}),
+}
+
+var testCostCategoryReference2 = &costexplorer.CostCategoryReference{
+	CostCategoryArn: aws.String("arn:aws:ce::012345678901:costcategory/project"),
+	Name:            aws.String("Project"),
+}
+
+func (m *mockCostExplorerClient) ListCostCategoryDefinitionsWithContext(ctx context.Context, input *costexplorer.ListCostCategoryDefinitionsInput, opts ...request.Option) (*costexplorer.ListCostCategoryDefinitionsOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextToken == nil {
+		return &costexplorer.ListCostCategoryDefinitionsOutput{
+			CostCategoryReferences: []*costexplorer.CostCategoryReference{testCostCategoryReference1},
+			NextToken:              aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.ListCostCategoryDefinitionsOutput{
+		CostCategoryReferences: []*costexplorer.CostCategoryReference{testCostCategoryReference2},
+	}, nil
+}
+
+func (m *mockCostExplorerClient) GetCostCategoriesWithContext(ctx context.Context, input *costexplorer.GetCostCategoriesInput, opts ...request.Option) (*costexplorer.GetCostCategoriesOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetCostCategoriesOutput{
+			CostCategoryValues: aws.StringSlice([]string{"Research"}),
+			NextPageToken:      aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetCostCategoriesOutput{
+		CostCategoryValues: aws.StringSlice([]string{"Teaching"}),
+	}, nil
+}
+
+func TestListCostCategoryDefinitions(t *testing.T) {
+	c := CostExplorer{
+		Service: newmockCostExplorerClient(t, nil),
+	}
+
+	// test success across pages
+	expected := []*costexplorer.CostCategoryReference{testCostCategoryReference1, testCostCategoryReference2}
+	out, err := c.ListCostCategoryDefinitions(context.TODO(), &costexplorer.ListCostCategoryDefinitionsInput{})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !awsutil.DeepEqual(out, expected) {
+		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+
+	// test nil input
+	_, err = c.ListCostCategoryDefinitions(context.TODO(), nil)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+
+	// test aws error
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeLimitExceededException, "boom", nil))
+	_, err = c.ListCostCategoryDefinitions(context.TODO(), &costexplorer.ListCostCategoryDefinitionsInput{})
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrLimitExceeded {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrLimitExceeded, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+}
+
+func TestGetCostCategories(t *testing.T) {
+	c := CostExplorer{
+		Service: newmockCostExplorerClient(t, nil),
+	}
+
+	input := &costexplorer.GetCostCategoriesInput{
+		CostCategoryName: aws.String("Department"),
+		TimePeriod: &costexplorer.DateInterval{
+			Start: aws.String("2021-05-01"),
+			End:   aws.String("2021-06-01"),
+		},
+	}
+
+	// test success across pages
+	expected := []string{"Research", "Teaching"}
+	out, err := c.GetCostCategories(context.TODO(), input)
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	// test nil input and missing time period
+	for _, in := range []*costexplorer.GetCostCategoriesInput{nil, {CostCategoryName: aws.String("Department")}} {
+		_, err = c.GetCostCategories(context.TODO(), in)
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+		}
+	}
+
+	// test aws error
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeDataUnavailableException, "boom", nil))
+	input.NextPageToken = nil
+	_, err = c.GetCostCategories(context.TODO(), input)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrNotFound {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrNotFound, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+}
@@ -33,7 +33,13 @@ func ErrCode(msg string, err error) error {
 			// "DataUnavailableException".
 			//
 			// The requested data is unavailable.
-			costexplorer.ErrCodeDataUnavailableException:
+			costexplorer.ErrCodeDataUnavailableException,
+
+			// ErrCodeUnknownMonitorException for service response error code
+			// "UnknownMonitorException".
+			//
+			// The cost anomaly monitor does not exist for the account.
+			costexplorer.ErrCodeUnknownMonitorException:
 			return apierror.New(apierror.ErrNotFound, msg, aerr)
 
 		case
@@ -0,0 +1,48 @@
+package costexplorer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetCostForecast gets a cost forecast from the cost explorer service
+func (c *CostExplorer) GetCostForecast(ctx context.Context, input *costexplorer.GetCostForecastInput) (*costexplorer.GetCostForecastOutput, error) {
+	if input == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting cost forecast with %+v", input)
+
+	out, err := c.Service.GetCostForecastWithContext(ctx, input)
+	if err != nil {
+		msg := fmt.Sprintf("failed to get cost forecast %+v", *input)
+		return nil, ErrCode(msg, err)
+	}
+
+	log.Debugf("got cost forecast: %+v", out)
+
+	return out, nil
+}
+
+// GetUsageForecast gets a usage forecast from the cost explorer service
+func (c *CostExplorer) GetUsageForecast(ctx context.Context, input *costexplorer.GetUsageForecastInput) (*costexplorer.GetUsageForecastOutput, error) {
+	if input == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting usage forecast with %+v", input)
+
+	out, err := c.Service.GetUsageForecastWithContext(ctx, input)
+	if err != nil {
+		msg := fmt.Sprintf("failed to get usage forecast %+v", *input)
+		return nil, ErrCode(msg, err)
+	}
+
+	log.Debugf("got usage forecast: %+v", out)
+
+	return out, nil
+}
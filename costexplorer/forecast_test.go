@@ -0,0 +1,137 @@
+package costexplorer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+var testForecastOutput = &costexplorer.GetCostForecastOutput{
+	ForecastResultsByTime: []*costexplorer.ForecastResult{
+		{
+			MeanValue:                    aws.String("123.45"),
+			PredictionIntervalLowerBound: aws.String("100.00"),
+			PredictionIntervalUpperBound: aws.String("150.00"),
+			TimePeriod: &costexplorer.DateInterval{
+				Start: aws.String("2019-07-15"),
+				End:   aws.String("2019-08-01"),
+			},
+		},
+	},
+	Total: &costexplorer.MetricValue{
+		Amount: aws.String("123.45"),
+		Unit:   aws.String("USD"),
+	},
+}
+
+func (m *mockCostExplorerClient) GetCostForecastWithContext(ctx context.Context, input *costexplorer.GetCostForecastInput, opts ...request.Option) (*costexplorer.GetCostForecastOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return testForecastOutput, nil
+}
+
+func TestGetCostForecast(t *testing.T) {
+	c := CostExplorer{
+		Service: newmockCostExplorerClient(t, nil),
+	}
+
+	// test success
+	out, err := c.GetCostForecast(context.TODO(), &costexplorer.GetCostForecastInput{})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !reflect.DeepEqual(out, testForecastOutput) {
+		t.Errorf("expected %+v, got %+v", testForecastOutput, out)
+	}
+
+	// test nil input
+	_, err = c.GetCostForecast(context.TODO(), nil)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+
+	// test aws error
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeDataUnavailableException, "boom", nil))
+	_, err = c.GetCostForecast(context.TODO(), &costexplorer.GetCostForecastInput{})
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrNotFound {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrNotFound, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+}
+
+var testUsageForecastOutput = &costexplorer.GetUsageForecastOutput{
+	ForecastResultsByTime: []*costexplorer.ForecastResult{
+		{
+			MeanValue: aws.String("372"),
+			TimePeriod: &costexplorer.DateInterval{
+				Start: aws.String("2019-07-15"),
+				End:   aws.String("2019-08-01"),
+			},
+		},
+	},
+	Total: &costexplorer.MetricValue{
+		Amount: aws.String("372"),
+		Unit:   aws.String("Hrs"),
+	},
+}
+
+func (m *mockCostExplorerClient) GetUsageForecastWithContext(ctx context.Context, input *costexplorer.GetUsageForecastInput, opts ...request.Option) (*costexplorer.GetUsageForecastOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return testUsageForecastOutput, nil
+}
+
+func TestGetUsageForecast(t *testing.T) {
+	c := CostExplorer{
+		Service: newmockCostExplorerClient(t, nil),
+	}
+
+	// test success
+	out, err := c.GetUsageForecast(context.TODO(), &costexplorer.GetUsageForecastInput{})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !reflect.DeepEqual(out, testUsageForecastOutput) {
+		t.Errorf("expected %+v, got %+v", testUsageForecastOutput, out)
+	}
+
+	// test nil input
+	_, err = c.GetUsageForecast(context.TODO(), nil)
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+
+	// test aws error
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeUnresolvableUsageUnitException, "boom", nil))
+	_, err = c.GetUsageForecast(context.TODO(), &costexplorer.GetUsageForecastInput{})
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrBadRequest {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+}
@@ -0,0 +1,121 @@
+package costexplorer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetSavingsPlansPurchaseRecommendation gets the savings plans purchase recommendation from the cost explorer
+// service.  Results are paged through and the recommendation details are merged.
+func (c *CostExplorer) GetSavingsPlansPurchaseRecommendation(ctx context.Context, input *costexplorer.GetSavingsPlansPurchaseRecommendationInput) (*costexplorer.GetSavingsPlansPurchaseRecommendationOutput, error) {
+	if input == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting savings plans purchase recommendation with %+v", input)
+
+	var recommendation *costexplorer.GetSavingsPlansPurchaseRecommendationOutput
+	for {
+		out, err := c.Service.GetSavingsPlansPurchaseRecommendationWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get savings plans purchase recommendation %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		if recommendation == nil {
+			recommendation = out
+		} else if out.SavingsPlansPurchaseRecommendation != nil {
+			if recommendation.SavingsPlansPurchaseRecommendation == nil {
+				recommendation.SavingsPlansPurchaseRecommendation = out.SavingsPlansPurchaseRecommendation
+			} else {
+				recommendation.SavingsPlansPurchaseRecommendation.SavingsPlansPurchaseRecommendationDetails = append(
+					recommendation.SavingsPlansPurchaseRecommendation.SavingsPlansPurchaseRecommendationDetails,
+					out.SavingsPlansPurchaseRecommendation.SavingsPlansPurchaseRecommendationDetails...,
+				)
+			}
+		}
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+	recommendation.NextPageToken = nil
+
+	log.Debugf("got savings plans purchase recommendation: %+v", recommendation)
+
+	return recommendation, nil
+}
+
+// GetReservationPurchaseRecommendation gets the reserved instance purchase recommendations from the cost
+// explorer service.  Results are paged through and the recommendations are merged.
+func (c *CostExplorer) GetReservationPurchaseRecommendation(ctx context.Context, input *costexplorer.GetReservationPurchaseRecommendationInput) (*costexplorer.GetReservationPurchaseRecommendationOutput, error) {
+	if input == nil || input.Service == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting reservation purchase recommendation with %+v", input)
+
+	recommendation := &costexplorer.GetReservationPurchaseRecommendationOutput{
+		Recommendations: []*costexplorer.ReservationPurchaseRecommendation{},
+	}
+	for {
+		out, err := c.Service.GetReservationPurchaseRecommendationWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get reservation purchase recommendation %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		if recommendation.Metadata == nil {
+			recommendation.Metadata = out.Metadata
+		}
+		recommendation.Recommendations = append(recommendation.Recommendations, out.Recommendations...)
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got reservation purchase recommendation: %+v", recommendation)
+
+	return recommendation, nil
+}
+
+// GetRightsizingRecommendation gets the rightsizing recommendations from the cost explorer service.
+// Results are paged through and the list of recommendations is returned.
+func (c *CostExplorer) GetRightsizingRecommendation(ctx context.Context, input *costexplorer.GetRightsizingRecommendationInput) ([]*costexplorer.RightsizingRecommendation, error) {
+	if input == nil || input.Service == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting rightsizing recommendation with %+v", input)
+
+	recommendations := []*costexplorer.RightsizingRecommendation{}
+	for {
+		out, err := c.Service.GetRightsizingRecommendationWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get rightsizing recommendation %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		recommendations = append(recommendations, out.RightsizingRecommendations...)
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got rightsizing recommendations: %+v", recommendations)
+
+	return recommendations, nil
+}
@@ -0,0 +1,188 @@
+package costexplorer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+func (m *mockCostExplorerClient) GetSavingsPlansPurchaseRecommendationWithContext(ctx context.Context, input *costexplorer.GetSavingsPlansPurchaseRecommendationInput, opts ...request.Option) (*costexplorer.GetSavingsPlansPurchaseRecommendationOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetSavingsPlansPurchaseRecommendationOutput{
+			Metadata: &costexplorer.SavingsPlansPurchaseRecommendationMetadata{
+				RecommendationId: aws.String("rec-1"),
+			},
+			SavingsPlansPurchaseRecommendation: &costexplorer.SavingsPlansPurchaseRecommendation{
+				SavingsPlansPurchaseRecommendationDetails: []*costexplorer.SavingsPlansPurchaseRecommendationDetail{
+					{HourlyCommitmentToPurchase: aws.String("1.00")},
+				},
+			},
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetSavingsPlansPurchaseRecommendationOutput{
+		SavingsPlansPurchaseRecommendation: &costexplorer.SavingsPlansPurchaseRecommendation{
+			SavingsPlansPurchaseRecommendationDetails: []*costexplorer.SavingsPlansPurchaseRecommendationDetail{
+				{HourlyCommitmentToPurchase: aws.String("2.00")},
+			},
+		},
+	}, nil
+}
+
+func (m *mockCostExplorerClient) GetReservationPurchaseRecommendationWithContext(ctx context.Context, input *costexplorer.GetReservationPurchaseRecommendationInput, opts ...request.Option) (*costexplorer.GetReservationPurchaseRecommendationOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetReservationPurchaseRecommendationOutput{
+			Metadata: &costexplorer.ReservationPurchaseRecommendationMetadata{
+				RecommendationId: aws.String("rec-2"),
+			},
+			Recommendations: []*costexplorer.ReservationPurchaseRecommendation{
+				{TermInYears: aws.String("ONE_YEAR")},
+			},
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetReservationPurchaseRecommendationOutput{
+		Recommendations: []*costexplorer.ReservationPurchaseRecommendation{
+			{TermInYears: aws.String("THREE_YEARS")},
+		},
+	}, nil
+}
+
+func TestGetSavingsPlansPurchaseRecommendation(t *testing.T) {
+	c := CostExplorer{Service: newmockCostExplorerClient(t, nil)}
+
+	out, err := c.GetSavingsPlansPurchaseRecommendation(context.TODO(), &costexplorer.GetSavingsPlansPurchaseRecommendationInput{})
+	if err != nil {
+		t.Fatalf("expected nil error, got: %s", err)
+	}
+
+	if id := aws.StringValue(out.Metadata.RecommendationId); id != "rec-1" {
+		t.Errorf("expected recommendation id rec-1, got %s", id)
+	}
+
+	if n := len(out.SavingsPlansPurchaseRecommendation.SavingsPlansPurchaseRecommendationDetails); n != 2 {
+		t.Errorf("expected 2 recommendation details, got %d", n)
+	}
+
+	if out.NextPageToken != nil {
+		t.Errorf("expected nil next page token, got %s", aws.StringValue(out.NextPageToken))
+	}
+
+	if _, err := c.GetSavingsPlansPurchaseRecommendation(context.TODO(), nil); err == nil {
+		t.Error("expected error for nil input, got nil")
+	}
+
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeLimitExceededException, "boom", nil))
+	if _, err := c.GetSavingsPlansPurchaseRecommendation(context.TODO(), &costexplorer.GetSavingsPlansPurchaseRecommendationInput{}); err == nil {
+		t.Error("expected error from aws, got nil")
+	}
+}
+
+func TestGetReservationPurchaseRecommendation(t *testing.T) {
+	c := CostExplorer{Service: newmockCostExplorerClient(t, nil)}
+
+	input := &costexplorer.GetReservationPurchaseRecommendationInput{
+		Service: aws.String("Amazon Elastic Compute Cloud - Compute"),
+	}
+
+	out, err := c.GetReservationPurchaseRecommendation(context.TODO(), input)
+	if err != nil {
+		t.Fatalf("expected nil error, got: %s", err)
+	}
+
+	if id := aws.StringValue(out.Metadata.RecommendationId); id != "rec-2" {
+		t.Errorf("expected recommendation id rec-2, got %s", id)
+	}
+
+	if n := len(out.Recommendations); n != 2 {
+		t.Errorf("expected 2 recommendations, got %d", n)
+	}
+
+	if _, err := c.GetReservationPurchaseRecommendation(context.TODO(), nil); err == nil {
+		t.Error("expected error for nil input, got nil")
+	}
+
+	if _, err := c.GetReservationPurchaseRecommendation(context.TODO(), &costexplorer.GetReservationPurchaseRecommendationInput{}); err == nil {
+		t.Error("expected error for missing service, got nil")
+	}
+
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeLimitExceededException, "boom", nil))
+	if _, err := c.GetReservationPurchaseRecommendation(context.TODO(), &costexplorer.GetReservationPurchaseRecommendationInput{Service: aws.String("foo")}); err == nil {
+		t.Error("expected error from aws, got nil")
+	}
+}
+
+func (m *mockCostExplorerClient) GetRightsizingRecommendationWithContext(ctx context.Context, input *costexplorer.GetRightsizingRecommendationInput, opts ...request.Option) (*costexplorer.GetRightsizingRecommendationOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetRightsizingRecommendationOutput{
+			RightsizingRecommendations: []*costexplorer.RightsizingRecommendation{
+				{
+					CurrentInstance: &costexplorer.CurrentInstance{ResourceId: aws.String("i-0000001")},
+					RightsizingType: aws.String(costexplorer.RightsizingTypeTerminate),
+				},
+			},
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetRightsizingRecommendationOutput{
+		RightsizingRecommendations: []*costexplorer.RightsizingRecommendation{
+			{
+				CurrentInstance: &costexplorer.CurrentInstance{ResourceId: aws.String("i-0000002")},
+				RightsizingType: aws.String(costexplorer.RightsizingTypeModify),
+			},
+		},
+	}, nil
+}
+
+func TestGetRightsizingRecommendation(t *testing.T) {
+	c := CostExplorer{Service: newmockCostExplorerClient(t, nil)}
+
+	out, err := c.GetRightsizingRecommendation(context.TODO(), &costexplorer.GetRightsizingRecommendationInput{
+		Service: aws.String("AmazonEC2"),
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got: %s", err)
+	}
+
+	if len(out) != 2 {
+		t.Fatalf("expected 2 recommendations, got %d", len(out))
+	}
+
+	for i, id := range []string{"i-0000001", "i-0000002"} {
+		if got := aws.StringValue(out[i].CurrentInstance.ResourceId); got != id {
+			t.Errorf("expected recommendation %d for %s, got %s", i, id, got)
+		}
+	}
+
+	if _, err := c.GetRightsizingRecommendation(context.TODO(), nil); err == nil {
+		t.Error("expected error for nil input, got nil")
+	}
+
+	if _, err := c.GetRightsizingRecommendation(context.TODO(), &costexplorer.GetRightsizingRecommendationInput{}); err == nil {
+		t.Error("expected error for missing service, got nil")
+	}
+
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeInvalidNextTokenException, "boom", nil))
+	if _, err := c.GetRightsizingRecommendation(context.TODO(), &costexplorer.GetRightsizingRecommendationInput{Service: aws.String("AmazonEC2")}); err == nil {
+		t.Error("expected error from aws, got nil")
+	}
+}
@@ -0,0 +1,73 @@
+package costexplorer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetDimensionValues gets the values of a dimension over a time period.  Results are paged through
+// and the list of values (with their attributes) is returned.
+func (c *CostExplorer) GetDimensionValues(ctx context.Context, input *costexplorer.GetDimensionValuesInput) ([]*costexplorer.DimensionValuesWithAttributes, error) {
+	if input == nil || input.Dimension == nil || input.TimePeriod == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting dimension values with %+v", input)
+
+	values := []*costexplorer.DimensionValuesWithAttributes{}
+	for {
+		out, err := c.Service.GetDimensionValuesWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get dimension values %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		values = append(values, out.DimensionValues...)
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got dimension values: %+v", values)
+
+	return values, nil
+}
+
+// GetTags gets the values of a tag key over a time period.  Results are paged through and the
+// list of values is returned.
+func (c *CostExplorer) GetTags(ctx context.Context, input *costexplorer.GetTagsInput) ([]string, error) {
+	if input == nil || input.TimePeriod == nil {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("getting tags with %+v", input)
+
+	tags := []string{}
+	for {
+		out, err := c.Service.GetTagsWithContext(ctx, input)
+		if err != nil {
+			msg := fmt.Sprintf("failed to get tags %+v", *input)
+			return nil, ErrCode(msg, err)
+		}
+
+		tags = append(tags, aws.StringValueSlice(out.Tags)...)
+
+		if aws.StringValue(out.NextPageToken) == "" {
+			break
+		}
+
+		input.NextPageToken = out.NextPageToken
+	}
+
+	log.Debugf("got tags: %+v", tags)
+
+	return tags, nil
+}
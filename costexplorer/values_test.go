@@ -0,0 +1,144 @@
+package costexplorer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/costexplorer"
+)
+
+func (m *mockCostExplorerClient) GetDimensionValuesWithContext(ctx context.Context, input *costexplorer.GetDimensionValuesInput, opts ...request.Option) (*costexplorer.GetDimensionValuesOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetDimensionValuesOutput{
+			DimensionValues: []*costexplorer.DimensionValuesWithAttributes{
+				{Value: aws.String("Amazon Elastic Compute Cloud - Compute")},
+			},
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetDimensionValuesOutput{
+		DimensionValues: []*costexplorer.DimensionValuesWithAttributes{
+			{Value: aws.String("Amazon Simple Storage Service")},
+		},
+	}, nil
+}
+
+func (m *mockCostExplorerClient) GetTagsWithContext(ctx context.Context, input *costexplorer.GetTagsInput, opts ...request.Option) (*costexplorer.GetTagsOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if input.NextPageToken == nil {
+		return &costexplorer.GetTagsOutput{
+			Tags:          aws.StringSlice([]string{"alice"}),
+			NextPageToken: aws.String("page2"),
+		}, nil
+	}
+
+	return &costexplorer.GetTagsOutput{
+		Tags: aws.StringSlice([]string{"bob"}),
+	}, nil
+}
+
+func TestGetDimensionValues(t *testing.T) {
+	c := CostExplorer{
+		Service: newmockCostExplorerClient(t, nil),
+	}
+
+	// test success across pages
+	expected := []*costexplorer.DimensionValuesWithAttributes{
+		{Value: aws.String("Amazon Elastic Compute Cloud - Compute")},
+		{Value: aws.String("Amazon Simple Storage Service")},
+	}
+	out, err := c.GetDimensionValues(context.TODO(), &costexplorer.GetDimensionValuesInput{
+		Dimension:  aws.String("SERVICE"),
+		TimePeriod: testTimePeriod,
+	})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !awsutil.DeepEqual(out, expected) {
+		t.Errorf("expected %s, got %s", awsutil.Prettify(expected), awsutil.Prettify(out))
+	}
+
+	// test invalid input
+	for _, in := range []*costexplorer.GetDimensionValuesInput{nil, {TimePeriod: testTimePeriod}, {Dimension: aws.String("SERVICE")}} {
+		_, err = c.GetDimensionValues(context.TODO(), in)
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+		}
+	}
+
+	// test aws error
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeLimitExceededException, "boom", nil))
+	_, err = c.GetDimensionValues(context.TODO(), &costexplorer.GetDimensionValuesInput{
+		Dimension:  aws.String("SERVICE"),
+		TimePeriod: testTimePeriod,
+	})
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrLimitExceeded {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrLimitExceeded, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+}
+
+func TestGetTags(t *testing.T) {
+	c := CostExplorer{
+		Service: newmockCostExplorerClient(t, nil),
+	}
+
+	// test success across pages
+	expected := []string{"alice", "bob"}
+	out, err := c.GetTags(context.TODO(), &costexplorer.GetTagsInput{
+		TagKey:     aws.String("spinup:owner"),
+		TimePeriod: testTimePeriod,
+	})
+	if err != nil {
+		t.Errorf("expected nil error, got: %s", err)
+	}
+
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	// test invalid input
+	for _, in := range []*costexplorer.GetTagsInput{nil, {TagKey: aws.String("spinup:owner")}} {
+		_, err = c.GetTags(context.TODO(), in)
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("expected error code %s, got: %s", apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+		}
+	}
+
+	// test aws error
+	c.Service = newmockCostExplorerClient(t, awserr.New(costexplorer.ErrCodeDataUnavailableException, "boom", nil))
+	_, err = c.GetTags(context.TODO(), &costexplorer.GetTagsInput{TimePeriod: testTimePeriod})
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrNotFound {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrNotFound, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err).String())
+	}
+}
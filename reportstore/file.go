@@ -0,0 +1,108 @@
+package reportstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/YaleSpinup/apierror"
+	log "github.com/sirupsen/logrus"
+)
+
+// FileStore stores report artifacts in a local directory
+type FileStore struct {
+	Directory string
+}
+
+// NewFileStore creates a new FileStore in the given directory
+func NewFileStore(directory string) (*FileStore, error) {
+	if directory == "" {
+		return nil, fmt.Errorf("report storage directory is required")
+	}
+
+	if err := os.MkdirAll(directory, 0750); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{Directory: directory}, nil
+}
+
+// Get reads the artifact with the key
+func (f *FileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("getting report artifact %s", path)
+
+	out, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			msg := fmt.Sprintf("report artifact %s not found", key)
+			return nil, apierror.New(apierror.ErrNotFound, msg, err)
+		}
+
+		msg := fmt.Sprintf("failed to read report artifact %s", key)
+		return nil, apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	return out, nil
+}
+
+// Put writes the artifact with the key, it fails if the artifact already exists.  The content
+// type isn't stored.
+func (f *FileStore) Put(ctx context.Context, key string, obj []byte, contentType string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("saving report artifact %s", path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		msg := fmt.Sprintf("failed to create directory for report artifact %s", key)
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0440)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			msg := fmt.Sprintf("report artifact %s already exists", key)
+			return apierror.New(apierror.ErrConflict, msg, err)
+		}
+
+		msg := fmt.Sprintf("failed to create report artifact %s", key)
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	if _, err := file.Write(obj); err != nil {
+		file.Close()
+		os.Remove(path)
+		msg := fmt.Sprintf("failed to write report artifact %s", key)
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		msg := fmt.Sprintf("failed to write report artifact %s", key)
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	return nil
+}
+
+// path returns the path of the artifact in the store directory, keys cannot escape the directory
+func (f *FileStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || clean == "/" || strings.Contains(key, "..") {
+		msg := fmt.Sprintf("invalid report artifact key '%s'", key)
+		return "", apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	return filepath.Join(f.Directory, filepath.FromSlash(clean)), nil
+}
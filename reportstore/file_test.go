@@ -0,0 +1,72 @@
+package reportstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFileStore(filepath.Join(dir, "reports"))
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if err := f.Put(context.TODO(), "chargeback/123/2021-05/report.json", []byte(`{"foo":"bar"}`), "application/json"); err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "reports", "chargeback", "123", "2021-05", "report.json")); err != nil {
+		t.Errorf("expected report to be written to the directory, got %s", err)
+	}
+
+	out, err := f.Get(context.TODO(), "chargeback/123/2021-05/report.json")
+	if err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if string(out) != `{"foo":"bar"}` {
+		t.Errorf("expected %s, got %s", `{"foo":"bar"}`, string(out))
+	}
+
+	// test put is write once
+	err = f.Put(context.TODO(), "chargeback/123/2021-05/report.json", []byte(`{"foo":"baz"}`), "application/json")
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrConflict {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrConflict, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %v", err)
+	}
+
+	// test missing artifact
+	_, err = f.Get(context.TODO(), "chargeback/123/2021-06/report.json")
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrNotFound {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrNotFound, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %v", err)
+	}
+
+	// test invalid keys
+	for _, key := range []string{"", "/", "../outside.json", "chargeback/../../outside.json"} {
+		err := f.Put(context.TODO(), key, []byte("foo"), "text/plain")
+		if aerr, ok := err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("expected error code %s for key '%s', got: %s", apierror.ErrBadRequest, key, aerr.Code)
+			}
+		} else {
+			t.Errorf("expected apierror.Error for key '%s', got: %s", key, reflect.TypeOf(err))
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "outside.json")); err == nil {
+		t.Errorf("expected artifact not to be written outside of the directory")
+	}
+}
@@ -0,0 +1,31 @@
+package reportstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YaleSpinup/cost-api/common"
+)
+
+// ReportStore stores report artifacts.  Artifacts are immutable, Put returns a conflict
+// error if an artifact already exists with the key.
+type ReportStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, obj []byte, contentType string) error
+}
+
+// New returns the report store for the storage configuration
+func New(config *common.ReportStorage) (ReportStore, error) {
+	if config == nil {
+		return nil, fmt.Errorf("report storage configuration is required")
+	}
+
+	switch config.Type {
+	case "s3":
+		return NewS3Store(config)
+	case "file":
+		return NewFileStore(config.Directory)
+	default:
+		return nil, fmt.Errorf("invalid report storage type '%s', valid values s3, file", config.Type)
+	}
+}
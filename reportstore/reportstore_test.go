@@ -0,0 +1,40 @@
+package reportstore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/cost-api/common"
+)
+
+func TestNew(t *testing.T) {
+	s, err := New(&common.ReportStorage{Type: "file", Directory: t.TempDir()})
+	if err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if to := reflect.TypeOf(s).String(); to != "*reportstore.FileStore" {
+		t.Errorf("expected type to be '*reportstore.FileStore', got %s", to)
+	}
+
+	s, err = New(&common.ReportStorage{Type: "s3", Bucket: "reports", Region: "us-east-1"})
+	if err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if to := reflect.TypeOf(s).String(); to != "*reportstore.S3Store" {
+		t.Errorf("expected type to be '*reportstore.S3Store', got %s", to)
+	}
+
+	for _, c := range []*common.ReportStorage{
+		nil,
+		{},
+		{Type: "gcs"},
+		{Type: "file"},
+		{Type: "s3"},
+	} {
+		if _, err := New(c); err == nil {
+			t.Errorf("expected error for config %+v, got nil", c)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package reportstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/YaleSpinup/cost-api/common"
+	"github.com/YaleSpinup/cost-api/s3cache"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// S3Store stores report artifacts in an S3 bucket
+type S3Store struct {
+	Service s3iface.S3API
+	Bucket  string
+	Prefix  string
+}
+
+// NewS3Store creates a new S3Store from the storage configuration
+func NewS3Store(config *common.ReportStorage) (*S3Store, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("report storage bucket name is required")
+	}
+
+	log.Infof("creating new aws session for S3 report storage with key id %s in region %s", config.Akid, config.Region)
+
+	awsConfig := aws.Config{
+		Credentials: credentials.NewStaticCredentials(config.Akid, config.Secret, ""),
+		Region:      aws.String(config.Region),
+	}
+
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+	}
+
+	sess, err := session.NewSession(&awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Store{
+		Service: s3.New(sess),
+		Bucket:  config.Bucket,
+		Prefix:  config.Prefix,
+	}, nil
+}
+
+// Get reads the artifact with the key from the bucket
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	key = s.key(key)
+
+	log.Infof("getting report artifact %s from bucket %s", key, s.Bucket)
+
+	out, err := s.Service.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("failed to get report artifact %s from bucket %s", key, s.Bucket)
+		return nil, s3cache.ErrCode(msg, err)
+	}
+	defer out.Body.Close()
+
+	obj, err := io.ReadAll(out.Body)
+	if err != nil {
+		msg := fmt.Sprintf("failed to read report artifact %s from bucket %s", key, s.Bucket)
+		return nil, apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	return obj, nil
+}
+
+// Put writes the artifact with the key to the bucket, it fails if the artifact already exists.  The
+// write is conditional (If-None-Match: *) so concurrent writers can't overwrite each other.
+func (s *S3Store) Put(ctx context.Context, key string, obj []byte, contentType string) error {
+	key = s.key(key)
+
+	log.Infof("saving report artifact %s to bucket %s", key, s.Bucket)
+
+	if _, err := s.Service.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(obj),
+		ContentType: aws.String(contentType),
+	}, ifNoneMatch); err != nil {
+		// the conditional write fails with PreconditionFailed when the key exists, or with
+		// ConditionalRequestConflict when a concurrent write to the key is in progress
+		if aerr, ok := errors.Cause(err).(awserr.Error); ok && (aerr.Code() == "PreconditionFailed" || aerr.Code() == "ConditionalRequestConflict") {
+			msg := fmt.Sprintf("report artifact %s already exists in bucket %s", key, s.Bucket)
+			return apierror.New(apierror.ErrConflict, msg, err)
+		}
+
+		msg := fmt.Sprintf("failed to save report artifact %s to bucket %s", key, s.Bucket)
+		return s3cache.ErrCode(msg, err)
+	}
+
+	return nil
+}
+
+// ifNoneMatch makes the put conditional on the key not existing
+func ifNoneMatch(r *request.Request) {
+	r.HTTPRequest.Header.Set("If-None-Match", "*")
+}
+
+func (s *S3Store) key(key string) string {
+	if s.Prefix != "" {
+		return s.Prefix + "/" + key
+	}
+	return key
+}
@@ -0,0 +1,126 @@
+package reportstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// mockS3Client is a fake s3 client backed by a map
+type mockS3Client struct {
+	s3iface.S3API
+	t       *testing.T
+	err     error
+	objects map[string][]byte
+}
+
+func newmockS3Client(t *testing.T, err error) *mockS3Client {
+	return &mockS3Client{
+		t:       t,
+		err:     err,
+		objects: map[string][]byte{},
+	}
+}
+
+func (m *mockS3Client) GetObjectWithContext(ctx context.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	obj, ok := m.objects[aws.StringValue(input.Key)]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(obj))}, nil
+}
+
+func (m *mockS3Client) PutObjectWithContext(ctx context.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	// apply the request options to honor conditional writes
+	r := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	r.ApplyOptions(opts...)
+
+	if _, ok := m.objects[aws.StringValue(input.Key)]; ok && r.HTTPRequest.Header.Get("If-None-Match") == "*" {
+		return nil, awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+	}
+
+	obj, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	m.objects[aws.StringValue(input.Key)] = obj
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3Store(t *testing.T) {
+	client := newmockS3Client(t, nil)
+	s := &S3Store{
+		Service: client,
+		Bucket:  "reports",
+		Prefix:  "costapi",
+	}
+
+	if err := s.Put(context.TODO(), "chargeback/123/2021-05/report.csv", []byte("foo,bar\n"), "text/csv"); err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if _, ok := client.objects["costapi/chargeback/123/2021-05/report.csv"]; !ok {
+		t.Errorf("expected object to be saved with the prefix, got %+v", client.objects)
+	}
+
+	out, err := s.Get(context.TODO(), "chargeback/123/2021-05/report.csv")
+	if err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if string(out) != "foo,bar\n" {
+		t.Errorf("expected foo,bar, got %s", string(out))
+	}
+
+	// test put is write once
+	err = s.Put(context.TODO(), "chargeback/123/2021-05/report.csv", []byte("baz\n"), "text/csv")
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrConflict {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrConflict, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err))
+	}
+
+	// test missing artifact
+	_, err = s.Get(context.TODO(), "chargeback/123/2021-06/report.csv")
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrNotFound {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrNotFound, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err))
+	}
+
+	// test access denied
+	client.err = awserr.New("AccessDenied", "access denied", nil)
+	err = s.Put(context.TODO(), "chargeback/123/2021-07/report.csv", []byte("baz\n"), "text/csv")
+	if aerr, ok := err.(apierror.Error); ok {
+		if aerr.Code != apierror.ErrForbidden {
+			t.Errorf("expected error code %s, got: %s", apierror.ErrForbidden, aerr.Code)
+		}
+	} else {
+		t.Errorf("expected apierror.Error, got: %s", reflect.TypeOf(err))
+	}
+}
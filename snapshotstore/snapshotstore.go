@@ -0,0 +1,262 @@
+package snapshotstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/YaleSpinup/apierror"
+	log "github.com/sirupsen/logrus"
+)
+
+// Snapshot is the cost of a space for a day
+type Snapshot struct {
+	Date   string
+	Space  string
+	Amount float64
+	Unit   string
+}
+
+// Store is an embedded store of daily space cost snapshots.  Snapshots are kept in a JSON
+// document per account and month in the store directory, ie. {directory}/{account}/2021-05.json.
+// Documents are replaced atomically so a failed write never loses existing snapshots.
+type Store struct {
+	Directory string
+	mu        sync.RWMutex
+}
+
+var validAccount = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// New creates a new snapshot store in the given directory
+func New(directory string) (*Store, error) {
+	if directory == "" {
+		return nil, fmt.Errorf("snapshot store directory is required")
+	}
+
+	if err := os.MkdirAll(directory, 0750); err != nil {
+		return nil, err
+	}
+
+	return &Store{Directory: directory}, nil
+}
+
+// Put saves the snapshots collected for an account from start (inclusive) to end (exclusive).  The
+// snapshots replace all of the existing snapshots in the range, so a day can be collected again as
+// its costs are finalized and a space without cost in the new collection doesn't keep a stale snapshot.
+func (s *Store) Put(account, start, end string, snapshots []*Snapshot) error {
+	if !validAccount.MatchString(account) {
+		msg := fmt.Sprintf("invalid snapshot account '%s'", account)
+		return apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	startTime, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return apierror.New(apierror.ErrBadRequest, fmt.Sprintf("invalid start date '%s'", start), err)
+	}
+
+	endTime, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return apierror.New(apierror.ErrBadRequest, fmt.Sprintf("invalid end date '%s'", end), err)
+	}
+
+	byMonth := map[string][]*Snapshot{}
+	for _, snap := range snapshots {
+		d, err := time.Parse("2006-01-02", snap.Date)
+		if err != nil {
+			msg := fmt.Sprintf("invalid snapshot date '%s'", snap.Date)
+			return apierror.New(apierror.ErrBadRequest, msg, err)
+		}
+
+		if snap.Date < start || snap.Date >= end {
+			msg := fmt.Sprintf("snapshot date '%s' is outside of %s to %s", snap.Date, start, end)
+			return apierror.New(apierror.ErrBadRequest, msg, nil)
+		}
+
+		month := d.Format("2006-01")
+		byMonth[month] = append(byMonth[month], snap)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for m := time.Date(startTime.Year(), startTime.Month(), 1, 0, 0, 0, 0, time.UTC); m.Before(endTime); m = m.AddDate(0, 1, 0) {
+		month := m.Format("2006-01")
+
+		existing, err := s.read(account, month)
+		if err != nil {
+			return err
+		}
+
+		if existing == nil && byMonth[month] == nil {
+			continue
+		}
+
+		out := byMonth[month]
+		for _, snap := range existing {
+			if snap.Date < start || snap.Date >= end {
+				out = append(out, snap)
+			}
+		}
+
+		if err := s.write(account, month, out); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("saved %d cost snapshots for account %s from %s to %s", len(snapshots), account, start, end)
+
+	return nil
+}
+
+// Query returns the snapshots for a space in an account from start (inclusive) to end (exclusive),
+// sorted by date.  If space is empty, the snapshots for all spaces are returned.
+func (s *Store) Query(account, space, start, end string) ([]*Snapshot, error) {
+	if !validAccount.MatchString(account) {
+		msg := fmt.Sprintf("invalid snapshot account '%s'", account)
+		return nil, apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	startTime, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return nil, apierror.New(apierror.ErrBadRequest, fmt.Sprintf("invalid start date '%s'", start), err)
+	}
+
+	endTime, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return nil, apierror.New(apierror.ErrBadRequest, fmt.Sprintf("invalid end date '%s'", end), err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []*Snapshot{}
+	for m := time.Date(startTime.Year(), startTime.Month(), 1, 0, 0, 0, 0, time.UTC); m.Before(endTime); m = m.AddDate(0, 1, 0) {
+		snapshots, err := s.read(account, m.Format("2006-01"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, snap := range snapshots {
+			if space != "" && snap.Space != space {
+				continue
+			}
+
+			if snap.Date < start || snap.Date >= end {
+				continue
+			}
+
+			out = append(out, snap)
+		}
+	}
+
+	return out, nil
+}
+
+// Latest returns the date of the most recent snapshot for an account, or an empty string if
+// there are no snapshots
+func (s *Store) Latest(account string) (string, error) {
+	if !validAccount.MatchString(account) {
+		msg := fmt.Sprintf("invalid snapshot account '%s'", account)
+		return "", apierror.New(apierror.ErrBadRequest, msg, nil)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files, err := filepath.Glob(filepath.Join(s.Directory, account, "????-??.json"))
+	if err != nil {
+		return "", apierror.New(apierror.ErrInternalError, "failed to list cost snapshots", err)
+	}
+
+	// month files sort lexically, check the newest month with snapshots first
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+
+	for _, f := range files {
+		snapshots, err := s.read(account, filepath.Base(f[:len(f)-len(".json")]))
+		if err != nil {
+			return "", err
+		}
+
+		if len(snapshots) > 0 {
+			return snapshots[len(snapshots)-1].Date, nil
+		}
+	}
+
+	return "", nil
+}
+
+// read reads the snapshots for an account and month, a missing document has no snapshots
+func (s *Store) read(account, month string) ([]*Snapshot, error) {
+	b, err := os.ReadFile(s.path(account, month))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		msg := fmt.Sprintf("failed to read cost snapshots for %s in account %s", month, account)
+		return nil, apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	snapshots := []*Snapshot{}
+	if err := json.Unmarshal(b, &snapshots); err != nil {
+		msg := fmt.Sprintf("failed to unmarshal cost snapshots for %s in account %s", month, account)
+		return nil, apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	return snapshots, nil
+}
+
+// write sorts and writes the snapshots for an account and month to a temporary file and renames
+// it into place
+func (s *Store) write(account, month string, snapshots []*Snapshot) error {
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Date == snapshots[j].Date {
+			return snapshots[i].Space < snapshots[j].Space
+		}
+		return snapshots[i].Date < snapshots[j].Date
+	})
+
+	msg := fmt.Sprintf("failed to save cost snapshots for %s in account %s", month, account)
+
+	b, err := json.Marshal(snapshots)
+	if err != nil {
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	path := s.path(account, month)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), month+".*.tmp")
+	if err != nil {
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return apierror.New(apierror.ErrInternalError, msg, err)
+	}
+
+	return nil
+}
+
+func (s *Store) path(account, month string) string {
+	return filepath.Join(s.Directory, account, month+".json")
+}
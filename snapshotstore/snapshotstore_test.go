@@ -0,0 +1,202 @@
+package snapshotstore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/YaleSpinup/apierror"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(""); err == nil {
+		t.Error("expected error for empty directory, got nil")
+	}
+
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	s, err := New(dir)
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if s.Directory != dir {
+		t.Errorf("expected directory %s, got %s", dir, s.Directory)
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("expected directory to be created, got %s", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	latest, err := s.Latest("012345678901")
+	if err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if latest != "" {
+		t.Errorf("expected no latest snapshot, got %s", latest)
+	}
+
+	if err := s.Put("012345678901", "2021-04-30", "2021-05-03", []*Snapshot{
+		{Date: "2021-04-30", Space: "spc-1", Amount: 1, Unit: "USD"},
+		{Date: "2021-05-01", Space: "spc-2", Amount: 2, Unit: "USD"},
+		{Date: "2021-05-01", Space: "spc-1", Amount: 3, Unit: "USD"},
+		{Date: "2021-05-02", Space: "spc-1", Amount: 4, Unit: "USD"},
+	}); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	// putting a day again replaces all of the snapshots for the day
+	if err := s.Put("012345678901", "2021-05-02", "2021-05-03", []*Snapshot{
+		{Date: "2021-05-02", Space: "spc-1", Amount: 5, Unit: "USD"},
+	}); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(s.Directory, "012345678901", "2021-04.json")); err != nil {
+		t.Errorf("expected snapshots to be stored by month, got %s", err)
+	}
+
+	out, err := s.Query("012345678901", "spc-1", "2021-04-01", "2021-06-01")
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := []*Snapshot{
+		{Date: "2021-04-30", Space: "spc-1", Amount: 1, Unit: "USD"},
+		{Date: "2021-05-01", Space: "spc-1", Amount: 3, Unit: "USD"},
+		{Date: "2021-05-02", Space: "spc-1", Amount: 5, Unit: "USD"},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	// the end date is exclusive
+	out, err = s.Query("012345678901", "", "2021-05-01", "2021-05-02")
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected = []*Snapshot{
+		{Date: "2021-05-01", Space: "spc-1", Amount: 3, Unit: "USD"},
+		{Date: "2021-05-01", Space: "spc-2", Amount: 2, Unit: "USD"},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	latest, err = s.Latest("012345678901")
+	if err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if latest != "2021-05-02" {
+		t.Errorf("expected latest snapshot 2021-05-02, got %s", latest)
+	}
+
+	// other accounts have no snapshots
+	out, err = s.Query("999999999999", "spc-1", "2021-04-01", "2021-06-01")
+	if err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if len(out) != 0 {
+		t.Errorf("expected no snapshots, got %+v", out)
+	}
+}
+
+func TestStorePutReplacesRange(t *testing.T) {
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if err := s.Put("012345678901", "2021-04-29", "2021-05-03", []*Snapshot{
+		{Date: "2021-04-29", Space: "spc-1", Amount: 1, Unit: "USD"},
+		{Date: "2021-04-30", Space: "spc-1", Amount: 2, Unit: "USD"},
+		{Date: "2021-04-30", Space: "spc-2", Amount: 3, Unit: "USD"},
+		{Date: "2021-05-01", Space: "spc-2", Amount: 4, Unit: "USD"},
+		{Date: "2021-05-02", Space: "spc-1", Amount: 5, Unit: "USD"},
+	}); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	// the costs of spc-2 were refunded when the range is collected again across the month boundary, its
+	// snapshots in the range are dropped and the snapshots outside of the range are kept
+	if err := s.Put("012345678901", "2021-04-30", "2021-05-02", []*Snapshot{
+		{Date: "2021-04-30", Space: "spc-1", Amount: 6, Unit: "USD"},
+	}); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	out, err := s.Query("012345678901", "", "2021-04-01", "2021-06-01")
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	expected := []*Snapshot{
+		{Date: "2021-04-29", Space: "spc-1", Amount: 1, Unit: "USD"},
+		{Date: "2021-04-30", Space: "spc-1", Amount: 6, Unit: "USD"},
+		{Date: "2021-05-02", Space: "spc-1", Amount: 5, Unit: "USD"},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v, got %+v", expected, out)
+	}
+
+	// an empty collection clears the range without creating documents for months without snapshots
+	if err := s.Put("012345678901", "2021-05-01", "2021-07-01", nil); err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(s.Directory, "012345678901", "2021-06.json")); !os.IsNotExist(err) {
+		t.Errorf("expected no document for a month without snapshots, got %v", err)
+	}
+
+	latest, err := s.Latest("012345678901")
+	if err != nil {
+		t.Errorf("expected nil error, got %s", err)
+	}
+
+	if latest != "2021-04-30" {
+		t.Errorf("expected latest snapshot 2021-04-30, got %s", latest)
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("expected nil error, got %s", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"invalid account put", s.Put("../outside", "2021-05-01", "2021-06-01", nil)},
+		{"invalid start put", s.Put("012345678901", "2021-05", "2021-06-01", nil)},
+		{"invalid end put", s.Put("012345678901", "2021-05-01", "", nil)},
+		{"invalid date put", s.Put("012345678901", "2021-05-01", "2021-06-01", []*Snapshot{{Date: "2021-05"}})},
+		{"date outside of range put", s.Put("012345678901", "2021-05-01", "2021-06-01", []*Snapshot{{Date: "2021-06-01"}})},
+		{"invalid account query", func() error { _, err := s.Query("a/b", "", "2021-05-01", "2021-06-01"); return err }()},
+		{"invalid start query", func() error { _, err := s.Query("012345678901", "", "2021-05", "2021-06-01"); return err }()},
+		{"invalid end query", func() error { _, err := s.Query("012345678901", "", "2021-05-01", ""); return err }()},
+		{"invalid account latest", func() error { _, err := s.Latest(".."); return err }()},
+	}
+
+	for _, tt := range tests {
+		if aerr, ok := tt.err.(apierror.Error); ok {
+			if aerr.Code != apierror.ErrBadRequest {
+				t.Errorf("%s: expected error code %s, got: %s", tt.name, apierror.ErrBadRequest, aerr.Code)
+			}
+		} else {
+			t.Errorf("%s: expected apierror.Error, got: %v", tt.name, tt.err)
+		}
+	}
+}
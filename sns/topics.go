@@ -60,3 +60,21 @@ func (s *SNS) CreateSubscription(ctx context.Context, input *sns.SubscribeInput)
 
 	return out, nil
 }
+
+// Publish publishes a message to an SNS topic
+func (s *SNS) Publish(ctx context.Context, input *sns.PublishInput) (*sns.PublishOutput, error) {
+	if input == nil || aws.StringValue(input.TopicArn) == "" {
+		return nil, apierror.New(apierror.ErrBadRequest, "invalid input", nil)
+	}
+
+	log.Infof("publishing message to SNS topic %s", aws.StringValue(input.TopicArn))
+
+	out, err := s.Service.PublishWithContext(ctx, input)
+	if err != nil {
+		return nil, ErrCode("failed to publish to sns topic", err)
+	}
+
+	log.Debugf("got output publishing to topic: %+v", out)
+
+	return out, nil
+}